@@ -0,0 +1,136 @@
+package glance
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/glanceapp/glance/pkg/sysinfo"
+	"gopkg.in/yaml.v3"
+)
+
+const defaultAgentPort = 27973
+
+// Config of the agent which exposes the system info of the host it runs on
+// so that it can be displayed by server-stats widgets with type: remote
+type agentConfig struct {
+	Server struct {
+		Host    string `yaml:"host"`
+		Port    uint16 `yaml:"port"`
+		Token   string `yaml:"token"`
+		TLSCert string `yaml:"tls-cert"`
+		TLSKey  string `yaml:"tls-key"`
+	} `yaml:"server"`
+
+	System sysinfo.SystemInfoRequest `yaml:"system"`
+}
+
+func newAgentConfigFromYAML(contents []byte) (*agentConfig, error) {
+	contents, err := parseConfigVariables(contents)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &agentConfig{}
+	config.Server.Port = defaultAgentPort
+
+	decoder := yaml.NewDecoder(bytes.NewReader(contents))
+	decoder.KnownFields(true)
+
+	if err := decoder.Decode(config); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	if (config.Server.TLSCert == "") != (config.Server.TLSKey == "") {
+		return nil, errors.New("both tls-cert and tls-key must be specified to enable TLS")
+	}
+
+	return config, nil
+}
+
+func newAgentHandler(config *agentConfig) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /api/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	mux.HandleFunc("GET /api/sysinfo/all", func(w http.ResponseWriter, r *http.Request) {
+		if !agentRequestIsAuthorized(r, config.Server.Token) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		info, errs := sysinfo.Collect(&config.System)
+		for i := range errs {
+			slog.Warn("Getting system info: " + errs[i].Error())
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		json.NewEncoder(w).Encode(info)
+	})
+
+	return mux
+}
+
+func agentRequestIsAuthorized(r *http.Request, token string) bool {
+	if token == "" {
+		return true
+	}
+
+	provided, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(provided), []byte(token)) == 1
+}
+
+func cliAgent(configPath string) int {
+	contents, _, err := parseYAMLIncludes(configPath)
+	if err != nil {
+		fmt.Printf("Could not parse config file: %v\n", err)
+		return 1
+	}
+
+	config, err := newAgentConfigFromYAML(contents)
+	if err != nil {
+		fmt.Printf("Config file is invalid: %v\n", err)
+		return 1
+	}
+
+	if config.Server.Token == "" {
+		log.Println("Warning: no token has been set, anyone who can reach the agent will be able to read the system info")
+	}
+
+	server := http.Server{
+		Addr:              fmt.Sprintf("%s:%d", config.Server.Host, config.Server.Port),
+		Handler:           newAgentHandler(config),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	useTLS := config.Server.TLSCert != ""
+	log.Printf("Starting agent on %s:%d (tls: %t)\n", config.Server.Host, config.Server.Port, useTLS)
+
+	if useTLS {
+		err = server.ListenAndServeTLS(config.Server.TLSCert, config.Server.TLSKey)
+	} else {
+		err = server.ListenAndServe()
+	}
+
+	if err != nil && err != http.ErrServerClosed {
+		fmt.Println(err)
+		return 1
+	}
+
+	return 0
+}
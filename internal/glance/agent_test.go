@@ -0,0 +1,53 @@
+package glance
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAgentServesSystemInfoToRemoteServerStats(t *testing.T) {
+	config, err := newAgentConfigFromYAML([]byte("server:\n  token: abc123\nsystem:\n  hide-mountpoints-by-default: true\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config.Server.Port != defaultAgentPort {
+		t.Errorf("expected the default port to be used, got %d", config.Server.Port)
+	}
+
+	server := httptest.NewServer(newAgentHandler(config))
+	defer server.Close()
+
+	request := &serverStatsRequest{URL: server.URL, Timeout: durationField(5 * time.Second)}
+
+	if _, err := fetchRemoteServerInfo(request); err == nil {
+		t.Error("expected requests without a token to be rejected")
+	}
+
+	request.Token = "abc123"
+	info, err := fetchRemoteServerInfo(request)
+	if err != nil {
+		t.Fatalf("fetching system info from agent: %v", err)
+	}
+
+	if info.Mountpoints == nil || len(info.Mountpoints) != 0 {
+		t.Errorf("expected no mountpoints, got %v", info.Mountpoints)
+	}
+
+	response, err := http.Get(server.URL + "/api/healthz")
+	if err != nil || response.StatusCode != http.StatusOK {
+		t.Errorf("expected health check to succeed, got %v", err)
+	}
+}
+
+func TestAgentConfigRequiresBothTLSFiles(t *testing.T) {
+	if _, err := newAgentConfigFromYAML([]byte("server:\n  tls-cert: cert.pem\n")); err == nil {
+		t.Error("expected an error when only the certificate is specified")
+	}
+
+	if _, err := newAgentConfigFromYAML([]byte("server:\n  prot: 1234\n")); err == nil {
+		t.Error("expected an error for unknown keys")
+	}
+}
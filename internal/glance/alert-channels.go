@@ -0,0 +1,307 @@
+package glance
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type alertChannel interface {
+	send(context.Context, *alertNotification) error
+}
+
+// All channel types share the same config, only the properties relevant
+// to the type of the channel are used
+type alertChannelConfig struct {
+	Name          string            `yaml:"name"`
+	Type          string            `yaml:"type"`
+	URL           string            `yaml:"url"`
+	Token         string            `yaml:"token"`
+	Headers       map[string]string `yaml:"headers"`
+	Priority      int               `yaml:"priority"`
+	AllowInsecure bool              `yaml:"allow-insecure"`
+	Host          string            `yaml:"host"`
+	Port          int               `yaml:"port"`
+	Username      string            `yaml:"username"`
+	Password      string            `yaml:"password"`
+	From          string            `yaml:"from"`
+	To            []string          `yaml:"to"`
+}
+
+func (c *alertChannelConfig) validate() error {
+	switch c.Type {
+	case "webhook", "ntfy", "gotify":
+		if c.URL == "" {
+			return errors.New("url is required")
+		}
+
+		if !strings.HasPrefix(c.URL, "http://") && !strings.HasPrefix(c.URL, "https://") {
+			return errors.New("url must start with http:// or https://")
+		}
+	case "smtp":
+		if c.Host == "" {
+			return errors.New("host is required")
+		}
+
+		if c.From == "" || len(c.To) == 0 {
+			return errors.New("from and to are required")
+		}
+	default:
+		return errUnknownAlertChannelType
+	}
+
+	if c.Type == "gotify" && c.Token == "" {
+		return errors.New("token is required")
+	}
+
+	if c.Type == "ntfy" && (c.Priority < 0 || c.Priority > 5) {
+		return errors.New("priority must be between 1 and 5")
+	}
+
+	return nil
+}
+
+func newAlertChannel(config *alertChannelConfig) (alertChannel, error) {
+	client := alertHTTPClient
+	if config.AllowInsecure {
+		client = alertInsecureHTTPClient
+	}
+
+	switch config.Type {
+	case "webhook":
+		return &webhookAlertChannel{config: config, client: client}, nil
+	case "ntfy":
+		return &ntfyAlertChannel{config: config, client: client}, nil
+	case "gotify":
+		return &gotifyAlertChannel{config: config, client: client}, nil
+	case "smtp":
+		return &smtpAlertChannel{config: config}, nil
+	}
+
+	return nil, errUnknownAlertChannelType
+}
+
+// Alerts are not upstream data, so unlike the widgets they bypass the
+// recording and replaying of responses
+var alertHTTPClient = &http.Client{
+	Timeout:   15 * time.Second,
+	Transport: &userAgentTransport{underlying: http.DefaultTransport},
+}
+
+var alertInsecureHTTPClient = &http.Client{
+	Timeout: 15 * time.Second,
+	Transport: &userAgentTransport{
+		underlying: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+	},
+}
+
+func sendAlertRequest(client *http.Client, request *http.Request) error {
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(response.Body, 256))
+		return fmt.Errorf("unexpected status code %d: %s", response.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	return nil
+}
+
+type webhookAlertChannel struct {
+	config *alertChannelConfig
+	client *http.Client
+}
+
+func (c *webhookAlertChannel) send(ctx context.Context, n *alertNotification) error {
+	status := "failing"
+	if n.Recovered {
+		status = "recovered"
+	}
+
+	body, err := json.Marshal(map[string]any{
+		"title":        n.title(),
+		"message":      n.body(),
+		"status":       status,
+		"repeated":     n.Repeated,
+		"rule":         n.Rule,
+		"widget_type":  n.WidgetType,
+		"widget_title": n.WidgetTitle,
+		"subject":      n.Subject,
+		"details":      n.Message,
+		"failing_from": n.FailingFrom,
+	})
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, "POST", c.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	if c.config.Token != "" {
+		request.Header.Set("Authorization", "Bearer "+c.config.Token)
+	}
+	for key, value := range c.config.Headers {
+		request.Header.Set(key, value)
+	}
+
+	return sendAlertRequest(c.client, request)
+}
+
+type ntfyAlertChannel struct {
+	config *alertChannelConfig
+	client *http.Client
+}
+
+func (c *ntfyAlertChannel) send(ctx context.Context, n *alertNotification) error {
+	request, err := http.NewRequestWithContext(ctx, "POST", c.config.URL, strings.NewReader(n.body()))
+	if err != nil {
+		return err
+	}
+
+	// header values have to be ASCII, ntfy decodes RFC 2047 encoded ones
+	request.Header.Set("Title", mime.QEncoding.Encode("utf-8", n.title()))
+	request.Header.Set("Tags", ternary(n.Recovered, "white_check_mark", "warning"))
+
+	if c.config.Priority > 0 {
+		request.Header.Set("Priority", strconv.Itoa(c.config.Priority))
+	}
+
+	if c.config.Token != "" {
+		request.Header.Set("Authorization", "Bearer "+c.config.Token)
+	}
+
+	return sendAlertRequest(c.client, request)
+}
+
+type gotifyAlertChannel struct {
+	config *alertChannelConfig
+	client *http.Client
+}
+
+func (c *gotifyAlertChannel) send(ctx context.Context, n *alertNotification) error {
+	body, err := json.Marshal(map[string]any{
+		"title":    n.title(),
+		"message":  n.body(),
+		"priority": c.config.Priority,
+	})
+	if err != nil {
+		return err
+	}
+
+	url := strings.TrimRight(c.config.URL, "/") + "/message"
+	request, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("X-Gotify-Key", c.config.Token)
+
+	return sendAlertRequest(c.client, request)
+}
+
+type smtpAlertChannel struct {
+	config *alertChannelConfig
+}
+
+func (c *smtpAlertChannel) send(ctx context.Context, n *alertNotification) error {
+	port := c.config.Port
+	if port == 0 {
+		port = 587
+	}
+
+	address := net.JoinHostPort(c.config.Host, strconv.Itoa(port))
+
+	var message bytes.Buffer
+	fmt.Fprintf(&message, "From: %s\r\n", c.config.From)
+	fmt.Fprintf(&message, "To: %s\r\n", strings.Join(c.config.To, ", "))
+	fmt.Fprintf(&message, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", n.title()))
+	fmt.Fprintf(&message, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	message.WriteString("MIME-Version: 1.0\r\n")
+	message.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	message.WriteString(strings.ReplaceAll(n.body(), "\n", "\r\n"))
+
+	var dialer net.Dialer
+	var conn net.Conn
+	var err error
+
+	// port 465 expects TLS from the start, everything else gets upgraded through STARTTLS
+	if port == 465 {
+		conn, err = (&tls.Dialer{
+			NetDialer: &dialer,
+			Config:    &tls.Config{ServerName: c.config.Host, InsecureSkipVerify: c.config.AllowInsecure},
+		}).DialContext(ctx, "tcp", address)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", address)
+	}
+	if err != nil {
+		return err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, c.config.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok && port != 465 {
+		if err := client.StartTLS(&tls.Config{ServerName: c.config.Host, InsecureSkipVerify: c.config.AllowInsecure}); err != nil {
+			return err
+		}
+	}
+
+	if c.config.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", c.config.Username, c.config.Password, c.config.Host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(c.config.From); err != nil {
+		return err
+	}
+
+	for _, recipient := range c.config.To {
+		if err := client.Rcpt(recipient); err != nil {
+			return err
+		}
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+
+	if _, err := writer.Write(message.Bytes()); err != nil {
+		return err
+	}
+
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
@@ -0,0 +1,414 @@
+package glance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+type alertsConfig struct {
+	Channels []*alertChannelConfig `yaml:"channels"`
+	Rules    []*alertRule          `yaml:"rules"`
+}
+
+type alertRule struct {
+	Name           string        `yaml:"name"`
+	Types          []string      `yaml:"types"`
+	Widgets        []string      `yaml:"widgets"`
+	Subjects       []string      `yaml:"subjects"`
+	Channels       []string      `yaml:"channels"`
+	RepeatInterval durationField `yaml:"repeat-interval"`
+	SendRecovery   *bool         `yaml:"send-recovery"`
+}
+
+func (c *alertsConfig) validate() error {
+	channelNames := make(map[string]bool, len(c.Channels))
+
+	for i, channel := range c.Channels {
+		if channel.Name == "" {
+			return fmt.Errorf("alert channel %d has no name", i+1)
+		}
+
+		if channelNames[channel.Name] {
+			return fmt.Errorf("alert channel %s is defined more than once", channel.Name)
+		}
+		channelNames[channel.Name] = true
+
+		if err := channel.validate(); err != nil {
+			return fmt.Errorf("alert channel %s: %v", channel.Name, err)
+		}
+	}
+
+	for i, rule := range c.Rules {
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule %d", i+1)
+		}
+
+		if len(rule.Channels) == 0 {
+			return fmt.Errorf("alert rule %s has no channels", rule.Name)
+		}
+
+		for _, name := range rule.Channels {
+			if !channelNames[name] {
+				return fmt.Errorf("alert rule %s uses unknown channel %s", rule.Name, name)
+			}
+		}
+
+		for _, widgetType := range rule.Types {
+			if !slices.Contains(alertingWidgetTypes, widgetType) {
+				return fmt.Errorf(
+					"alert rule %s: widget type %s does not emit alerts, supported types are %s",
+					rule.Name, widgetType, strings.Join(alertingWidgetTypes, ", "),
+				)
+			}
+		}
+
+		for _, pattern := range rule.Subjects {
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				return fmt.Errorf("alert rule %s: invalid subject pattern %s", rule.Name, pattern)
+			}
+		}
+
+		if rule.RepeatInterval != 0 && time.Duration(rule.RepeatInterval) < time.Minute {
+			return fmt.Errorf("alert rule %s: repeat-interval must be at least 1m", rule.Name)
+		}
+	}
+
+	return nil
+}
+
+func (r *alertRule) matches(event *alertEvent) bool {
+	if len(r.Types) > 0 && !slices.Contains(r.Types, event.WidgetType) {
+		return false
+	}
+
+	if len(r.Widgets) > 0 && !slices.Contains(r.Widgets, event.WidgetTitle) {
+		return false
+	}
+
+	if len(r.Subjects) > 0 && !slices.ContainsFunc(r.Subjects, func(pattern string) bool {
+		matched, _ := filepath.Match(pattern, event.Subject)
+		return matched
+	}) {
+		return false
+	}
+
+	return true
+}
+
+func (r *alertRule) sendsRecovery() bool {
+	return r.SendRecovery == nil || *r.SendRecovery
+}
+
+// The widget types which report the state of the things they display through emitAlertEvent
+var alertingWidgetTypes = []string{"dns-stats", "docker-containers", "kubernetes", "monitor", "tailscale"}
+
+// Reported by widgets on every update for each of the things they keep track of,
+// the dispatcher is the one that figures out whether the state has changed
+type alertEvent struct {
+	WidgetID    uint64
+	WidgetType  string
+	WidgetTitle string
+	Subject     string
+	Failing     bool
+	Message     string
+}
+
+type alertNotification struct {
+	Rule        string
+	WidgetType  string
+	WidgetTitle string
+	Subject     string
+	Message     string
+	Recovered   bool
+	Repeated    bool
+	FailingFrom time.Time
+}
+
+func (n *alertNotification) title() string {
+	subject := n.Subject
+	if subject == "" {
+		subject = n.WidgetTitle
+	}
+
+	if n.Recovered {
+		return subject + " has recovered"
+	}
+
+	if n.Repeated {
+		return subject + " is still failing"
+	}
+
+	return subject + " is failing"
+}
+
+func (n *alertNotification) body() string {
+	var body strings.Builder
+
+	if n.Message != "" {
+		body.WriteString(n.Message)
+		body.WriteString("\n\n")
+	}
+
+	if n.Recovered {
+		fmt.Fprintf(&body, "Failing for %s.\n", time.Since(n.FailingFrom).Round(time.Second))
+	} else {
+		fmt.Fprintf(&body, "Failing since %s.\n", n.FailingFrom.Format(time.DateTime))
+	}
+
+	fmt.Fprintf(&body, "Widget: %s (%s), rule: %s", n.WidgetTitle, n.WidgetType, n.Rule)
+
+	return body.String()
+}
+
+type alertState struct {
+	rule         string
+	widgetType   string
+	widgetTitle  string
+	subject      string
+	failing      bool
+	failingFrom  time.Time
+	lastNotified time.Time
+	// the widget which last reported the subject, 0 until the first update
+	// after a config reload since IDs aren't stable across reloads
+	widgetID uint64
+}
+
+// Keyed by the rule name, the type and title of the widget and the subject
+type alertStates struct {
+	mu     sync.Mutex
+	states map[string]*alertState
+}
+
+func newAlertStates() *alertStates {
+	return &alertStates{states: make(map[string]*alertState)}
+}
+
+// Kept outside of the dispatcher, which gets recreated on every config reload,
+// so that subjects which were already failing aren't notified about again
+var alertStatesAcrossReloads = newAlertStates()
+
+type alertDispatcher struct {
+	rules    []*alertRule
+	channels map[string]alertChannel
+	now      func() time.Time
+	states   *alertStates
+}
+
+func newAlertDispatcher(config *alertsConfig) (*alertDispatcher, error) {
+	if len(config.Rules) == 0 {
+		return nil, nil
+	}
+
+	dispatcher := &alertDispatcher{
+		rules:    config.Rules,
+		channels: make(map[string]alertChannel, len(config.Channels)),
+		now:      time.Now,
+		states:   alertStatesAcrossReloads,
+	}
+
+	dispatcher.states.mu.Lock()
+	for key, state := range dispatcher.states.states {
+		if !slices.ContainsFunc(config.Rules, func(rule *alertRule) bool { return rule.Name == state.rule }) {
+			delete(dispatcher.states.states, key)
+			continue
+		}
+
+		state.widgetID = 0
+	}
+	dispatcher.states.mu.Unlock()
+
+	for _, channelConfig := range config.Channels {
+		channel, err := newAlertChannel(channelConfig)
+		if err != nil {
+			return nil, fmt.Errorf("alert channel %s: %v", channelConfig.Name, err)
+		}
+
+		dispatcher.channels[channelConfig.Name] = channel
+	}
+
+	return dispatcher, nil
+}
+
+// Whether any of the rules could be interested in the events of the widget
+func (d *alertDispatcher) watches(w widget) bool {
+	if d == nil || !slices.Contains(alertingWidgetTypes, w.GetType()) {
+		return false
+	}
+
+	var title string
+	if titled, ok := w.(interface{ getTitle() string }); ok {
+		title = titled.getTitle()
+	}
+
+	return slices.ContainsFunc(d.rules, func(rule *alertRule) bool {
+		return (len(rule.Types) == 0 || slices.Contains(rule.Types, w.GetType())) &&
+			(len(rule.Widgets) == 0 || slices.Contains(rule.Widgets, title))
+	})
+}
+
+func alertStateKey(rule string, event *alertEvent) string {
+	return strings.Join([]string{rule, event.WidgetType, event.WidgetTitle, event.Subject}, "\x00")
+}
+
+func (d *alertDispatcher) emit(event alertEvent) {
+	now := d.now()
+
+	d.states.mu.Lock()
+	defer d.states.mu.Unlock()
+
+	for _, rule := range d.rules {
+		if !rule.matches(&event) {
+			continue
+		}
+
+		key := alertStateKey(rule.Name, &event)
+		state, exists := d.states.states[key]
+
+		if !exists {
+			state = &alertState{
+				rule:        rule.Name,
+				widgetType:  event.WidgetType,
+				widgetTitle: event.WidgetTitle,
+				subject:     event.Subject,
+			}
+			d.states.states[key] = state
+		}
+
+		state.widgetID = event.WidgetID
+
+		notification := &alertNotification{
+			Rule:        rule.Name,
+			WidgetType:  event.WidgetType,
+			WidgetTitle: event.WidgetTitle,
+			Subject:     event.Subject,
+			Message:     event.Message,
+		}
+
+		switch {
+		case event.Failing && !state.failing:
+			state.failing = true
+			state.failingFrom = now
+			state.lastNotified = now
+		case event.Failing && rule.RepeatInterval > 0 && now.Sub(state.lastNotified) >= time.Duration(rule.RepeatInterval):
+			state.lastNotified = now
+			notification.Repeated = true
+		case !event.Failing && state.failing:
+			state.failing = false
+			if !rule.sendsRecovery() {
+				continue
+			}
+			notification.Recovered = true
+		default:
+			continue
+		}
+
+		notification.FailingFrom = state.failingFrom
+
+		for _, name := range rule.Channels {
+			go d.deliver(name, notification)
+		}
+	}
+}
+
+// Subjects which were failing and are no longer reported by their widget, such as
+// containers that got removed, would otherwise stay failing forever
+func (d *alertDispatcher) recoverMissing(widgetID uint64, widgetType, widgetTitle string, reported map[string]bool) {
+	var missing []alertEvent
+
+	d.states.mu.Lock()
+	for _, state := range d.states.states {
+		if !state.failing || reported[state.subject] || state.widgetType != widgetType || state.widgetTitle != widgetTitle {
+			continue
+		}
+
+		if state.widgetID != widgetID && state.widgetID != 0 {
+			continue
+		}
+
+		missing = append(missing, alertEvent{
+			WidgetID:    widgetID,
+			WidgetType:  widgetType,
+			WidgetTitle: widgetTitle,
+			Subject:     state.subject,
+			Message:     "No longer reported by the widget.",
+		})
+	}
+	d.states.mu.Unlock()
+
+	for i := range missing {
+		d.emit(missing[i])
+	}
+}
+
+func (d *alertDispatcher) deliver(channelName string, notification *alertNotification) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := d.channels[channelName].send(ctx, notification); err != nil {
+		slog.Error("Failed to send alert", "channel", channelName, "rule", notification.Rule, "error", err)
+	}
+}
+
+func widgetDescendants(w widget) widgets {
+	container, ok := w.(interface{ childWidgets() widgets })
+	if !ok {
+		return nil
+	}
+
+	var descendants widgets
+	for _, child := range container.childWidgets() {
+		descendants = append(descendants, child)
+		descendants = append(descendants, widgetDescendants(child)...)
+	}
+
+	return descendants
+}
+
+// Called before every update, the subjects reported during it are then
+// compared against the failing ones in finishAlertEvents
+func (w *widgetBase) beginAlertEvents() {
+	w.alertSubjects = nil
+}
+
+func (w *widgetBase) finishAlertEvents() {
+	if w.Providers == nil || w.Providers.alerts == nil || !slices.Contains(alertingWidgetTypes, w.Type) {
+		return
+	}
+
+	// a subject can only be assumed to be gone if the widget got a complete
+	// list of them, not when a host was unreachable
+	if w.Error != nil || w.Notice != nil {
+		return
+	}
+
+	w.Providers.alerts.recoverMissing(w.ID, w.Type, w.Title, w.alertSubjects)
+}
+
+func (w *widgetBase) emitAlertEvent(subject string, failing bool, message string) {
+	if w.Providers == nil || w.Providers.alerts == nil {
+		return
+	}
+
+	if w.alertSubjects == nil {
+		w.alertSubjects = make(map[string]bool)
+	}
+	w.alertSubjects[subject] = true
+
+	w.Providers.alerts.emit(alertEvent{
+		WidgetID:    w.ID,
+		WidgetType:  w.Type,
+		WidgetTitle: w.Title,
+		Subject:     subject,
+		Failing:     failing,
+		Message:     message,
+	})
+}
+
+var errUnknownAlertChannelType = errors.New("type must be one of webhook, ntfy, gotify or smtp")
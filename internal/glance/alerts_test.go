@@ -0,0 +1,183 @@
+package glance
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+type recordingAlertChannel struct {
+	notifications chan *alertNotification
+}
+
+func (c *recordingAlertChannel) send(_ context.Context, n *alertNotification) error {
+	c.notifications <- n
+	return nil
+}
+
+// Collects what was sent until the channel goes quiet, sorted since
+// notifications are delivered concurrently
+func receivedAlertTitles(channel *recordingAlertChannel) []string {
+	var titles []string
+
+	for {
+		select {
+		case n := <-channel.notifications:
+			titles = append(titles, n.title())
+		case <-time.After(50 * time.Millisecond):
+			slices.Sort(titles)
+			return titles
+		}
+	}
+}
+
+func TestAlertDispatcherTransitions(t *testing.T) {
+	channel := &recordingAlertChannel{notifications: make(chan *alertNotification, 10)}
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	dispatcher := &alertDispatcher{
+		rules: []*alertRule{{
+			Name:           "sites",
+			Types:          []string{"monitor"},
+			Subjects:       []string{"Jelly*"},
+			Channels:       []string{"test"},
+			RepeatInterval: durationField(time.Hour),
+		}},
+		channels: map[string]alertChannel{"test": channel},
+		now:      func() time.Time { return now },
+		states:   newAlertStates(),
+	}
+
+	tests := []struct {
+		description string
+		elapsed     time.Duration
+		subject     string
+		failing     bool
+		expected    []string
+	}{
+		{"initial healthy state", 0, "Jellyfin", false, nil},
+		{"going down", 0, "Jellyfin", true, []string{"Jellyfin is failing"}},
+		{"still down before the repeat interval", 0, "Jellyfin", true, nil},
+		{"subject not matched by the rule", 0, "Sonarr", true, nil},
+		{"repeat interval elapsed", time.Hour, "Jellyfin", true, []string{"Jellyfin is still failing"}},
+		{"recovery", 0, "Jellyfin", false, []string{"Jellyfin has recovered"}},
+		{"still healthy", 0, "Jellyfin", false, nil},
+	}
+
+	for _, test := range tests {
+		now = now.Add(test.elapsed)
+		dispatcher.emit(alertEvent{WidgetID: 1, WidgetType: "monitor", WidgetTitle: "Monitor", Subject: test.subject, Failing: test.failing})
+
+		if titles := receivedAlertTitles(channel); !slices.Equal(titles, test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.description, test.expected, titles)
+		}
+	}
+}
+
+func TestAlertChannelsConfigValidation(t *testing.T) {
+	tests := []struct {
+		description string
+		types       []string
+		channels    []string
+		valid       bool
+	}{
+		{"widget type which doesn't emit alerts", []string{"rss"}, []string{"hook"}, false},
+		{"unknown channel", []string{"tailscale"}, []string{"missing"}, false},
+		{"valid rule", []string{"tailscale", "kubernetes"}, []string{"hook"}, true},
+	}
+
+	for _, test := range tests {
+		config := &alertsConfig{
+			Channels: []*alertChannelConfig{{Name: "hook", Type: "webhook", URL: "https://example.com"}},
+			Rules:    []*alertRule{{Channels: test.channels, Types: test.types}},
+		}
+
+		if err := config.validate(); (err == nil) != test.valid {
+			t.Errorf("%s: expected valid to be %v, got error %v", test.description, test.valid, err)
+		}
+	}
+}
+
+func TestWebhookAlertChannel(t *testing.T) {
+	received := make(chan map[string]any, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]any
+		json.NewDecoder(r.Body).Decode(&payload)
+		received <- payload
+	}))
+	defer server.Close()
+
+	channel, err := newAlertChannel(&alertChannelConfig{Type: "webhook", URL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = channel.send(context.Background(), &alertNotification{Subject: "nas", Recovered: true, WidgetTitle: "Tailscale"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload := <-received
+	if payload["status"] != "recovered" || payload["title"] != "nas has recovered" {
+		t.Errorf("unexpected payload %v", payload)
+	}
+}
+
+func TestAlertStatesSurviveReloadsAndMissingSubjectsRecover(t *testing.T) {
+	previous := alertStatesAcrossReloads
+	alertStatesAcrossReloads = newAlertStates()
+	t.Cleanup(func() { alertStatesAcrossReloads = previous })
+
+	channel := &recordingAlertChannel{notifications: make(chan *alertNotification, 10)}
+	config := &alertsConfig{
+		Channels: []*alertChannelConfig{{Name: "test", Type: "webhook", URL: "https://example.com"}},
+		Rules:    []*alertRule{{Name: "containers", Channels: []string{"test"}}},
+	}
+
+	tests := []struct {
+		description string
+		reload      bool
+		incomplete  bool
+		failing     []string
+		expected    []string
+	}{
+		{"first update", true, false, []string{"jellyfin", "sonarr"}, []string{"jellyfin is failing", "sonarr is failing"}},
+		{"reload", true, false, []string{"jellyfin", "sonarr"}, nil},
+		{"container removed", false, false, []string{"jellyfin"}, []string{"sonarr has recovered"}},
+		{"unreachable host", false, true, nil, nil},
+	}
+
+	var widget *widgetBase
+
+	for i, test := range tests {
+		// the dispatcher gets recreated and widget IDs change on every reload
+		if test.reload {
+			dispatcher, err := newAlertDispatcher(config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			dispatcher.channels["test"] = channel
+
+			widget = &widgetBase{ID: uint64(i + 1), Type: "docker-containers", Title: "Containers", Providers: &widgetProviders{alerts: dispatcher}}
+		}
+
+		widget.Notice = nil
+		if test.incomplete {
+			widget.Notice = errPartialContent
+		}
+
+		widget.beginAlertEvents()
+		for _, subject := range test.failing {
+			widget.emitAlertEvent(subject, true, "")
+		}
+		widget.finishAlertEvents()
+
+		if titles := receivedAlertTitles(channel); !slices.Equal(titles, test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.description, test.expected, titles)
+		}
+	}
+}
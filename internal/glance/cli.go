@@ -1,10 +1,13 @@
 package glance
 
 import (
+	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/shirou/gopsutil/v4/disk"
 	"github.com/shirou/gopsutil/v4/sensors"
@@ -17,17 +20,24 @@ const (
 	cliIntentServe
 	cliIntentConfigValidate
 	cliIntentConfigPrint
+	cliIntentConfigSchema
 	cliIntentDiagnose
 	cliIntentSensorsPrint
 	cliIntentMountpointInfo
 	cliIntentSecretMake
 	cliIntentPasswordHash
+	cliIntentWidgetRun
+	cliIntentAgent
 )
 
 type cliOptions struct {
-	intent     cliIntent
-	configPath string
-	args       []string
+	intent      cliIntent
+	configPath  string
+	args        []string
+	configPrint configPrintOptions
+	widgetRun   widgetRunOptions
+	recordDir   string
+	replayDir   string
 }
 
 func parseCliOptions() (*cliOptions, error) {
@@ -50,6 +60,14 @@ func parseCliOptions() (*cliOptions, error) {
 		fmt.Println("\nPolecenia:")
 		fmt.Println("  config:validate       Sprawdzenie poprawności pliku konfiguracyjnego")
 		fmt.Println("  config:print          Wyświetlenie sparsowanego pliku konfiguracyjnego z wbudowanymi include'ami")
+		fmt.Println("    --resolved          Z podstawionymi zmiennymi i zamaskowanymi sekretami")
+		fmt.Println("    --json              Jako JSON w pełni zdekodowanej konfiguracji")
+		fmt.Println("  config:schema         Wygenerowanie JSON Schema pliku konfiguracyjnego")
+		fmt.Println("  widget:run            Uruchomienie aktualizacji jednego widgetu i wyświetlenie jego danych")
+		fmt.Println("    --page <slug>       Strona, na której znajduje się widget (domyślnie pierwsza)")
+		fmt.Println("    --index <n>         Pozycja widgetu na stronie, licząc od 1")
+		fmt.Println("    --title <title>     Tytuł widgetu")
+		fmt.Println("  agent                 Uruchomienie agenta udostępniającego informacje o systemie dla widgetu server-stats")
 		fmt.Println("  password:hash <pwd>   Zahashowanie hasła")
 		fmt.Println("  secret:make           Wygenerowanie losowego tajnego klucza")
 		fmt.Println("  sensors:print         Wyświetlenie wszystkich czujników")
@@ -58,28 +76,69 @@ func parseCliOptions() (*cliOptions, error) {
 	}
 
 	configPath := flags.String("config", "glance.yml", "Set config path")
+	recordDir := flags.String("record-dir", "", "Save responses from upstream services to this directory")
+	replayDir := flags.String("replay-dir", "", "Serve previously recorded responses from this directory instead of the network")
 	err := flags.Parse(os.Args[1:])
 	if err != nil {
 		return nil, err
 	}
 
+	if *recordDir != "" && *replayDir != "" {
+		return nil, fmt.Errorf("--record-dir and --replay-dir can not be used together")
+	}
+
 	var intent cliIntent
+	var configPrint configPrintOptions
+	var widgetRun widgetRunOptions
 	args = flags.Args()
 	unknownCommandErr := fmt.Errorf("unknown command: %s", strings.Join(args, " "))
 
 	if len(args) == 0 {
 		intent = cliIntentServe
+	} else if args[0] == "config:print" {
+		intent = cliIntentConfigPrint
+
+		printFlags := flag.NewFlagSet("config:print", flag.ContinueOnError)
+		printFlags.BoolVar(&configPrint.resolved, "resolved", false, "Resolve variables and mask secrets")
+		printFlags.BoolVar(&configPrint.asJSON, "json", false, "Print the fully decoded config as JSON")
+		if err := printFlags.Parse(args[1:]); err != nil {
+			return nil, err
+		}
+
+		if printFlags.NArg() > 0 {
+			return nil, unknownCommandErr
+		}
+	} else if args[0] == "widget:run" {
+		intent = cliIntentWidgetRun
+
+		runFlags := flag.NewFlagSet("widget:run", flag.ContinueOnError)
+		runFlags.StringVar(&widgetRun.pageSlug, "page", "", "Slug of the page the widget is on")
+		runFlags.IntVar(&widgetRun.index, "index", 0, "Position of the widget on the page, starting from 1")
+		runFlags.StringVar(&widgetRun.title, "title", "", "Title of the widget")
+		if err := runFlags.Parse(args[1:]); err != nil {
+			return nil, err
+		}
+
+		if runFlags.NArg() > 0 {
+			return nil, unknownCommandErr
+		}
+
+		if (widgetRun.index == 0) == (widgetRun.title == "") {
+			return nil, fmt.Errorf("widget:run requires either --index or --title")
+		}
 	} else if len(args) == 1 {
 		if args[0] == "config:validate" {
 			intent = cliIntentConfigValidate
-		} else if args[0] == "config:print" {
-			intent = cliIntentConfigPrint
+		} else if args[0] == "config:schema" {
+			intent = cliIntentConfigSchema
 		} else if args[0] == "sensors:print" {
 			intent = cliIntentSensorsPrint
 		} else if args[0] == "diagnose" {
 			intent = cliIntentDiagnose
 		} else if args[0] == "secret:make" {
 			intent = cliIntentSecretMake
+		} else if args[0] == "agent" {
+			intent = cliIntentAgent
 		} else {
 			return nil, unknownCommandErr
 		}
@@ -100,9 +159,13 @@ func parseCliOptions() (*cliOptions, error) {
 	}
 
 	return &cliOptions{
-		intent:     intent,
-		configPath: *configPath,
-		args:       args,
+		intent:      intent,
+		configPath:  *configPath,
+		args:        args,
+		configPrint: configPrint,
+		widgetRun:   widgetRun,
+		recordDir:   *recordDir,
+		replayDir:   *replayDir,
 	}, nil
 }
 
@@ -153,3 +216,134 @@ func cliMountpointInfo(requestedPath string) int {
 
 	return 0
 }
+
+type widgetRunOptions struct {
+	pageSlug string
+	index    int
+	title    string
+}
+
+func cliWidgetRun(configPath string, options widgetRunOptions) int {
+	contents, sources, _, err := parseYAMLIncludesWithSources(configPath)
+	if err != nil {
+		fmt.Printf("Could not parse config file: %v\n", err)
+		return 1
+	}
+
+	config, err := newConfigFromYAMLWithSources(contents, sources, false)
+	if err != nil {
+		fmt.Printf("Config file is invalid: %v\n", err)
+		return 1
+	}
+
+	app, err := newApplication(config)
+	if err != nil {
+		fmt.Printf("Failed to create application: %v\n", err)
+		return 1
+	}
+
+	page, exists := app.slugToPage[options.pageSlug]
+	if !exists {
+		fmt.Printf("Page with slug %s not found\n", options.pageSlug)
+		return 1
+	}
+
+	// widgets inside of groups and split columns come right after their parent
+	var pageWidgets widgets
+	addWidgets := func(ws widgets) {
+		for _, w := range ws {
+			pageWidgets = append(pageWidgets, w)
+			pageWidgets = append(pageWidgets, widgetDescendants(w)...)
+		}
+	}
+
+	addWidgets(page.HeadWidgets)
+	for c := range page.Columns {
+		addWidgets(page.Columns[c].Widgets)
+	}
+
+	var w widget
+	if options.index > 0 {
+		if options.index > len(pageWidgets) {
+			fmt.Printf("Page %s only has %d widgets\n", page.Title, len(pageWidgets))
+			return 1
+		}
+		w = pageWidgets[options.index-1]
+	} else {
+		for _, candidate := range pageWidgets {
+			if titled, ok := candidate.(interface{ getTitle() string }); ok && strings.EqualFold(titled.getTitle(), options.title) {
+				w = candidate
+				break
+			}
+		}
+
+		if w == nil {
+			fmt.Printf("No widget titled %s found on page %s\n", options.title, page.Title)
+			return 1
+		}
+	}
+
+	sensitiveValues, err := sensitiveConfigVariableValues(contents)
+	if err != nil {
+		fmt.Printf("Could not resolve config variables: %v\n", err)
+		return 1
+	}
+
+	debugAPITiming = true
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	start := time.Now()
+	w.setUpdating(true)
+	updateWidget(ctx, w)
+	w.setUpdating(false)
+	duration := time.Since(start)
+
+	result := struct {
+		Type       string `json:"type"`
+		ID         uint64 `json:"id"`
+		DurationMS int64  `json:"duration-ms"`
+		Error      string `json:"error,omitempty"`
+		Notice     string `json:"notice,omitempty"`
+		Data       any    `json:"data"`
+		DataError  string `json:"data-error,omitempty"`
+		HTML       string `json:"html"`
+	}{
+		Type:       w.GetType(),
+		ID:         w.GetID(),
+		DurationMS: duration.Milliseconds(),
+		HTML:       redactSensitiveString(string(w.Render()), sensitiveValues),
+	}
+
+	if withErrors, ok := w.(interface{ getErrors() (error, error) }); ok {
+		err, notice := withErrors.getErrors()
+		if err != nil {
+			result.Error = redactSensitiveString(err.Error(), sensitiveValues)
+		}
+		if notice != nil {
+			result.Notice = redactSensitiveString(notice.Error(), sensitiveValues)
+		}
+	}
+
+	// not every widget's data is guaranteed to be serializable, i.e. if it holds
+	// on to an http.Client, in which case the rendered HTML is still useful.
+	// Credentials get masked the same way as with config:print
+	if data, err := json.Marshal(w); err != nil {
+		result.DataError = err.Error()
+	} else if data, err = redactSensitiveJSON(data, sensitiveValues); err != nil {
+		result.DataError = err.Error()
+	} else {
+		result.Data = json.RawMessage(data)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(result); err != nil {
+		fmt.Printf("Failed to encode result: %v\n", err)
+		return 1
+	}
+
+	return 0
+}
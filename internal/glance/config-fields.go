@@ -93,10 +93,31 @@ func (c *hslColorField) UnmarshalYAML(node *yaml.Node) error {
 	return nil
 }
 
+func (c hslColorField) MarshalYAML() (any, error) {
+	return fmt.Sprintf("%g %g %g", c.H, c.S, c.L), nil
+}
+
 var durationFieldPattern = regexp.MustCompile(`^(\d+)(s|m|h|d)$`)
 
 type durationField time.Duration
 
+func (d durationField) MarshalYAML() (any, error) {
+	duration := time.Duration(d)
+
+	switch {
+	case duration == 0:
+		return nil, nil
+	case duration%(24*time.Hour) == 0:
+		return fmt.Sprintf("%dd", duration/(24*time.Hour)), nil
+	case duration%time.Hour == 0:
+		return fmt.Sprintf("%dh", duration/time.Hour), nil
+	case duration%time.Minute == 0:
+		return fmt.Sprintf("%dm", duration/time.Minute), nil
+	}
+
+	return fmt.Sprintf("%ds", duration/time.Second), nil
+}
+
 func (d *durationField) UnmarshalYAML(node *yaml.Node) error {
 	var value string
 
@@ -225,7 +246,7 @@ func (p *proxyOptionsField) UnmarshalYAML(node *yaml.Node) error {
 
 	p.client = &http.Client{
 		Timeout: timeout,
-		Transport: &userAgentTransport{
+		Transport: newUpstreamTransport(&userAgentTransport{
 			underlying: &http.Transport{
 				MaxIdleConns:        maxIdleConns,
 				MaxIdleConnsPerHost: maxIdleConnsPerHost,
@@ -235,7 +256,7 @@ func (p *proxyOptionsField) UnmarshalYAML(node *yaml.Node) error {
 				Proxy:               http.ProxyURL(parsedUrl),
 				TLSClientConfig:     &tls.Config{InsecureSkipVerify: p.AllowInsecure},
 			},
-		},
+		}),
 	}
 
 	return nil
@@ -0,0 +1,200 @@
+package glance
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const configRedactedValue = "<redacted>"
+
+var sensitiveConfigKeyPattern = regexp.MustCompile(`(?i)(token|password|secret|api-?key|private-?key|service-?account-?key)`)
+
+type configPrintOptions struct {
+	resolved bool
+	asJSON   bool
+}
+
+func cliConfigPrint(configPath string, options configPrintOptions) int {
+	contents, _, err := parseYAMLIncludes(configPath)
+	if err != nil {
+		fmt.Printf("Could not parse config file: %v\n", err)
+		return 1
+	}
+
+	if !options.resolved && !options.asJSON {
+		fmt.Println(string(contents))
+		return 0
+	}
+
+	var output []byte
+	if options.asJSON {
+		output, err = decodedConfigAsRedactedJSON(contents)
+	} else {
+		output, err = resolvedConfigAsRedactedYAML(contents)
+	}
+
+	if err != nil {
+		fmt.Printf("Could not print config: %v\n", err)
+		return 1
+	}
+
+	fmt.Println(strings.TrimSpace(string(output)))
+	return 0
+}
+
+// Returns the config with all variables resolved, values of sensitive variables and
+// properties with names like token or password are masked
+func resolvedConfigAsRedactedYAML(contents []byte) ([]byte, error) {
+	resolved, err := resolveConfigVariables(contents, func(variableType, value string) string {
+		if isConfigVariableTypeSensitive(variableType) {
+			return configRedactedValue
+		}
+		return value
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(resolved, &root); err != nil {
+		return nil, err
+	}
+
+	redactSensitiveConfigNodes(&root, nil)
+
+	return encodeYAMLNode(&root)
+}
+
+// Returns the fully decoded config, including widget defaults and the values that
+// widgets fill in when they're initialized, with sensitive values masked
+func decodedConfigAsRedactedJSON(contents []byte) ([]byte, error) {
+	sensitiveValues, err := sensitiveConfigVariableValues(contents)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := newConfigFromYAML(contents)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, err := yaml.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(encoded, &root); err != nil {
+		return nil, err
+	}
+
+	redactSensitiveConfigNodes(&root, sensitiveValues)
+
+	return redactedNodeAsJSON(&root)
+}
+
+// The resolved values of variables such as secret or sops, which have to be
+// masked wherever they show up
+func sensitiveConfigVariableValues(contents []byte) ([]string, error) {
+	var sensitiveValues []string
+	_, err := resolveConfigVariables(contents, func(variableType, value string) string {
+		if isConfigVariableTypeSensitive(variableType) && value != "" {
+			sensitiveValues = append(sensitiveValues, value)
+		}
+		return value
+	})
+
+	return sensitiveValues, err
+}
+
+// Masks the same values as config:print in arbitrary JSON, such as a widget
+// serialized by widget:run, whose keys are the names of the Go fields
+func redactSensitiveJSON(data []byte, sensitiveValues []string) ([]byte, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+
+	redactSensitiveConfigNodes(&root, sensitiveValues)
+
+	return redactedNodeAsJSON(&root)
+}
+
+func redactSensitiveString(value string, sensitiveValues []string) string {
+	for _, sensitive := range sensitiveValues {
+		value = strings.ReplaceAll(value, sensitive, configRedactedValue)
+	}
+
+	return value
+}
+
+func redactedNodeAsJSON(root *yaml.Node) ([]byte, error) {
+	var decoded any
+	if err := root.Decode(&decoded); err != nil {
+		return nil, err
+	}
+
+	var buffer bytes.Buffer
+
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(decoded); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func redactSensitiveConfigNodes(node *yaml.Node, sensitiveValues []string) {
+	switch node.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, child := range node.Content {
+			redactSensitiveConfigNodes(child, sensitiveValues)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+
+			if value.Kind == yaml.ScalarNode && value.Value != "" && value.Tag != "!!null" && sensitiveConfigKeyPattern.MatchString(key.Value) {
+				redactYAMLScalar(value, configRedactedValue)
+				continue
+			}
+
+			redactSensitiveConfigNodes(value, sensitiveValues)
+		}
+	case yaml.ScalarNode:
+		if redacted := redactSensitiveString(node.Value, sensitiveValues); redacted != node.Value {
+			redactYAMLScalar(node, redacted)
+		}
+	}
+}
+
+func redactYAMLScalar(node *yaml.Node, value string) {
+	node.Value = value
+	node.Tag = "!!str"
+	node.Style = 0
+}
+
+func encodeYAMLNode(node *yaml.Node) ([]byte, error) {
+	var buffer bytes.Buffer
+
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+
+	if err := encoder.Encode(node); err != nil {
+		return nil, err
+	}
+
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,73 @@
+package glance
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfigPrintRedactsSecrets(t *testing.T) {
+	secretPath := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(secretPath, []byte("hunter22\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GLANCE_TEST_TOKEN", "ghp_visible_in_env")
+	t.Setenv("GLANCE_TEST_HOST", "example.com")
+
+	contents := []byte(strings.Join([]string{
+		"pages:",
+		"  - name: Home",
+		"    columns:",
+		"      - size: full",
+		"        widgets:",
+		"          - type: releases",
+		"            token: ${GLANCE_TEST_TOKEN}",
+		"            repositories: [glanceapp/glance]",
+		"          - type: iframe",
+		"            source: https://${GLANCE_TEST_HOST}/${file:" + secretPath + "}",
+	}, "\n"))
+
+	for name, print := range map[string]func([]byte) ([]byte, error){
+		"resolved": resolvedConfigAsRedactedYAML,
+		"json":     decodedConfigAsRedactedJSON,
+	} {
+		output, err := print(contents)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		for _, leaked := range []string{"hunter22", "ghp_visible_in_env"} {
+			if strings.Contains(string(output), leaked) {
+				t.Errorf("%s: output contains %q:\n%s", name, leaked, output)
+			}
+		}
+
+		if !strings.Contains(string(output), "https://example.com/"+configRedactedValue) {
+			t.Errorf("%s: expected non-sensitive variables to be resolved:\n%s", name, output)
+		}
+	}
+}
+
+func TestWidgetRunOutputIsRedacted(t *testing.T) {
+	widget := &beszelWidget{URL: "https://beszel.lan/?key=from-sops", Token: "jwt", Password: "hunter22"}
+	monitor := &monitorWidget{Sites: []monitorSite{{SiteStatusRequest: &SiteStatusRequest{DefaultURL: "https://nas.lan"}}}}
+	monitor.Sites[0].BasicAuth.Password = "basic"
+
+	data, err := json.Marshal([]any{widget, monitor})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	redacted, err := redactSensitiveJSON(data, []string{"from-sops"})
+	if err != nil {
+		t.Fatalf("redacting: %v", err)
+	}
+
+	for _, secret := range []string{"jwt", "hunter22", "basic", "from-sops"} {
+		if strings.Contains(string(redacted), secret) {
+			t.Errorf("expected %s to be redacted from %s", secret, redacted)
+		}
+	}
+}
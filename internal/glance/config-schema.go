@@ -0,0 +1,217 @@
+package glance
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"reflect"
+)
+
+// Used to describe the structure of glance.yml so that editors can provide
+// completion and validation through the YAML language server
+type jsonSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	Ref                  string                 `json:"$ref,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Type                 any                    `json:"type,omitempty"`
+	Pattern              string                 `json:"pattern,omitempty"`
+	Enum                 []string               `json:"enum,omitempty"`
+	Const                string                 `json:"const,omitempty"`
+	Properties           map[string]*jsonSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AdditionalProperties any                    `json:"additionalProperties,omitempty"`
+	Items                *jsonSchema            `json:"items,omitempty"`
+	OneOf                []*jsonSchema          `json:"oneOf,omitempty"`
+	AnyOf                []*jsonSchema          `json:"anyOf,omitempty"`
+	AllOf                []*jsonSchema          `json:"allOf,omitempty"`
+	If                   *jsonSchema            `json:"if,omitempty"`
+	Then                 *jsonSchema            `json:"then,omitempty"`
+	Defs                 map[string]*jsonSchema `json:"$defs,omitempty"`
+}
+
+func configSchemaStringOrList() *jsonSchema {
+	return &jsonSchema{OneOf: []*jsonSchema{
+		{Type: "string"},
+		{Type: []string{"number", "boolean"}},
+		{Type: "array", Items: &jsonSchema{Type: []string{"string", "number", "boolean"}}},
+	}}
+}
+
+// Values such as ${PORT} only get resolved when the config is loaded, so
+// they're accepted in place of numbers and booleans
+func configSchemaScalar(scalarType string) *jsonSchema {
+	return &jsonSchema{AnyOf: []*jsonSchema{
+		{Type: scalarType},
+		{Type: "string", Pattern: `^\$\{.+\}$`},
+	}}
+}
+
+var configSchemaInclude = &jsonSchema{
+	Type:                 "object",
+	Required:             []string{"$include"},
+	Properties:           map[string]*jsonSchema{"$include": {Type: "string"}},
+	AdditionalProperties: false,
+}
+
+// Lists can be made up of, or entirely replaced with, $include directives
+func configSchemaArray(items *jsonSchema) *jsonSchema {
+	return &jsonSchema{AnyOf: []*jsonSchema{
+		{Type: "array", Items: &jsonSchema{AnyOf: []*jsonSchema{items, {Ref: "#/$defs/include"}}}},
+		{Ref: "#/$defs/include"},
+	}}
+}
+
+type configSchemaGenerator struct {
+	defs     map[string]*jsonSchema
+	visiting map[reflect.Type]bool
+}
+
+func generateConfigSchema() ([]byte, error) {
+	g := &configSchemaGenerator{
+		defs:     make(map[string]*jsonSchema),
+		visiting: make(map[reflect.Type]bool),
+	}
+
+	schema := g.schemaOf(reflect.TypeFor[config]())
+	schema.Schema = "https://json-schema.org/draft/2020-12/schema"
+	schema.Title = "Glance configuration"
+	g.defs["widgets"] = g.widgetsSchema()
+	g.defs["include"] = configSchemaInclude
+	schema.Defs = g.defs
+
+	widgetDefaults := &jsonSchema{
+		Type:                 "object",
+		Properties:           make(map[string]*jsonSchema),
+		AdditionalProperties: false,
+	}
+	for _, widgetType := range registeredWidgetTypes() {
+		widgetDefaults.Properties[widgetType] = &jsonSchema{Ref: "#/$defs/widget-" + widgetType}
+	}
+	schema.Properties["widget-defaults"] = widgetDefaults
+
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+func (g *configSchemaGenerator) widgetsSchema() *jsonSchema {
+	types := registeredWidgetTypes()
+
+	item := &jsonSchema{
+		Type:       "object",
+		Required:   []string{"type"},
+		Properties: map[string]*jsonSchema{"type": {Type: "string", Enum: types}},
+	}
+
+	for _, widgetType := range types {
+		t, ok := widgetTypeOf(widgetType)
+		if !ok {
+			continue
+		}
+
+		name := "widget-" + widgetType
+		g.defs[name] = g.schemaOf(t)
+
+		item.AllOf = append(item.AllOf, &jsonSchema{
+			If: &jsonSchema{
+				Properties: map[string]*jsonSchema{"type": {Const: widgetType}},
+				Required:   []string{"type"},
+			},
+			Then: &jsonSchema{Ref: "#/$defs/" + name},
+		})
+	}
+
+	return configSchemaArray(item)
+}
+
+// Handles types which implement their own unmarshaling and can't be described through their fields
+func (g *configSchemaGenerator) customSchemaOf(t reflect.Type) (*jsonSchema, bool) {
+	switch t {
+	case reflect.TypeFor[durationField]():
+		return &jsonSchema{Type: "string", Pattern: durationFieldPattern.String()}, true
+	case reflect.TypeFor[hslColorField]():
+		return &jsonSchema{Type: "string", Pattern: hslColorFieldPattern.String()}, true
+	case reflect.TypeFor[customIconField](), reflect.TypeFor[template.HTML]():
+		return &jsonSchema{Type: "string"}, true
+	case reflect.TypeFor[queryParametersField]():
+		return &jsonSchema{Type: "object", AdditionalProperties: configSchemaStringOrList()}, true
+	case reflect.TypeFor[proxyOptionsField](), reflect.TypeFor[releaseRequest]():
+		// accept either a string or the full struct
+		return &jsonSchema{OneOf: []*jsonSchema{{Type: "string"}, g.structSchema(t)}}, true
+	case widgetsType:
+		return &jsonSchema{Ref: "#/$defs/widgets"}, true
+	}
+
+	return nil, false
+}
+
+func (g *configSchemaGenerator) schemaOf(t reflect.Type) *jsonSchema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if custom, ok := g.customSchemaOf(t); ok {
+		return custom
+	}
+
+	if ordered, ok := reflect.New(t).Interface().(yamlMappingOfType); ok {
+		return &jsonSchema{Type: "object", AdditionalProperties: g.schemaOf(ordered.yamlValueType())}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return configSchemaScalar("boolean")
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return configSchemaScalar("integer")
+	case reflect.Float32, reflect.Float64:
+		return configSchemaScalar("number")
+	case reflect.String:
+		return &jsonSchema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return configSchemaArray(g.schemaOf(t.Elem()))
+	case reflect.Map:
+		return &jsonSchema{Type: "object", AdditionalProperties: g.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if reflect.PointerTo(t).Implements(yamlUnmarshalerType) && !hasYAMLTags(t) {
+			return &jsonSchema{}
+		}
+
+		return g.structSchema(t)
+	}
+
+	return &jsonSchema{}
+}
+
+func (g *configSchemaGenerator) structSchema(t reflect.Type) *jsonSchema {
+	if g.visiting[t] {
+		return &jsonSchema{Type: "object"}
+	}
+
+	g.visiting[t] = true
+	defer delete(g.visiting, t)
+
+	schema := &jsonSchema{
+		Type:                 "object",
+		Properties:           make(map[string]*jsonSchema),
+		AdditionalProperties: false,
+	}
+
+	for name, field := range yamlFieldsOfStruct(t) {
+		schema.Properties[name] = g.schemaOf(field.Type)
+	}
+
+	// the contents of the included file get merged into the object
+	schema.Properties["$include"] = &jsonSchema{Type: "string"}
+
+	return schema
+}
+
+func cliConfigSchemaPrint() int {
+	schema, err := generateConfigSchema()
+	if err != nil {
+		fmt.Printf("Failed to generate schema: %v\n", err)
+		return 1
+	}
+
+	fmt.Println(string(schema))
+	return 0
+}
@@ -0,0 +1,49 @@
+package glance
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestConfigSchemaDescribesEveryWidget(t *testing.T) {
+	contents, err := generateConfigSchema()
+	if err != nil {
+		t.Fatalf("generating schema: %v", err)
+	}
+
+	var schema jsonSchema
+	if err := json.Unmarshal(contents, &schema); err != nil {
+		t.Fatalf("schema is not valid JSON: %v", err)
+	}
+
+	for _, widgetType := range registeredWidgetTypes() {
+		definition, ok := schema.Defs["widget-"+widgetType]
+		if !ok {
+			t.Errorf("missing definition for %s widget", widgetType)
+			continue
+		}
+
+		if _, ok := definition.Properties["type"]; !ok {
+			t.Errorf("definition for %s widget is missing the type property", widgetType)
+		}
+	}
+
+	releases := schema.Defs["widget-releases"]
+	if cache := releases.Properties["cache"]; cache == nil || cache.Type != "string" || cache.Pattern == "" {
+		t.Errorf("expected cache to be described as a duration string, got %+v", cache)
+	}
+
+	pages := schema.Properties["pages"]
+	if pages == nil || len(pages.AnyOf) != 2 || pages.AnyOf[1].Ref != "#/$defs/include" || pages.AnyOf[0].Items == nil || len(pages.AnyOf[0].Items.AnyOf) != 2 {
+		t.Errorf("expected pages to accept $include directives, got %+v", pages)
+	}
+
+	if include := schema.Defs["include"]; include == nil || include.Properties["$include"] == nil {
+		t.Errorf("missing definition for $include directives")
+	}
+
+	collapseAfter := schema.Defs["widget-releases"].Properties["collapse-after"]
+	if collapseAfter == nil || len(collapseAfter.AnyOf) != 2 || collapseAfter.AnyOf[0].Type != "integer" || collapseAfter.AnyOf[1].Pattern == "" {
+		t.Errorf("expected integers to also accept variables, got %+v", collapseAfter)
+	}
+}
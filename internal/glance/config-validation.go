@@ -0,0 +1,315 @@
+package glance
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Identifies the file and line that a line of the flattened config originated from
+type configSourceLine struct {
+	file string
+	line int
+}
+
+// Indexed by the line number of the flattened config minus one
+type configSourceMap []configSourceLine
+
+func (m configSourceMap) locate(line int) string {
+	if line <= 0 {
+		return ""
+	}
+
+	if line > len(m) {
+		return fmt.Sprintf("line %d", line)
+	}
+
+	source := m[line-1]
+	return fmt.Sprintf("%s:%d", source.file, source.line)
+}
+
+var yamlErrorLinePattern = regexp.MustCompile(`line (\d+)`)
+
+// Rewrites the line numbers in errors returned by the YAML parser to point at the original files
+func (m configSourceMap) mapYAMLError(err error) error {
+	if m == nil || err == nil {
+		return err
+	}
+
+	return fmt.Errorf("%s", yamlErrorLinePattern.ReplaceAllStringFunc(err.Error(), func(match string) string {
+		line, _ := strconv.Atoi(match[len("line "):])
+		return m.locate(line)
+	}))
+}
+
+type configError struct {
+	line int
+	err  error
+}
+
+// Collects every error found in the config so that they can be reported at once
+type configErrors struct {
+	sources configSourceMap
+	errors  []configError
+}
+
+func (e *configErrors) add(line int, err error) {
+	e.errors = append(e.errors, configError{line: line, err: err})
+}
+
+var yamlTypeErrorPattern = regexp.MustCompile(`^line (\d+): (.*)$`)
+
+func (e *configErrors) addYAMLTypeError(typeErr *yaml.TypeError) {
+	for _, message := range typeErr.Errors {
+		if matches := yamlTypeErrorPattern.FindStringSubmatch(message); matches != nil {
+			line, _ := strconv.Atoi(matches[1])
+			e.add(line, fmt.Errorf("%s", matches[2]))
+		} else {
+			e.add(0, fmt.Errorf("%s", message))
+		}
+	}
+}
+
+func (e *configErrors) empty() bool {
+	return len(e.errors) == 0
+}
+
+func (e *configErrors) Error() string {
+	errs := slices.Clone(e.errors)
+	slices.SortStableFunc(errs, func(a, b configError) int {
+		return a.line - b.line
+	})
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "found %d error(s):", len(errs))
+
+	for _, err := range errs {
+		builder.WriteString("\n  ")
+
+		if err.line > 0 {
+			if e.sources != nil {
+				builder.WriteString(e.sources.locate(err.line))
+			} else {
+				fmt.Fprintf(&builder, "line %d", err.line)
+			}
+			builder.WriteString(": ")
+		}
+
+		builder.WriteString(err.err.Error())
+	}
+
+	return builder.String()
+}
+
+type unknownConfigKey struct {
+	key    string
+	within string
+	line   int
+}
+
+var (
+	yamlUnmarshalerType = reflect.TypeFor[yaml.Unmarshaler]()
+	widgetsType         = reflect.TypeFor[widgets]()
+)
+
+// Implemented by types which decode a mapping with arbitrary keys into values of the returned type
+type yamlMappingOfType interface {
+	yamlValueType() reflect.Type
+}
+
+// Walks the node tree alongside the type it decodes into and reports every mapping
+// key that would otherwise be silently ignored by the YAML decoder
+func findUnknownConfigKeys(node *yaml.Node, t reflect.Type) []unknownConfigKey {
+	var unknown []unknownConfigKey
+
+	walker := &configNodeWalker{
+		onUnknownKey: func(key *yaml.Node, within string) {
+			unknown = append(unknown, unknownConfigKey{key: key.Value, within: within, line: key.Line})
+		},
+	}
+	walker.walk(node, t, "config")
+
+	return unknown
+}
+
+// Walks a node tree alongside the type that it decodes into
+type configNodeWalker struct {
+	onUnknownKey func(key *yaml.Node, within string)
+	// called before walking the node of each widget
+	onWidget func(node *yaml.Node, widgetType string)
+}
+
+func (cw *configNodeWalker) walk(node *yaml.Node, t reflect.Type, within string) {
+	if node == nil {
+		return
+	}
+
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			cw.walk(child, t, within)
+		}
+		return
+	case yaml.AliasNode:
+		cw.walk(node.Alias, t, within)
+		return
+	}
+
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t == widgetsType {
+		if node.Kind != yaml.SequenceNode {
+			return
+		}
+
+		for _, item := range node.Content {
+			meta := struct {
+				Type string `yaml:"type"`
+			}{}
+
+			if item.Decode(&meta) != nil {
+				continue
+			}
+
+			widgetType, ok := widgetTypeOf(meta.Type)
+			if !ok {
+				continue
+			}
+
+			if cw.onWidget != nil {
+				cw.onWidget(item, meta.Type)
+			}
+
+			cw.walk(item, widgetType, meta.Type+" widget")
+		}
+
+		return
+	}
+
+	if ordered, ok := reflect.New(t).Interface().(yamlMappingOfType); ok {
+		if node.Kind == yaml.MappingNode {
+			for i := 1; i < len(node.Content); i += 2 {
+				cw.walk(node.Content[i], ordered.yamlValueType(), within)
+			}
+		}
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+
+		// types with their own unmarshaling logic that don't decode into their fields
+		if reflect.PointerTo(t).Implements(yamlUnmarshalerType) && !hasYAMLTags(t) {
+			return
+		}
+
+		fields := yamlFieldsOfStruct(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+
+			if key.Value == "<<" {
+				cw.walk(value, t, within)
+				continue
+			}
+
+			field, ok := fields[key.Value]
+			if !ok {
+				if cw.onUnknownKey != nil {
+					cw.onUnknownKey(key, within)
+				}
+				continue
+			}
+
+			cw.walk(value, field.Type, within)
+		}
+	case reflect.Slice, reflect.Array:
+		if node.Kind != yaml.SequenceNode {
+			// some fields accept either a single value or a list of values
+			cw.walk(node, t.Elem(), within)
+			return
+		}
+
+		for _, item := range node.Content {
+			cw.walk(item, t.Elem(), within)
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+
+		for i := 1; i < len(node.Content); i += 2 {
+			cw.walk(node.Content[i], t.Elem(), within)
+		}
+	}
+}
+
+func hasYAMLTags(t reflect.Type) bool {
+	for i := range t.NumField() {
+		if _, ok := t.Field(i).Tag.Lookup("yaml"); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func widgetTypeOf(widgetType string) (reflect.Type, bool) {
+	w, err := newWidget(widgetType)
+	if err != nil {
+		return nil, false
+	}
+
+	return reflect.TypeOf(w), true
+}
+
+// Returns the exported fields of a struct keyed by the name they have in the YAML,
+// including the fields of embedded structs marked as inline
+func yamlFieldsOfStruct(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	collectYAMLFieldsOfStruct(t, fields)
+	return fields
+}
+
+func collectYAMLFieldsOfStruct(t reflect.Type, fields map[string]reflect.StructField) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct {
+		return
+	}
+
+	for i := range t.NumField() {
+		field := t.Field(i)
+		name, options, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+
+		if name == "-" {
+			continue
+		}
+
+		if slices.Contains(strings.Split(options, ","), "inline") {
+			collectYAMLFieldsOfStruct(field.Type, fields)
+			continue
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+
+		fields[name] = field
+	}
+}
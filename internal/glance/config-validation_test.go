@@ -0,0 +1,94 @@
+package glance
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfigStrictValidationReportsEveryErrorWithItsSource(t *testing.T) {
+	dir := t.TempDir()
+	mainPath := filepath.Join(dir, "glance.yml")
+
+	writeFile := func(path, contents string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeFile(mainPath, strings.Join([]string{
+		"server:",
+		"  prot: 8080",
+		"pages:",
+		"  - name: Home",
+		"    columns:",
+		"      - size: full",
+		"        widgets:",
+		"          - type: rss",
+		"            colapse-after: 3",
+		"      - $include: column.yml",
+	}, "\n"))
+
+	writeFile(filepath.Join(dir, "column.yml"), strings.Join([]string{
+		"- size: small",
+		"  widgets:",
+		"    - type: clock",
+		"      cach: 1h",
+		"    - type: releases",
+		"      limit: abc",
+	}, "\n"))
+
+	contents, sources, _, err := parseYAMLIncludesWithSources(mainPath)
+	if err != nil {
+		t.Fatalf("parsing includes: %v", err)
+	}
+
+	if len(sources) != strings.Count(string(contents), "\n")+1 {
+		t.Fatalf("expected a source for each of the lines, got %d sources", len(sources))
+	}
+
+	if _, err := newConfigFromYAMLWithSources(contents, sources, false); err == nil {
+		t.Fatal("expected the config to be invalid")
+	}
+
+	_, err = newConfigFromYAMLWithSources(contents, sources, true)
+	if err == nil {
+		t.Fatal("expected the config to be invalid")
+	}
+
+	message := err.Error()
+	expected := []string{
+		mainPath + `:2: unknown key "prot" in config`,
+		mainPath + `:9: unknown key "colapse-after" in rss widget`,
+		filepath.Join(dir, "column.yml") + `:4: unknown key "cach" in clock widget`,
+		filepath.Join(dir, "column.yml") + ":6: cannot unmarshal",
+	}
+
+	for _, e := range expected {
+		if !strings.Contains(message, e) {
+			t.Errorf("expected error to contain %q, got:\n%s", e, message)
+		}
+	}
+}
+
+func TestConfigUnknownKeysAreIgnoredWhenNotStrict(t *testing.T) {
+	contents := strings.Join([]string{
+		"pages:",
+		"  - name: Home",
+		"    columns:",
+		"      - size: full",
+		"        widgets:",
+		"          - type: clock",
+		"            unknown-key: true",
+	}, "\n")
+
+	if _, err := newConfigFromYAML([]byte(contents)); err != nil {
+		t.Fatalf("expected unknown keys to be ignored, got: %v", err)
+	}
+
+	if _, err := newConfigFromYAML([]byte("server:\n  strict: true\n" + contents)); err == nil {
+		t.Fatal("expected unknown keys to be reported when server.strict is enabled")
+	}
+}
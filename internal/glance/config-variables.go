@@ -0,0 +1,515 @@
+package glance
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	configVarTypeEnv         = "env"
+	configVarTypeSecret      = "secret"
+	configVarTypeFileFromEnv = "readFileFromEnv"
+	configVarTypeFile        = "file"
+	configVarTypeCredential  = "credential"
+	configVarTypeSops        = "sops"
+)
+
+// A source of values for ${type:name} variables in the config
+type configVariableProvider interface {
+	// When the bool return value is true, it indicates that the caller should use the original value
+	resolve(name string) (string, bool, error)
+}
+
+type configVariableProviderFunc func(name string) (string, bool, error)
+
+func (f configVariableProviderFunc) resolve(name string) (string, bool, error) {
+	return f(name)
+}
+
+type registeredConfigVariableProvider struct {
+	provider configVariableProvider
+	// whether the values are secrets which should be masked when printing the config
+	sensitive bool
+}
+
+var configVariableProviders = map[string]registeredConfigVariableProvider{}
+
+func registerConfigVariableProvider(variableType string, provider configVariableProvider, sensitive bool) {
+	if _, exists := configVariableProviders[variableType]; exists {
+		panic("config variable provider already registered: " + variableType)
+	}
+
+	configVariableProviders[variableType] = registeredConfigVariableProvider{
+		provider:  provider,
+		sensitive: sensitive,
+	}
+}
+
+func init() {
+	registerConfigVariableProvider(configVarTypeEnv, configVariableProviderFunc(resolveEnvConfigVariable), false)
+	registerConfigVariableProvider(configVarTypeSecret, configVariableProviderFunc(resolveDockerSecretConfigVariable), true)
+	registerConfigVariableProvider(configVarTypeFileFromEnv, configVariableProviderFunc(resolveFileFromEnvConfigVariable), true)
+	registerConfigVariableProvider(configVarTypeFile, configVariableProviderFunc(resolveFileConfigVariable), true)
+	registerConfigVariableProvider(configVarTypeCredential, configVariableProviderFunc(resolveCredentialConfigVariable), true)
+	registerConfigVariableProvider(configVarTypeSops, &sopsConfigVariableProvider{}, true)
+}
+
+// When the bool return value is true, it indicates that the caller should use the original value
+func parseConfigVariableOfType(variableType, variableName string) (string, bool, error) {
+	registered, ok := configVariableProviders[variableType]
+	if !ok {
+		return "", true, nil
+	}
+
+	return registered.provider.resolve(variableName)
+}
+
+func isConfigVariableTypeSensitive(variableType string) bool {
+	return configVariableProviders[variableType].sensitive
+}
+
+func resolveEnvConfigVariable(name string) (string, bool, error) {
+	if !envVariableNamePattern.MatchString(name) {
+		return "", true, nil
+	}
+
+	v, found := os.LookupEnv(name)
+	if !found {
+		return "", false, fmt.Errorf("environment variable %s not found", name)
+	}
+
+	return v, false, nil
+}
+
+func resolveDockerSecretConfigVariable(name string) (string, bool, error) {
+	if strings.ContainsAny(name, `/\`) {
+		return "", false, fmt.Errorf("secret: invalid secret name %s", name)
+	}
+
+	secret, err := os.ReadFile(filepath.Join("/run/secrets", name))
+	if err != nil {
+		return "", false, fmt.Errorf("reading secret file: %v", err)
+	}
+
+	return strings.TrimSpace(string(secret)), false, nil
+}
+
+func resolveFileFromEnvConfigVariable(name string) (string, bool, error) {
+	if !envVariableNamePattern.MatchString(name) {
+		return "", true, nil
+	}
+
+	filePath, found := os.LookupEnv(name)
+	if !found {
+		return "", false, fmt.Errorf("readFileFromEnv: environment variable %s not found", name)
+	}
+
+	if !filepath.IsAbs(filePath) {
+		return "", false, fmt.Errorf("readFileFromEnv: file path %s is not absolute", filePath)
+	}
+
+	fileContents, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", false, fmt.Errorf("readFileFromEnv: reading file from %s: %v", name, err)
+	}
+
+	return strings.TrimSpace(string(fileContents)), false, nil
+}
+
+func resolveFileConfigVariable(name string) (string, bool, error) {
+	if !filepath.IsAbs(name) {
+		return "", false, fmt.Errorf("file: path %s is not absolute", name)
+	}
+
+	fileContents, err := os.ReadFile(name)
+	if err != nil {
+		return "", false, fmt.Errorf("file: %v", err)
+	}
+
+	return strings.TrimSpace(string(fileContents)), false, nil
+}
+
+// Reads credentials passed by systemd through LoadCredential=/SetCredential=,
+// see https://systemd.io/CREDENTIALS/
+func resolveCredentialConfigVariable(name string) (string, bool, error) {
+	directory, found := os.LookupEnv("CREDENTIALS_DIRECTORY")
+	if !found || directory == "" {
+		return "", false, errors.New("credential: CREDENTIALS_DIRECTORY is not set, is glance running as a systemd service with credentials?")
+	}
+
+	if strings.ContainsAny(name, `/\`) {
+		return "", false, fmt.Errorf("credential: invalid credential name %s", name)
+	}
+
+	contents, err := os.ReadFile(filepath.Join(directory, name))
+	if err != nil {
+		return "", false, fmt.Errorf("credential: %v", err)
+	}
+
+	return strings.TrimSpace(string(contents)), false, nil
+}
+
+// Loads values from a YAML file encrypted with sops (https://github.com/getsops/sops)
+// using an age key. The file is specified through GLANCE_SOPS_FILE and the key through
+// SOPS_AGE_KEY_FILE (or SOPS_AGE_KEY), the variable name is a dot separated path to the
+// value within the file, i.e. ${sops:database.password}.
+//
+// Only age recipients are supported. Every value is authenticated through AES-GCM with its
+// path as additional data and the file as a whole through the MAC that sops computes.
+type sopsConfigVariableProvider struct {
+	mu       sync.Mutex
+	checksum [sha256.Size]byte
+	values   map[string]string
+}
+
+var sopsEncryptedValuePattern = regexp.MustCompile(`^ENC\[AES256_GCM,data:([^,]*),iv:([^,]+),tag:([^,]+),type:([a-z]+)\]$`)
+
+func (p *sopsConfigVariableProvider) resolve(name string) (string, bool, error) {
+	filePath := os.Getenv("GLANCE_SOPS_FILE")
+	if filePath == "" {
+		return "", false, errors.New("sops: GLANCE_SOPS_FILE is not set")
+	}
+
+	contents, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", false, fmt.Errorf("sops: %v", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	checksum := sha256.Sum256(contents)
+	if p.values == nil || checksum != p.checksum {
+		identities, err := loadSopsAgeIdentities()
+		if err != nil {
+			return "", false, fmt.Errorf("sops: %v", err)
+		}
+
+		values, err := decryptSopsYAML(contents, identities)
+		if err != nil {
+			return "", false, fmt.Errorf("sops: decrypting %s: %v", filePath, err)
+		}
+
+		p.values = values
+		p.checksum = checksum
+	}
+
+	value, ok := p.values[name]
+	if !ok {
+		return "", false, fmt.Errorf("sops: key %s not found in %s", name, filePath)
+	}
+
+	return value, false, nil
+}
+
+func loadSopsAgeIdentities() ([]age.Identity, error) {
+	if key := os.Getenv("SOPS_AGE_KEY"); key != "" {
+		return age.ParseIdentities(strings.NewReader(key))
+	}
+
+	keyFilePath := os.Getenv("SOPS_AGE_KEY_FILE")
+	if keyFilePath == "" {
+		configDir, err := os.UserConfigDir()
+		if err != nil {
+			return nil, errors.New("neither SOPS_AGE_KEY nor SOPS_AGE_KEY_FILE is set")
+		}
+		keyFilePath = filepath.Join(configDir, "sops", "age", "keys.txt")
+	}
+
+	keyFile, err := os.Open(keyFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("opening age key file: %v", err)
+	}
+	defer keyFile.Close()
+
+	return age.ParseIdentities(keyFile)
+}
+
+type sopsMetadata struct {
+	Age []struct {
+		Recipient string `yaml:"recipient"`
+		Enc       string `yaml:"enc"`
+	} `yaml:"age"`
+	LastModified      string `yaml:"lastmodified"`
+	MAC               string `yaml:"mac"`
+	MACOnlyEncrypted  bool   `yaml:"mac_only_encrypted"`
+	UnencryptedSuffix string `yaml:"unencrypted_suffix"`
+	EncryptedSuffix   string `yaml:"encrypted_suffix"`
+	UnencryptedRegex  string `yaml:"unencrypted_regex"`
+	EncryptedRegex    string `yaml:"encrypted_regex"`
+}
+
+// Mirrors how sops decides which values get encrypted, any key in the path
+// matching one of the rules applies to everything below it
+func (m *sopsMetadata) isUnencrypted(path []string) (bool, error) {
+	matches := func(pattern string, key string) (bool, error) {
+		if pattern == "" {
+			return false, nil
+		}
+		return regexp.MatchString(pattern, key)
+	}
+
+	unencrypted := m.EncryptedSuffix != "" || m.EncryptedRegex != ""
+	for _, key := range path {
+		if m.EncryptedSuffix != "" && strings.HasSuffix(key, m.EncryptedSuffix) {
+			unencrypted = false
+		}
+
+		matched, err := matches(m.EncryptedRegex, key)
+		if err != nil {
+			return false, fmt.Errorf("encrypted_regex: %v", err)
+		}
+		if matched {
+			unencrypted = false
+		}
+	}
+
+	for _, key := range path {
+		if m.UnencryptedSuffix != "" && strings.HasSuffix(key, m.UnencryptedSuffix) {
+			return true, nil
+		}
+
+		matched, err := matches(m.UnencryptedRegex, key)
+		if err != nil {
+			return false, fmt.Errorf("unencrypted_regex: %v", err)
+		}
+		if matched {
+			return true, nil
+		}
+	}
+
+	return unencrypted, nil
+}
+
+// Returns every leaf value in the document keyed by its dot separated path,
+// with the index of items in lists in brackets, i.e. servers[0].password
+//
+// Values are authenticated individually through AES-GCM with their path as
+// additional data and the document as a whole through the MAC stored in the
+// metadata, so that values can't be swapped for plain text or removed.
+func decryptSopsYAML(contents []byte, identities []age.Identity) (map[string]string, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(contents, &document); err != nil {
+		return nil, err
+	}
+
+	if len(document.Content) == 0 || document.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("expected a mapping at the root of the document")
+	}
+	root := document.Content[0]
+
+	var metadata sopsMetadata
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "sops" {
+			if err := root.Content[i+1].Decode(&metadata); err != nil {
+				return nil, fmt.Errorf("decoding sops metadata: %v", err)
+			}
+		}
+	}
+
+	if len(metadata.Age) == 0 {
+		return nil, errors.New("file has no age recipients in its sops metadata")
+	}
+
+	if metadata.MAC == "" {
+		return nil, errors.New("file has no MAC in its sops metadata")
+	}
+
+	var dataKey []byte
+	for _, recipient := range metadata.Age {
+		decrypted, err := age.Decrypt(armor.NewReader(strings.NewReader(recipient.Enc)), identities...)
+		if err != nil {
+			continue
+		}
+
+		dataKey, err = io.ReadAll(decrypted)
+		if err == nil {
+			break
+		}
+	}
+
+	if len(dataKey) != 32 {
+		return nil, errors.New("none of the provided age identities could decrypt the data key")
+	}
+
+	block, err := aes.NewCipher(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := cipher.NewGCMWithNonceSize(block, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string)
+	mac := sha512.New()
+
+	// sopsPath is what sops authenticates values with, which unlike the
+	// name the value is stored under doesn't include the index in lists
+	var walk func(node *yaml.Node, sopsPath []string, name string) error
+	walk = func(node *yaml.Node, sopsPath []string, name string) error {
+		if node.HeadComment != "" || node.LineComment != "" || node.FootComment != "" {
+			return fmt.Errorf("%s: comments are not supported since they can't be verified", ternary(name == "", "root", name))
+		}
+
+		switch node.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				keyNode := node.Content[i]
+				key := keyNode.Value
+				if len(sopsPath) == 0 && key == "sops" {
+					continue
+				}
+
+				if keyNode.HeadComment != "" || keyNode.LineComment != "" || keyNode.FootComment != "" {
+					return fmt.Errorf("%s: comments are not supported since they can't be verified", key)
+				}
+
+				if err := walk(node.Content[i+1], append(slices.Clone(sopsPath), key), ternary(name == "", key, name+"."+key)); err != nil {
+					return err
+				}
+			}
+		case yaml.SequenceNode:
+			for i := range node.Content {
+				if err := walk(node.Content[i], sopsPath, fmt.Sprintf("%s[%d]", name, i)); err != nil {
+					return err
+				}
+			}
+		case yaml.ScalarNode:
+			unencrypted, err := metadata.isUnencrypted(sopsPath)
+			if err != nil {
+				return err
+			}
+
+			var value string
+			if unencrypted {
+				value = sopsScalarBytes(node)
+			} else if node.Value != "" {
+				// sops leaves empty values as they are
+				matches := sopsEncryptedValuePattern.FindStringSubmatch(node.Value)
+				if matches == nil {
+					return fmt.Errorf("%s: value is not encrypted", name)
+				}
+
+				value, err = decryptSopsValue(gcm, matches, strings.Join(sopsPath, ":")+":")
+				if err != nil {
+					return fmt.Errorf("%s: %v", name, err)
+				}
+			}
+
+			if !unencrypted || !metadata.MACOnlyEncrypted {
+				mac.Write([]byte(value))
+			}
+
+			values[name] = value
+		}
+
+		return nil
+	}
+
+	if err := walk(root, nil, ""); err != nil {
+		return nil, err
+	}
+
+	if err := verifySopsMAC(gcm, &metadata, fmt.Sprintf("%X", mac.Sum(nil))); err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+// The representation of unencrypted values that sops includes in the MAC
+func sopsScalarBytes(node *yaml.Node) string {
+	switch node.ShortTag() {
+	case "!!bool":
+		var value bool
+		if node.Decode(&value) == nil {
+			return ternary(value, "True", "False")
+		}
+	case "!!int":
+		var value int
+		if node.Decode(&value) == nil {
+			return strconv.Itoa(value)
+		}
+	case "!!float":
+		var value float64
+		if node.Decode(&value) == nil {
+			return strconv.FormatFloat(value, 'f', -1, 64)
+		}
+	case "!!null":
+		return ""
+	}
+
+	return node.Value
+}
+
+func verifySopsMAC(gcm cipher.AEAD, metadata *sopsMetadata, computed string) error {
+	lastModified, err := time.Parse(time.RFC3339, metadata.LastModified)
+	if err != nil {
+		return fmt.Errorf("parsing lastmodified of the sops metadata: %v", err)
+	}
+
+	matches := sopsEncryptedValuePattern.FindStringSubmatch(metadata.MAC)
+	if matches == nil {
+		return errors.New("MAC in the sops metadata is not encrypted")
+	}
+
+	stored, err := decryptSopsValue(gcm, matches, lastModified.Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("MAC: %v", err)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(stored), []byte(computed)) != 1 {
+		return errors.New("MAC mismatch, the file was modified after it was encrypted")
+	}
+
+	return nil
+}
+
+func decryptSopsValue(gcm cipher.AEAD, matches []string, additionalData string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(matches[1])
+	if err != nil {
+		return "", fmt.Errorf("decoding data: %v", err)
+	}
+
+	iv, err := base64.StdEncoding.DecodeString(matches[2])
+	if err != nil {
+		return "", fmt.Errorf("decoding iv: %v", err)
+	}
+
+	tag, err := base64.StdEncoding.DecodeString(matches[3])
+	if err != nil {
+		return "", fmt.Errorf("decoding tag: %v", err)
+	}
+
+	if len(iv) != gcm.NonceSize() {
+		return "", fmt.Errorf("unexpected iv length %d", len(iv))
+	}
+
+	plaintext, err := gcm.Open(nil, iv, append(data, tag...), []byte(additionalData))
+	if err != nil {
+		return "", errors.New("value could not be authenticated")
+	}
+
+	return string(plaintext), nil
+}
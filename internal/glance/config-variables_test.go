@@ -0,0 +1,196 @@
+package glance
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+)
+
+func TestConfigVariablesFileAndCredential(t *testing.T) {
+	dir := t.TempDir()
+	tokenPath := filepath.Join(dir, "token")
+	if err := os.WriteFile(tokenPath, []byte("  abc123\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "api_key"), []byte("xyz\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CREDENTIALS_DIRECTORY", dir)
+
+	contents := fmt.Sprintf("token: ${file:%s}\nkey: ${credential:api_key}\nescaped: \\${file:%s}", tokenPath, tokenPath)
+	parsed, err := parseConfigVariables([]byte(contents))
+	if err != nil {
+		t.Fatalf("parsing variables: %v", err)
+	}
+
+	expected := fmt.Sprintf("token: abc123\nkey: xyz\nescaped: ${file:%s}", tokenPath)
+	if string(parsed) != expected {
+		t.Fatalf("got %q, want %q", parsed, expected)
+	}
+
+	if _, err := parseConfigVariables([]byte("token: ${file:relative/path}")); err == nil {
+		t.Fatal("expected relative file paths to be rejected")
+	}
+
+	if _, err := parseConfigVariables([]byte("token: ${credential:../token}")); err == nil {
+		t.Fatal("expected credential names containing a path separator to be rejected")
+	}
+}
+
+func TestConfigVariablesUnknownTypeIsLeftAsIs(t *testing.T) {
+	parsed, err := parseConfigVariables([]byte("value: ${unknown:thing}"))
+	if err != nil {
+		t.Fatalf("parsing variables: %v", err)
+	}
+
+	if string(parsed) != "value: ${unknown:thing}" {
+		t.Fatalf("unexpected result %q", parsed)
+	}
+}
+
+func TestConfigVariablesSops(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		t.Fatal(err)
+	}
+
+	armored := &bytes.Buffer{}
+	armorWriter := armor.NewWriter(armored)
+	ageWriter, err := age.Encrypt(armorWriter, identity.Recipient())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ageWriter.Write(dataKey)
+	ageWriter.Close()
+	armorWriter.Close()
+
+	block, _ := aes.NewCipher(dataKey)
+	gcm, _ := cipher.NewGCMWithNonceSize(block, 32)
+	encrypt := func(value, path string) string {
+		iv := make([]byte, 32)
+		rand.Read(iv)
+		sealed := gcm.Seal(nil, iv, []byte(value), []byte(path))
+		data, tag := sealed[:len(sealed)-gcm.Overhead()], sealed[len(sealed)-gcm.Overhead():]
+		return fmt.Sprintf(
+			"ENC[AES256_GCM,data:%s,iv:%s,tag:%s,type:str]",
+			base64.StdEncoding.EncodeToString(data),
+			base64.StdEncoding.EncodeToString(iv),
+			base64.StdEncoding.EncodeToString(tag),
+		)
+	}
+
+	dir := t.TempDir()
+	secretsPath := filepath.Join(dir, "secrets.yml")
+	lastModified := "2024-05-01T10:00:00Z"
+
+	// values are in the order that they appear in the file, which is the
+	// order sops hashes them in
+	makeSecretsFile := func(lines []string, macValues ...string) []byte {
+		hash := sha512.New()
+		for _, value := range macValues {
+			hash.Write([]byte(value))
+		}
+
+		return []byte(strings.Join(append(lines,
+			"sops:",
+			"    lastmodified: \""+lastModified+"\"",
+			"    mac: "+encrypt(fmt.Sprintf("%X", hash.Sum(nil)), lastModified),
+			"    unencrypted_suffix: _unencrypted",
+			"    age:",
+			"        - recipient: "+identity.Recipient().String(),
+			"          enc: |",
+			prefixStringLines("            ", armored.String()),
+		), "\n"))
+	}
+
+	validLines := []string{
+		"github:",
+		"    token: " + encrypt("ghp_secret", "github:token:"),
+		"servers:",
+		"    - password: " + encrypt("first", "servers:password:"),
+		"    - password: " + encrypt("second", "servers:password:"),
+		"plain_unencrypted: visible",
+	}
+	validMAC := []string{"ghp_secret", "first", "second", "visible"}
+
+	if err := os.WriteFile(secretsPath, makeSecretsFile(validLines, validMAC...), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	keyPath := filepath.Join(dir, "keys.txt")
+	if err := os.WriteFile(keyPath, []byte(identity.String()+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("GLANCE_SOPS_FILE", secretsPath)
+	t.Setenv("SOPS_AGE_KEY", "")
+	t.Setenv("SOPS_AGE_KEY_FILE", keyPath)
+
+	parsed, err := parseConfigVariables([]byte("token: ${sops:github.token}\nplain: ${sops:plain_unencrypted}\nfirst: ${sops:servers[0].password}\nsecond: ${sops:servers[1].password}"))
+	if err != nil {
+		t.Fatalf("parsing variables: %v", err)
+	}
+
+	if string(parsed) != "token: ghp_secret\nplain: visible\nfirst: first\nsecond: second" {
+		t.Fatalf("unexpected result %q", parsed)
+	}
+
+	if _, err := parseConfigVariables([]byte("token: ${sops:github.missing}")); err == nil {
+		t.Fatal("expected an error for a missing key")
+	}
+
+	swapped := slices.Clone(validLines)
+	swapped[1] = "    token: ghp_attacker"
+	if err := os.WriteFile(secretsPath, makeSecretsFile(swapped, validMAC...), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := parseConfigVariables([]byte("token: ${sops:github.token}")); err == nil {
+		t.Fatal("expected a value which isn't encrypted to be rejected")
+	}
+
+	if err := os.WriteFile(secretsPath, makeSecretsFile(validLines[2:], validMAC...), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := parseConfigVariables([]byte("plain: ${sops:plain_unencrypted}")); err == nil {
+		t.Fatal("expected a removed value to fail the MAC check")
+	}
+
+	changedPlain := slices.Clone(validLines)
+	changedPlain[5] = "plain_unencrypted: changed"
+	if err := os.WriteFile(secretsPath, makeSecretsFile(changedPlain, validMAC...), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := parseConfigVariables([]byte("plain: ${sops:plain_unencrypted}")); err == nil {
+		t.Fatal("expected a modified unencrypted value to fail the MAC check")
+	}
+
+	otherPath := slices.Clone(validLines)
+	otherPath[1] = "    token: " + encrypt("ghp_secret", "other:path:")
+	if err := os.WriteFile(secretsPath, makeSecretsFile(otherPath, validMAC...), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := parseConfigVariables([]byte("token: ${sops:github.token}")); err == nil {
+		t.Fatal("expected a value encrypted under a different path to fail authentication")
+	}
+}
@@ -10,7 +10,9 @@ import (
 	"maps"
 	"os"
 	"path/filepath"
+	"reflect"
 	"regexp"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -21,12 +23,6 @@ import (
 
 const CONFIG_INCLUDE_RECURSION_DEPTH_LIMIT = 20
 
-const (
-	configVarTypeEnv         = "env"
-	configVarTypeSecret      = "secret"
-	configVarTypeFileFromEnv = "readFileFromEnv"
-)
-
 type config struct {
 	Server struct {
 		Host       string `yaml:"host"`
@@ -34,6 +30,7 @@ type config struct {
 		Proxied    bool   `yaml:"proxied"`
 		AssetsPath string `yaml:"assets-path"`
 		BaseURL    string `yaml:"base-url"`
+		Strict     bool   `yaml:"strict"`
 	} `yaml:"server"`
 
 	Auth struct {
@@ -65,6 +62,12 @@ type config struct {
 		AppBackgroundColor string        `yaml:"app-background-color"`
 	} `yaml:"branding"`
 
+	WidgetDefaults map[string]map[string]any `yaml:"widget-defaults"`
+
+	Alerts alertsConfig `yaml:"alerts"`
+
+	StatusPage statusPageConfig `yaml:"status-page"`
+
 	Pages []page `yaml:"pages"`
 }
 
@@ -92,54 +95,185 @@ type page struct {
 }
 
 func newConfigFromYAML(contents []byte) (*config, error) {
+	return newConfigFromYAMLWithSources(contents, nil, false)
+}
+
+// When strict is true or the config sets server.strict, unknown keys are treated as
+// errors and every error found is reported instead of only the first one. The sources
+// are optional and are used to point errors to the file and line they originated from.
+func newConfigFromYAMLWithSources(contents []byte, sources configSourceMap, strict bool) (*config, error) {
 	contents, err := parseConfigVariables(contents)
 	if err != nil {
 		return nil, err
 	}
 
+	var root yaml.Node
+	if err = yaml.Unmarshal(contents, &root); err != nil {
+		return nil, sources.mapYAMLError(err)
+	}
+
+	if err = applyWidgetDefaults(&root); err != nil {
+		return nil, sources.mapYAMLError(err)
+	}
+
 	config := &config{}
 	config.Server.Port = 8080
 
-	err = yaml.Unmarshal(contents, config)
-	if err != nil {
-		return nil, err
+	errs := &configErrors{sources: sources}
+
+	if err = root.Decode(config); err != nil {
+		var typeErr *yaml.TypeError
+		if !errors.As(err, &typeErr) {
+			return nil, sources.mapYAMLError(err)
+		}
+
+		if !strict && !config.Server.Strict {
+			return nil, sources.mapYAMLError(err)
+		}
+
+		errs.addYAMLTypeError(typeErr)
+	}
+
+	strict = strict || config.Server.Strict
+
+	if strict {
+		for _, unknown := range findUnknownConfigKeys(&root, reflect.TypeOf(config)) {
+			errs.add(unknown.line, fmt.Errorf("unknown key %q in %s", unknown.key, unknown.within))
+		}
 	}
 
 	if err = isConfigStateValid(config); err != nil {
-		return nil, err
+		if !strict {
+			return nil, err
+		}
+		errs.add(0, err)
 	}
 
 	for p := range config.Pages {
 		for w := range config.Pages[p].HeadWidgets {
-			if err := config.Pages[p].HeadWidgets[w].initialize(); err != nil {
-				return nil, formatWidgetInitError(err, config.Pages[p].HeadWidgets[w])
+			widget := config.Pages[p].HeadWidgets[w]
+			if err := widget.initialize(); err != nil {
+				if !strict {
+					return nil, formatWidgetInitError(err, widget)
+				}
+				errs.add(widget.getSourceLine(), formatWidgetInitError(err, widget))
 			}
 		}
 
 		for c := range config.Pages[p].Columns {
 			for w := range config.Pages[p].Columns[c].Widgets {
-				if err := config.Pages[p].Columns[c].Widgets[w].initialize(); err != nil {
-					return nil, formatWidgetInitError(err, config.Pages[p].Columns[c].Widgets[w])
+				widget := config.Pages[p].Columns[c].Widgets[w]
+				if err := widget.initialize(); err != nil {
+					if !strict {
+						return nil, formatWidgetInitError(err, widget)
+					}
+					errs.add(widget.getSourceLine(), formatWidgetInitError(err, widget))
 				}
 			}
 		}
 	}
 
+	if !errs.empty() {
+		return nil, errs
+	}
+
 	return config, nil
 }
 
+// Merges the properties from widget-defaults into every widget of the matching type,
+// properties specified on the widget itself take precedence over the defaults
+func applyWidgetDefaults(root *yaml.Node) error {
+	document := root
+	if document.Kind == yaml.DocumentNode {
+		if len(document.Content) == 0 {
+			return nil
+		}
+		document = document.Content[0]
+	}
+
+	if document.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	var defaultsNode *yaml.Node
+	for i := 0; i+1 < len(document.Content); i += 2 {
+		if document.Content[i].Value == "widget-defaults" {
+			defaultsNode = document.Content[i+1]
+		}
+	}
+
+	if defaultsNode == nil {
+		return nil
+	}
+
+	if defaultsNode.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: widget-defaults must be a map of widget types to their default properties", defaultsNode.Line)
+	}
+
+	defaults := make(map[string]*yaml.Node, len(defaultsNode.Content)/2)
+	for i := 0; i+1 < len(defaultsNode.Content); i += 2 {
+		widgetType, properties := defaultsNode.Content[i], defaultsNode.Content[i+1]
+
+		if _, ok := widgetTypeOf(widgetType.Value); !ok {
+			return fmt.Errorf("line %d: widget-defaults: unknown widget type: %s", widgetType.Line, widgetType.Value)
+		}
+
+		if properties.Kind != yaml.MappingNode {
+			return fmt.Errorf("line %d: widget-defaults: properties for %s must be a map", properties.Line, widgetType.Value)
+		}
+
+		defaults[widgetType.Value] = properties
+	}
+
+	walker := &configNodeWalker{
+		onWidget: func(node *yaml.Node, widgetType string) {
+			properties, ok := defaults[widgetType]
+			if !ok || node.Kind != yaml.MappingNode {
+				return
+			}
+
+			existing := make(map[string]struct{}, len(node.Content)/2)
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				existing[node.Content[i].Value] = struct{}{}
+			}
+
+			for i := 0; i+1 < len(properties.Content); i += 2 {
+				key := properties.Content[i].Value
+				if _, ok := existing[key]; ok || key == "type" {
+					continue
+				}
+
+				node.Content = append(node.Content, properties.Content[i], properties.Content[i+1])
+			}
+		},
+	}
+
+	walker.walk(root, reflect.TypeFor[config](), "config")
+
+	return nil
+}
+
 var envVariableNamePattern = regexp.MustCompile(`^[A-Z0-9_]+$`)
-var configVariablePattern = regexp.MustCompile(`(^|.)\$\{(?:([a-zA-Z]+):)?([a-zA-Z0-9_-]+)\}`)
+var configVariablePattern = regexp.MustCompile(`(^|.)\$\{(?:([a-zA-Z]+):)?([a-zA-Z0-9_./\[\]-]+)\}`)
 
 // Parses variables defined in the config such as:
 // ${API_KEY} 				            - gets replaced with the value of the API_KEY environment variable
 // \${API_KEY} 					        - escaped, gets used as is without the \ in the config
 // ${secret:api_key} 			        - value gets loaded from /run/secrets/api_key
 // ${readFileFromEnv:PATH_TO_SECRET}    - value gets loaded from the file path specified in the environment variable PATH_TO_SECRET
+// ${file:/path/to/secret}              - value gets loaded from /path/to/secret
+// ${credential:api_key}                - value gets loaded from $CREDENTIALS_DIRECTORY/api_key (systemd credentials)
+// ${sops:api.key}                      - value gets decrypted from the sops file specified in GLANCE_SOPS_FILE
 //
 // TODO: don't match against commented out sections, not sure exactly how since
 // variables can be placed anywhere and used to modify the YAML structure itself
 func parseConfigVariables(contents []byte) ([]byte, error) {
+	return resolveConfigVariables(contents, nil)
+}
+
+// Same as parseConfigVariables, the optional transform gets called with every resolved
+// value along with the type of the variable and returns the value that gets inserted
+func resolveConfigVariables(contents []byte, transform func(variableType, value string) string) ([]byte, error) {
 	var err error
 
 	replaced := configVariablePattern.ReplaceAllFunc(contents, func(match []byte) []byte {
@@ -176,6 +310,10 @@ func parseConfigVariables(contents []byte) ([]byte, error) {
 			return match
 		}
 
+		if transform != nil {
+			parsedValue = transform(variableType, parsedValue)
+		}
+
 		return []byte(prefix + parsedValue)
 	})
 
@@ -186,127 +324,111 @@ func parseConfigVariables(contents []byte) ([]byte, error) {
 	return replaced, nil
 }
 
-// When the bool return value is true, it indicates that the caller should use the original value
-func parseConfigVariableOfType(variableType, variableName string) (string, bool, error) {
-	switch variableType {
-	case configVarTypeEnv:
-		if !envVariableNamePattern.MatchString(variableName) {
-			return "", true, nil
-		}
-
-		v, found := os.LookupEnv(variableName)
-		if !found {
-			return "", false, fmt.Errorf("environment variable %s not found", variableName)
-		}
-
-		return v, false, nil
-	case configVarTypeSecret:
-		secretPath := filepath.Join("/run/secrets", variableName)
-		secret, err := os.ReadFile(secretPath)
-		if err != nil {
-			return "", false, fmt.Errorf("reading secret file: %v", err)
-		}
-
-		return strings.TrimSpace(string(secret)), false, nil
-	case configVarTypeFileFromEnv:
-		if !envVariableNamePattern.MatchString(variableName) {
-			return "", true, nil
-		}
-
-		filePath, found := os.LookupEnv(variableName)
-		if !found {
-			return "", false, fmt.Errorf("readFileFromEnv: environment variable %s not found", variableName)
-		}
-
-		if !filepath.IsAbs(filePath) {
-			return "", false, fmt.Errorf("readFileFromEnv: file path %s is not absolute", filePath)
-		}
-
-		fileContents, err := os.ReadFile(filePath)
-		if err != nil {
-			return "", false, fmt.Errorf("readFileFromEnv: reading file from %s: %v", variableName, err)
-		}
-
-		return strings.TrimSpace(string(fileContents)), false, nil
-	default:
-		return "", true, nil
-	}
-}
-
 func formatWidgetInitError(err error, w widget) error {
 	return fmt.Errorf("%s widget: %v", w.GetType(), err)
 }
 
 var configIncludePattern = regexp.MustCompile(`(?m)^([ \t]*)(?:-[ \t]*)?(?:!|\$)include:[ \t]*(.+)$`)
 
+func isGlobPattern(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
+
 func parseYAMLIncludes(mainFilePath string) ([]byte, map[string]struct{}, error) {
+	contents, _, includes, err := parseYAMLIncludesWithSources(mainFilePath)
+	return contents, includes, err
+}
+
+// Same as parseYAMLIncludes but also returns where each line of the flattened contents came from
+func parseYAMLIncludesWithSources(mainFilePath string) ([]byte, configSourceMap, map[string]struct{}, error) {
 	return recursiveParseYAMLIncludes(mainFilePath, nil, 0)
 }
 
-func recursiveParseYAMLIncludes(mainFilePath string, includes map[string]struct{}, depth int) ([]byte, map[string]struct{}, error) {
+func recursiveParseYAMLIncludes(
+	mainFilePath string,
+	includes map[string]struct{},
+	depth int,
+) ([]byte, configSourceMap, map[string]struct{}, error) {
 	if depth > CONFIG_INCLUDE_RECURSION_DEPTH_LIMIT {
-		return nil, nil, fmt.Errorf("recursion depth limit of %d reached", CONFIG_INCLUDE_RECURSION_DEPTH_LIMIT)
+		return nil, nil, nil, fmt.Errorf("recursion depth limit of %d reached", CONFIG_INCLUDE_RECURSION_DEPTH_LIMIT)
 	}
 
 	mainFileContents, err := os.ReadFile(mainFilePath)
 	if err != nil {
-		return nil, nil, fmt.Errorf("reading %s: %w", mainFilePath, err)
+		return nil, nil, nil, fmt.Errorf("reading %s: %w", mainFilePath, err)
 	}
 
 	mainFileAbsPath, err := filepath.Abs(mainFilePath)
 	if err != nil {
-		return nil, nil, fmt.Errorf("getting absolute path of %s: %w", mainFilePath, err)
+		return nil, nil, nil, fmt.Errorf("getting absolute path of %s: %w", mainFilePath, err)
 	}
 	mainFileDir := filepath.Dir(mainFileAbsPath)
 
 	if includes == nil {
 		includes = make(map[string]struct{})
 	}
-	var includesLastErr error
 
-	mainFileContents = configIncludePattern.ReplaceAllFunc(mainFileContents, func(match []byte) []byte {
-		if includesLastErr != nil {
-			return nil
-		}
+	lines := strings.Split(string(mainFileContents), "\n")
+	output := make([]string, 0, len(lines))
+	sources := make(configSourceMap, 0, len(lines))
 
-		matches := configIncludePattern.FindSubmatch(match)
-		if len(matches) != 3 {
-			includesLastErr = fmt.Errorf("invalid include match: %v", matches)
-			return nil
+	for i, line := range lines {
+		matches := configIncludePattern.FindStringSubmatch(line)
+		if matches == nil {
+			output = append(output, line)
+			sources = append(sources, configSourceLine{file: mainFilePath, line: i + 1})
+			continue
 		}
 
-		indent := string(matches[1])
-		includeFilePath := strings.TrimSpace(string(matches[2]))
-		if !filepath.IsAbs(includeFilePath) {
-			includeFilePath = filepath.Join(mainFileDir, includeFilePath)
+		indent := matches[1]
+		includePattern := strings.TrimSpace(matches[2])
+		if !filepath.IsAbs(includePattern) {
+			includePattern = filepath.Join(mainFileDir, includePattern)
 		}
 
-		var fileContents []byte
-		var err error
+		includeFilePaths := []string{includePattern}
 
-		includes[includeFilePath] = struct{}{}
+		if isGlobPattern(includePattern) {
+			includeFilePaths, err = filepath.Glob(includePattern)
+			if err != nil {
+				return nil, nil, nil, fmt.Errorf("invalid include pattern %s: %w", includePattern, err)
+			}
 
-		fileContents, includes, err = recursiveParseYAMLIncludes(includeFilePath, includes, depth+1)
-		if err != nil {
-			includesLastErr = err
-			return nil
+			if len(includeFilePaths) == 0 {
+				return nil, nil, nil, fmt.Errorf("no files match include pattern %s", includePattern)
+			}
+
+			slices.Sort(includeFilePaths)
+
+			// watch the directory so that newly added files get picked up
+			includes[filepath.Dir(includePattern)] = struct{}{}
 		}
 
-		return []byte(prefixStringLines(indent, string(fileContents)))
-	})
+		for _, includeFilePath := range includeFilePaths {
+			includes[includeFilePath] = struct{}{}
+
+			var fileContents []byte
+			var fileSources configSourceMap
 
-	if includesLastErr != nil {
-		return nil, nil, includesLastErr
+			fileContents, fileSources, includes, err = recursiveParseYAMLIncludes(includeFilePath, includes, depth+1)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+
+			output = append(output, prefixStringLines(indent, string(fileContents)))
+			sources = append(sources, fileSources...)
+		}
 	}
 
-	return mainFileContents, includes, nil
+	return []byte(strings.Join(output, "\n")), sources, includes, nil
 }
 
 func configFilesWatcher(
 	mainFilePath string,
 	lastContents []byte,
+	lastSources configSourceMap,
 	lastIncludes map[string]struct{},
-	onChange func(newContents []byte),
+	onChange func(newContents []byte, sources configSourceMap),
 	onErr func(error),
 ) (func() error, error) {
 	mainFileAbsPath, err := filepath.Abs(mainFilePath)
@@ -347,7 +469,7 @@ func configFilesWatcher(
 	mu := sync.Mutex{}
 
 	parseAndCompareBeforeCallback := func() {
-		currentContents, currentIncludes, err := parseYAMLIncludes(mainFilePath)
+		currentContents, currentSources, currentIncludes, err := parseYAMLIncludesWithSources(mainFilePath)
 		if err != nil {
 			onErr(fmt.Errorf("parsing main file contents for comparison: %w", err))
 			return
@@ -366,7 +488,7 @@ func configFilesWatcher(
 
 		if !bytes.Equal(lastContents, currentContents) {
 			lastContents = currentContents
-			onChange(currentContents)
+			onChange(currentContents, currentSources)
 		}
 	}
 
@@ -395,7 +517,7 @@ func configFilesWatcher(
 				if !isOpen {
 					return
 				}
-				if event.Has(fsnotify.Write) {
+				if event.Has(fsnotify.Write) || event.Has(fsnotify.Create) {
 					debouncedParseAndCompareBeforeCallback()
 				} else if event.Has(fsnotify.Rename) {
 					// on linux the file will no longer be watched after a rename, on windows
@@ -433,7 +555,7 @@ func configFilesWatcher(
 		}
 	}()
 
-	onChange(lastContents)
+	onChange(lastContents, lastSources)
 
 	return func() error {
 		if debounceTimer != nil {
@@ -477,6 +599,14 @@ func isConfigStateValid(config *config) error {
 		}
 	}
 
+	if err := config.Alerts.validate(); err != nil {
+		return err
+	}
+
+	if err := config.StatusPage.validate(); err != nil {
+		return err
+	}
+
 	if config.Server.AssetsPath != "" {
 		if _, err := os.Stat(config.Server.AssetsPath); os.IsNotExist(err) {
 			return fmt.Errorf("assets directory does not exist: %s", config.Server.AssetsPath)
@@ -533,6 +663,55 @@ func isConfigStateValid(config *config) error {
 		}
 	}
 
+	if err := checkPersistFilesAreUnique(config); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Widgets overwrite their persist file after every update, so two of them
+// sharing one would keep discarding each other's history
+func checkPersistFilesAreUnique(config *config) error {
+	seen := make(map[string]bool)
+
+	var check func(widgets) error
+	check = func(ws widgets) error {
+		for _, w := range ws {
+			if persisting, ok := w.(interface{ persistFile() string }); ok && persisting.persistFile() != "" {
+				path := filepath.Clean(persisting.persistFile())
+				if abs, err := filepath.Abs(path); err == nil {
+					path = abs
+				}
+
+				if seen[path] {
+					return fmt.Errorf("persist-file %s is used by more than one widget", persisting.persistFile())
+				}
+				seen[path] = true
+			}
+
+			if container, ok := w.(interface{ childWidgets() widgets }); ok {
+				if err := check(container.childWidgets()); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	}
+
+	for p := range config.Pages {
+		if err := check(config.Pages[p].HeadWidgets); err != nil {
+			return err
+		}
+
+		for c := range config.Pages[p].Columns {
+			if err := check(config.Pages[p].Columns[c].Widgets); err != nil {
+				return err
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -599,6 +778,10 @@ func (self *orderedYAMLMap[K, V]) Merge(other *orderedYAMLMap[K, V]) *orderedYAM
 	return merged
 }
 
+func (om *orderedYAMLMap[K, V]) yamlValueType() reflect.Type {
+	return reflect.TypeFor[V]()
+}
+
 func (om *orderedYAMLMap[K, V]) UnmarshalYAML(node *yaml.Node) error {
 	if node.Kind != yaml.MappingNode {
 		return fmt.Errorf("orderedMap: expected mapping node, got %d", node.Kind)
@@ -0,0 +1,113 @@
+package glance
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfigGlobIncludesAreSortedAndApplyWidgetDefaults(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "pages"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	writeFile := func(path, contents string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, path), []byte(contents), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeFile("glance.yml", strings.Join([]string{
+		"widget-defaults:",
+		"  releases:",
+		"    cache: 3h",
+		"    collapse-after: 2",
+		"pages:",
+		"  - $include: pages/*.yml",
+	}, "\n"))
+
+	page := func(name string, releasesCache string) string {
+		lines := []string{
+			"- name: " + name,
+			"  columns:",
+			"    - size: full",
+			"      widgets:",
+			"        - type: releases",
+			"          repositories: [glanceapp/glance]",
+		}
+		if releasesCache != "" {
+			lines = append(lines, "          cache: "+releasesCache)
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	writeFile("pages/b-second.yml", page("Second", ""))
+	writeFile("pages/a-first.yml", page("First", "10m"))
+
+	contents, sources, includes, err := parseYAMLIncludesWithSources(filepath.Join(dir, "glance.yml"))
+	if err != nil {
+		t.Fatalf("parsing includes: %v", err)
+	}
+
+	if _, ok := includes[filepath.Join(dir, "pages")]; !ok {
+		t.Error("expected the directory of the glob pattern to be watched")
+	}
+
+	config, err := newConfigFromYAMLWithSources(contents, sources, true)
+	if err != nil {
+		t.Fatalf("creating config: %v", err)
+	}
+
+	if len(config.Pages) != 2 || config.Pages[0].Title != "First" || config.Pages[1].Title != "Second" {
+		t.Fatalf("expected pages to be included in lexical order, got %d pages", len(config.Pages))
+	}
+
+	first := config.Pages[0].Columns[0].Widgets[0].(*releasesWidget)
+	second := config.Pages[1].Columns[0].Widgets[0].(*releasesWidget)
+
+	if time.Duration(first.CustomCacheDuration) != 10*time.Minute {
+		t.Errorf("expected the widget's own cache to take precedence, got %v", time.Duration(first.CustomCacheDuration))
+	}
+
+	if time.Duration(second.CustomCacheDuration) != 3*time.Hour {
+		t.Errorf("expected the default cache to be applied, got %v", time.Duration(second.CustomCacheDuration))
+	}
+
+	if first.CollapseAfter != 2 || second.CollapseAfter != 2 {
+		t.Errorf("expected collapse-after default to be applied to both widgets")
+	}
+}
+
+func TestConfigWidgetDefaultsRejectUnknownWidgetTypes(t *testing.T) {
+	contents := "widget-defaults:\n  not-a-widget:\n    cache: 1h\npages: []"
+
+	if _, err := newConfigFromYAML([]byte(contents)); err == nil || !strings.Contains(err.Error(), "not-a-widget") {
+		t.Fatalf("expected an error about the unknown widget type, got %v", err)
+	}
+}
+
+func TestConfigRejectsSharedPersistFiles(t *testing.T) {
+	contents := `
+pages:
+  - name: Home
+    columns:
+      - size: full
+        widgets:
+          - type: server-stats
+            history:
+              persist-file: /tmp/glance/history.json
+          - type: group
+            widgets:
+              - type: monitor
+                history:
+                  persist-file: /tmp/glance/../glance/history.json
+`
+
+	if _, err := newConfigFromYAML([]byte(contents)); err == nil || !strings.Contains(err.Error(), "persist-file") {
+		t.Fatalf("expected an error about the shared persist-file, got %v", err)
+	}
+}
@@ -0,0 +1,87 @@
+package glance
+
+import (
+	"errors"
+	"slices"
+	"strings"
+)
+
+// Used by widgets other than docker-containers to build their entries from
+// the glance.* labels of containers, so that new services show up without
+// having to edit the config
+type dockerDiscoveryConfig struct {
+	SockPath string `yaml:"sock-path"`
+	Category string `yaml:"category"`
+
+	host *dockerHost
+}
+
+type dockerDiscoveredService struct {
+	// The name of the container, which unlike the title doesn't change when
+	// the labels do
+	Container   string
+	Title       string
+	URL         string
+	CheckURL    string
+	Description string
+	Icon        string
+	SameTab     *bool
+}
+
+func (c *dockerDiscoveryConfig) initialize() error {
+	if c.SockPath == "" {
+		c.SockPath = "/var/run/docker.sock"
+	}
+
+	c.host = &dockerHost{URL: c.SockPath}
+	if err := c.host.initialize(); err != nil {
+		return errors.New("from-docker: " + err.Error())
+	}
+
+	return nil
+}
+
+// Only containers with a glance.url label are included, hidden ones and the
+// children of other containers are skipped
+func (c *dockerDiscoveryConfig) discover() ([]dockerDiscoveredService, error) {
+	containers, err := fetchDockerContainersFromSource(c.host, c.Category, false, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	services := make([]dockerDiscoveredService, 0, len(containers))
+	for i := range containers {
+		container := &containers[i]
+
+		if isDockerContainerHidden(container, false) || container.Labels.getOrDefault(dockerContainerLabelParent, "") != "" {
+			continue
+		}
+
+		url := container.Labels.getOrDefault(dockerContainerLabelURL, "")
+		if url == "" {
+			continue
+		}
+
+		service := dockerDiscoveredService{
+			Container:   strings.TrimLeft(itemAtIndexOrDefault(container.Names, 0, ""), "/"),
+			Title:       deriveDockerContainerName(container, false),
+			URL:         url,
+			CheckURL:    container.Labels.getOrDefault(dockerContainerLabelCheckURL, ""),
+			Description: container.Labels.getOrDefault(dockerContainerLabelDescription, ""),
+			Icon:        container.Labels.getOrDefault(dockerContainerLabelIcon, ""),
+		}
+
+		if v := container.Labels.getOrDefault(dockerContainerLabelSameTab, ""); v != "" {
+			sameTab := stringToBool(v)
+			service.SameTab = &sameTab
+		}
+
+		services = append(services, service)
+	}
+
+	slices.SortFunc(services, func(a, b dockerDiscoveredService) int {
+		return strings.Compare(strings.ToLower(a.Title), strings.ToLower(b.Title))
+	})
+
+	return services, nil
+}
@@ -0,0 +1,77 @@
+package glance
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDockerDiscovery(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer site.Close()
+
+	containers := fmt.Sprintf(`[
+		{"Id": "aaa", "Names": ["/jellyfin"], "Labels": {"glance.name": "Jellyfin", "glance.url": "https://jellyfin.lan", "glance.check-url": "%s", "glance.icon": "si:jellyfin", "glance.category": "media"}},
+		{"Id": "bbb", "Names": ["/sonarr"], "Labels": {"glance.url": "https://sonarr.lan", "glance.check-url": "%s", "glance.same-tab": "true", "glance.category": "media"}},
+		{"Id": "ccc", "Names": ["/sonarr-db"], "Labels": {"glance.url": "https://db.lan", "glance.parent": "sonarr", "glance.category": "media"}},
+		{"Id": "ddd", "Names": ["/secret"], "Labels": {"glance.url": "https://secret.lan", "glance.hide": "true", "glance.category": "media"}},
+		{"Id": "eee", "Names": ["/no-url"], "Labels": {"glance.category": "media"}},
+		{"Id": "fff", "Names": ["/gitea"], "Labels": {"glance.url": "https://gitea.lan", "glance.category": "dev"}}
+	]`, site.URL, site.URL)
+
+	docker := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(containers))
+	}))
+	defer docker.Close()
+
+	bookmarks := &bookmarksWidget{Groups: []bookmarksGroup{{
+		Title:      "Media",
+		Links:      []bookmarkLink{{Title: "Router", URL: "https://router.lan"}},
+		FromDocker: &dockerDiscoveryConfig{SockPath: docker.URL, Category: "media"},
+	}}}
+	if err := bookmarks.initialize(); err != nil {
+		t.Fatalf("initializing bookmarks: %v", err)
+	}
+	bookmarks.update(t.Context())
+
+	links := bookmarks.Groups[0].Links
+	if len(links) != 3 {
+		t.Fatalf("expected the static link and 2 discovered ones, got %+v", links)
+	}
+
+	if links[0].Title != "Router" || links[1].Title != "Jellyfin" || links[2].Title != "sonarr" {
+		t.Errorf("unexpected order of links: %s, %s, %s", links[0].Title, links[1].Title, links[2].Title)
+	}
+
+	if links[1].Icon.URL == "" || links[1].Target != "_blank" || links[2].Target != "" {
+		t.Errorf("labels weren't applied to the links: %+v", links[1:])
+	}
+
+	monitor := &monitorWidget{FromDocker: &dockerDiscoveryConfig{SockPath: docker.URL, Category: "media"}}
+	if err := monitor.initialize(); err != nil {
+		t.Fatalf("initializing monitor: %v", err)
+	}
+	monitor.update(t.Context())
+
+	if len(monitor.Sites) != 2 {
+		t.Fatalf("expected 2 discovered sites, got %d", len(monitor.Sites))
+	}
+
+	jellyfin := &monitor.Sites[0]
+	if jellyfin.Title != "Jellyfin" || jellyfin.URL != "https://jellyfin.lan" || jellyfin.StatusStyle != "ok" {
+		t.Errorf("unexpected site: %s %s %s", jellyfin.Title, jellyfin.URL, jellyfin.StatusStyle)
+	}
+
+	history := jellyfin.History
+	monitor.update(t.Context())
+	if monitor.Sites[0].History != history || len(history.values()) != 2 {
+		t.Errorf("expected the history of the site to be kept between updates")
+	}
+
+	containers = `[]`
+	monitor.update(t.Context())
+	if len(monitor.Sites) != 0 {
+		t.Errorf("expected sites of removed containers to be dropped, got %d", len(monitor.Sites))
+	}
+}
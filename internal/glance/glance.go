@@ -38,8 +38,10 @@ type application struct {
 
 	parsedManifest []byte
 
-	slugToPage map[string]*page
-	widgetByID map[uint64]widget
+	slugToPage     map[string]*page
+	widgetByID     map[uint64]widget
+	pageByWidgetID map[uint64]*page
+	alerts         *alertDispatcher
 
 	RequiresAuth           bool
 	authSecretKey          []byte
@@ -50,13 +52,14 @@ type application struct {
 
 func newApplication(c *config) (*application, error) {
 	app := &application{
-		Version:    buildVersion,
-		CommitSHA:  commitSHA,
-		HasUpdate:  false,
-		CreatedAt:  time.Now(),
-		Config:     *c,
-		slugToPage: make(map[string]*page),
-		widgetByID: make(map[uint64]widget),
+		Version:        buildVersion,
+		CommitSHA:      commitSHA,
+		HasUpdate:      false,
+		CreatedAt:      time.Now(),
+		Config:         *c,
+		slugToPage:     make(map[string]*page),
+		widgetByID:     make(map[uint64]widget),
+		pageByWidgetID: make(map[uint64]*page),
 	}
 
 	// Sprawdź czy jest dostępna aktualizacja (tylko dla prawdziwych commit SHA, nie dla dev/unknown)
@@ -158,9 +161,42 @@ func newApplication(c *config) (*application, error) {
 
 	app.slugToPage[""] = &config.Pages[0]
 
+	// used by show-when conditions, if more than one widget has the same title the first one wins
+	widgetByTitle := make(map[string]widget)
+
+	alerts, err := newAlertDispatcher(&config.Alerts)
+	if err != nil {
+		return nil, err
+	}
+	app.alerts = alerts
+
 	providers := &widgetProviders{
+		alerts:            alerts,
 		assetResolver:     app.StaticAssetPath,
 		userAssetResolver: app.resolveUserDefinedAssetPath,
+		widgetByTitle: func(title string) widget {
+			return widgetByTitle[title]
+		},
+	}
+
+	// nested widgets are indexed as well so that they can be refreshed and
+	// referenced individually
+	var indexWidgets func(*page, widgets)
+	indexWidgets = func(p *page, ws widgets) {
+		for _, w := range ws {
+			app.widgetByID[w.GetID()] = w
+			app.pageByWidgetID[w.GetID()] = p
+
+			if titled, ok := w.(interface{ getTitle() string }); ok {
+				if _, exists := widgetByTitle[titled.getTitle()]; !exists {
+					widgetByTitle[titled.getTitle()] = w
+				}
+			}
+
+			if container, ok := w.(interface{ childWidgets() widgets }); ok {
+				indexWidgets(p, container.childWidgets())
+			}
+		}
 	}
 
 	for p := range config.Pages {
@@ -185,10 +221,10 @@ func newApplication(c *config) (*application, error) {
 			page.DesktopNavigationWidth = page.Width
 		}
 
+		indexWidgets(page, page.HeadWidgets)
+
 		for i := range page.HeadWidgets {
-			widget := page.HeadWidgets[i]
-			app.widgetByID[widget.GetID()] = widget
-			widget.setProviders(providers)
+			page.HeadWidgets[i].setProviders(providers)
 		}
 
 		for c := range page.Columns {
@@ -198,14 +234,38 @@ func newApplication(c *config) (*application, error) {
 				page.PrimaryColumnIndex = int8(c)
 			}
 
+			indexWidgets(page, column.Widgets)
+
 			for w := range column.Widgets {
-				widget := column.Widgets[w]
-				app.widgetByID[widget.GetID()] = widget
-				widget.setProviders(providers)
+				column.Widgets[w].setProviders(providers)
+			}
+		}
+	}
+
+	// done once every widget is indexed since conditions can reference widgets defined
+	// after them, the initial snapshot covers widgets which never get updated
+	for _, w := range app.widgetByID {
+		if title, ok := w.getShowWhen().referencedWidgetTitle(); ok {
+			if referenced := widgetByTitle[title]; referenced != nil {
+				referenced.keepSnapshots(referenced)
 			}
 		}
 	}
 
+	if config.StatusPage.Enabled {
+		if config.StatusPage.Slug == "" {
+			config.StatusPage.Slug = "status"
+		}
+
+		if _, exists := app.slugToPage[config.StatusPage.Slug]; exists {
+			return nil, fmt.Errorf("status page slug \"%s\" is already used by a page", config.StatusPage.Slug)
+		}
+
+		if config.StatusPage.Title == "" {
+			config.StatusPage.Title = "Status usług"
+		}
+	}
+
 	config.Server.BaseURL = strings.TrimRight(config.Server.BaseURL, "/")
 	config.Theme.CustomCSSFile = app.resolveUserDefinedAssetPath(config.Theme.CustomCSSFile)
 	config.Branding.LogoURL = app.resolveUserDefinedAssetPath(config.Branding.LogoURL)
@@ -240,61 +300,147 @@ func newApplication(c *config) (*application, error) {
 	}
 	app.parsedManifest = []byte(manifest)
 
-	// Inicjalna aktualizacja widgetów (cold start) - synchroniczna, przed startem serwera
+	return app, nil
+}
+
+// Inicjalna aktualizacja widgetów (cold start) - synchroniczna, przed startem serwera
+func (a *application) performInitialWidgetUpdate() {
 	// Używamy config.Pages zamiast slugToPage aby uniknąć duplikatów (slugToPage zawiera pusty slug + slug pierwszej strony)
 	log.Println("Performing initial widget update...")
-	for i := range config.Pages {
-		page := &config.Pages[i]
+	for i := range a.Config.Pages {
+		page := &a.Config.Pages[i]
 		page.mu.Lock()
 		page.updateOutdatedWidgets()
 		page.mu.Unlock()
 	}
 	log.Println("Initial widget update complete")
-
-	return app, nil
 }
 
 func (p *page) updateOutdatedWidgets() {
+	p.updateOutdatedWidgetsMatching(nil)
+}
+
+// A nil filter matches every widget
+func (p *page) updateOutdatedWidgetsMatching(filter func(widget) bool) {
 	now := time.Now()
 
 	var wg sync.WaitGroup
 	context := context.Background()
 
-	for w := range p.HeadWidgets {
-		wd := p.HeadWidgets[w]
-
-		if !wd.requiresUpdate(&now) {
-			continue
+	update := func(wd widget) {
+		// widgets claimed by a manual refresh are left to it
+		if (filter != nil && !filter(wd)) || !wd.requiresUpdate(&now) || !wd.startUpdating() {
+			return
 		}
 
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			wd.setUpdating(true)
 			defer wd.setUpdating(false)
-			wd.update(context)
+			updateWidget(context, wd)
 		}()
 	}
 
+	for w := range p.HeadWidgets {
+		update(p.HeadWidgets[w])
+	}
+
 	for c := range p.Columns {
 		for w := range p.Columns[c].Widgets {
-			wd := p.Columns[c].Widgets[w]
+			update(p.Columns[c].Widgets[w])
+		}
+	}
+
+	wg.Wait()
+}
 
-			if !wd.requiresUpdate(&now) {
-				continue
+func (a *application) startWidgetBackgroundTasks() func() {
+	var stops []func()
+
+	var start func(widgets)
+	start = func(ws widgets) {
+		for _, w := range ws {
+			if container, ok := w.(interface{ childWidgets() widgets }); ok {
+				start(container.childWidgets())
 			}
 
-			wg.Add(1)
-			go func() {
-				defer wg.Done()
-				wd.setUpdating(true)
-				defer wd.setUpdating(false)
-				wd.update(context)
-			}()
+			if task, ok := w.(widgetWithBackgroundTask); ok {
+				stops = append(stops, task.startBackgroundTask())
+			}
 		}
 	}
 
-	wg.Wait()
+	for p := range a.Config.Pages {
+		page := &a.Config.Pages[p]
+		start(page.HeadWidgets)
+
+		for c := range page.Columns {
+			start(page.Columns[c].Widgets)
+		}
+	}
+
+	stops = append(stops, a.startScheduledUpdates())
+
+	return func() {
+		for _, stop := range stops {
+			stop()
+		}
+	}
+}
+
+// Widgets normally only update while someone is looking at their page, which isn't
+// good enough for the ones that keep a history of checks or are watched by alerts
+func (a *application) startScheduledUpdates() (stop func()) {
+	isScheduled := func(w widget) bool {
+		if _, ok := w.(widgetWithScheduledUpdates); ok {
+			return true
+		}
+
+		return a.alerts.watches(w)
+	}
+
+	filter := func(w widget) bool {
+		return isScheduled(w) || slices.ContainsFunc(widgetDescendants(w), isScheduled)
+	}
+
+	hasScheduled := false
+	for p := range a.Config.Pages {
+		page := &a.Config.Pages[p]
+		hasScheduled = hasScheduled || slices.ContainsFunc(page.HeadWidgets, filter)
+
+		for c := range page.Columns {
+			hasScheduled = hasScheduled || slices.ContainsFunc(page.Columns[c].Widgets, filter)
+		}
+	}
+
+	if !hasScheduled {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	ticker := time.NewTicker(30 * time.Second)
+
+	go func() {
+		for {
+			for p := range a.Config.Pages {
+				page := &a.Config.Pages[p]
+				page.mu.Lock()
+				page.updateOutdatedWidgetsMatching(filter)
+				page.mu.Unlock()
+			}
+
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return func() {
+		ticker.Stop()
+		close(done)
+	}
 }
 
 func (a *application) startBackgroundUpdates() func() {
@@ -472,6 +618,64 @@ func (a *application) handleWidgetRequest(w http.ResponseWriter, r *http.Request
 	// widget.handleRequest(w, r)
 }
 
+func (a *application) handleWidgetRefreshRequest(w http.ResponseWriter, r *http.Request) {
+	if a.handleUnauthorizedResponse(w, r, showUnauthorizedJSON) {
+		return
+	}
+
+	widgetID, err := strconv.ParseUint(r.PathValue("widget"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid widget ID"))
+		return
+	}
+
+	widget, exists := a.widgetByID[widgetID]
+	if !exists {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Widget not found"))
+		return
+	}
+
+	// hidden widgets aren't updated on their page either, so there's nothing to show
+	if !widget.IsVisible() {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if !widget.startUpdating() {
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte("Widget is already updating"))
+		return
+	}
+
+	page := a.pageByWidgetID[widgetID]
+	page.mu.Lock()
+	defer page.mu.Unlock()
+
+	expireWidgetCache(widget)
+
+	// the update shouldn't get cancelled midway if the client goes away
+	updateWidget(context.WithoutCancel(r.Context()), widget)
+	widget.setUpdating(false)
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(widget.Render()))
+}
+
+// Containers only update the children whose cache has expired
+func expireWidgetCache(w widget) {
+	if expirable, ok := w.(interface{ expireCache() }); ok {
+		expirable.expireCache()
+	}
+
+	if container, ok := w.(interface{ childWidgets() widgets }); ok {
+		for _, child := range container.childWidgets() {
+			expireWidgetCache(child)
+		}
+	}
+}
+
 func (a *application) StaticAssetPath(asset string) string {
 	return a.Config.Server.BaseURL + "/static/" + staticFSHash + "/" + asset
 }
@@ -493,22 +697,18 @@ func (a *application) server() (func() error, func() error) {
 		mux.HandleFunc("POST /api/set-theme/{key}", a.handleThemeChangeRequest)
 	}
 
+	mux.HandleFunc("POST /api/widgets/{widget}/refresh", a.handleWidgetRefreshRequest)
 	mux.HandleFunc("/api/widgets/{widget}/{path...}", a.handleWidgetRequest)
 	mux.HandleFunc("GET /api/healthz", func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	})
 	mux.HandleFunc("GET /api/audio-proxy", a.handleAudioProxyRequest)
-	mux.HandleFunc("POST /api/vikunja/{widgetID}/complete-task", a.handleVikunjaCompleteTask)
-	mux.HandleFunc("POST /api/vikunja/{widgetID}/update-task", a.handleVikunjaUpdateTask)
-	mux.HandleFunc("POST /api/vikunja/{widgetID}/add-label", a.handleVikunjaAddLabel)
-	mux.HandleFunc("POST /api/vikunja/{widgetID}/remove-label", a.handleVikunjaRemoveLabel)
-	mux.HandleFunc("GET /api/vikunja/{widgetID}/labels", a.handleVikunjaGetLabels)
-	mux.HandleFunc("GET /api/vikunja/{widgetID}/projects", a.handleVikunjaGetProjects)
-	mux.HandleFunc("GET /api/vikunja/{widgetID}/refresh", a.handleVikunjaRefresh)
-	mux.HandleFunc("POST /api/vikunja/{widgetID}/create-task", a.handleVikunjaCreateTask)
-	mux.HandleFunc("POST /api/cloudflare/{widgetID}/update", a.handleCloudflareUpdate)
-	mux.HandleFunc("POST /api/google-compute/{widgetID}/action", a.handleGoogleComputeAction)
-	mux.HandleFunc("POST /api/beszel/{widgetID}/chart", a.handleBeszelChartData)
+	a.registerWidgetActionRoutes(mux)
+
+	if a.Config.StatusPage.Enabled {
+		mux.HandleFunc("GET /"+a.Config.StatusPage.Slug, a.handleStatusPageRequest)
+		mux.HandleFunc("GET /"+a.Config.StatusPage.Slug+".json", a.handleStatusPageJSONRequest)
+	}
 
 	if a.RequiresAuth {
 		mux.HandleFunc("GET /login", a.handleLoginPageRequest)
@@ -575,519 +775,6 @@ func (a *application) server() (func() error, func() error) {
 	return start, stop
 }
 
-func (a *application) handleVikunjaCompleteTask(w http.ResponseWriter, r *http.Request) {
-	widgetIDStr := r.PathValue("widgetID")
-	widgetID, err := strconv.ParseUint(widgetIDStr, 10, 64)
-	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("Invalid widget ID"))
-		return
-	}
-
-	widget, exists := a.widgetByID[widgetID]
-	if !exists {
-		w.WriteHeader(http.StatusNotFound)
-		w.Write([]byte("Widget not found"))
-		return
-	}
-
-	vikunjaWidget, ok := widget.(*vikunjaWidget)
-	if !ok {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("Widget is not a Vikunja widget"))
-		return
-	}
-
-	var request struct {
-		TaskID int `json:"task_id"`
-	}
-
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("Invalid request body"))
-		return
-	}
-
-	if err := vikunjaWidget.completeTask(request.TaskID); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(err.Error()))
-		return
-	}
-
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(`{"success": true}`))
-}
-
-func (a *application) handleVikunjaUpdateTask(w http.ResponseWriter, r *http.Request) {
-	widgetIDStr := r.PathValue("widgetID")
-	widgetID, err := strconv.ParseUint(widgetIDStr, 10, 64)
-	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("Invalid widget ID"))
-		return
-	}
-
-	widget, exists := a.widgetByID[widgetID]
-	if !exists {
-		w.WriteHeader(http.StatusNotFound)
-		w.Write([]byte("Widget not found"))
-		return
-	}
-
-	vikunjaWidget, ok := widget.(*vikunjaWidget)
-	if !ok {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("Widget is not a Vikunja widget"))
-		return
-	}
-
-	var request struct {
-		TaskID          int    `json:"task_id"`
-		Title           string `json:"title"`
-		DueDate         string `json:"due_date"`
-		AffineNoteURL   string `json:"affine_note_url"`
-		CustomLinkURL   string `json:"custom_link_url"`
-		CustomLinkTitle string `json:"custom_link_title"`
-	}
-
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("Invalid request body"))
-		return
-	}
-
-	if err := vikunjaWidget.updateTaskBasic(request.TaskID, request.Title, request.DueDate, request.AffineNoteURL, request.CustomLinkURL, request.CustomLinkTitle); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(err.Error()))
-		return
-	}
-
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(`{"success": true}`))
-}
-
-func (a *application) handleVikunjaAddLabel(w http.ResponseWriter, r *http.Request) {
-	widgetIDStr := r.PathValue("widgetID")
-	widgetID, err := strconv.ParseUint(widgetIDStr, 10, 64)
-	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("Invalid widget ID"))
-		return
-	}
-
-	widget, exists := a.widgetByID[widgetID]
-	if !exists {
-		w.WriteHeader(http.StatusNotFound)
-		w.Write([]byte("Widget not found"))
-		return
-	}
-
-	vikunjaWidget, ok := widget.(*vikunjaWidget)
-	if !ok {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("Widget is not a Vikunja widget"))
-		return
-	}
-
-	var request struct {
-		TaskID  int `json:"task_id"`
-		LabelID int `json:"label_id"`
-	}
-
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("Invalid request body"))
-		return
-	}
-
-	if err := vikunjaWidget.addLabelToTask(request.TaskID, request.LabelID); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(err.Error()))
-		return
-	}
-
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(`{"success": true}`))
-}
-
-func (a *application) handleVikunjaRemoveLabel(w http.ResponseWriter, r *http.Request) {
-	widgetIDStr := r.PathValue("widgetID")
-	widgetID, err := strconv.ParseUint(widgetIDStr, 10, 64)
-	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("Invalid widget ID"))
-		return
-	}
-
-	widget, exists := a.widgetByID[widgetID]
-	if !exists {
-		w.WriteHeader(http.StatusNotFound)
-		w.Write([]byte("Widget not found"))
-		return
-	}
-
-	vikunjaWidget, ok := widget.(*vikunjaWidget)
-	if !ok {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("Widget is not a Vikunja widget"))
-		return
-	}
-
-	var request struct {
-		TaskID  int `json:"task_id"`
-		LabelID int `json:"label_id"`
-	}
-
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("Invalid request body"))
-		return
-	}
-
-	if err := vikunjaWidget.removeLabelFromTask(request.TaskID, request.LabelID); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(err.Error()))
-		return
-	}
-
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(`{"success": true}`))
-}
-
-func (a *application) handleVikunjaGetLabels(w http.ResponseWriter, r *http.Request) {
-	widgetIDStr := r.PathValue("widgetID")
-	widgetID, err := strconv.ParseUint(widgetIDStr, 10, 64)
-	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("Invalid widget ID"))
-		return
-	}
-
-	widget, exists := a.widgetByID[widgetID]
-	if !exists {
-		w.WriteHeader(http.StatusNotFound)
-		w.Write([]byte("Widget not found"))
-		return
-	}
-
-	vikunjaWidget, ok := widget.(*vikunjaWidget)
-	if !ok {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("Widget is not a Vikunja widget"))
-		return
-	}
-
-	labels, err := vikunjaWidget.fetchAllLabels()
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(err.Error()))
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(labels)
-}
-
-func (a *application) handleVikunjaGetProjects(w http.ResponseWriter, r *http.Request) {
-	widgetIDStr := r.PathValue("widgetID")
-	widgetID, err := strconv.ParseUint(widgetIDStr, 10, 64)
-	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("Invalid widget ID"))
-		return
-	}
-
-	widget, exists := a.widgetByID[widgetID]
-	if !exists {
-		w.WriteHeader(http.StatusNotFound)
-		w.Write([]byte("Widget not found"))
-		return
-	}
-
-	vikunjaWidget, ok := widget.(*vikunjaWidget)
-	if !ok {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("Widget is not a Vikunja widget"))
-		return
-	}
-
-	projects, err := vikunjaWidget.fetchProjects()
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(err.Error()))
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(projects)
-}
-
-func (a *application) handleVikunjaRefresh(w http.ResponseWriter, r *http.Request) {
-	widgetIDStr := r.PathValue("widgetID")
-	widgetID, err := strconv.ParseUint(widgetIDStr, 10, 64)
-	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("Invalid widget ID"))
-		return
-	}
-
-	widget, exists := a.widgetByID[widgetID]
-	if !exists {
-		w.WriteHeader(http.StatusNotFound)
-		w.Write([]byte("Widget not found"))
-		return
-	}
-
-	vikunjaWidget, ok := widget.(*vikunjaWidget)
-	if !ok {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("Widget is not a Vikunja widget"))
-		return
-	}
-
-	// Force a refresh of the widget data
-	vikunjaWidget.update(context.Background())
-
-	// Render the widget HTML
-	html := vikunjaWidget.Render()
-
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(html))
-}
-
-func (a *application) handleVikunjaCreateTask(w http.ResponseWriter, r *http.Request) {
-	widgetIDStr := r.PathValue("widgetID")
-	widgetID, err := strconv.ParseUint(widgetIDStr, 10, 64)
-	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("Invalid widget ID"))
-		return
-	}
-
-	widget, exists := a.widgetByID[widgetID]
-	if !exists {
-		w.WriteHeader(http.StatusNotFound)
-		w.Write([]byte("Widget not found"))
-		return
-	}
-
-	vikunjaWidget, ok := widget.(*vikunjaWidget)
-	if !ok {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("Widget is not a Vikunja widget"))
-		return
-	}
-
-	var request struct {
-		Title           string `json:"title"`
-		DueDate         string `json:"due_date"`
-		LabelIDs        []int  `json:"label_ids"`
-		ProjectID       int    `json:"project_id"`
-		AffineNoteURL   string `json:"affine_note_url"`
-		CustomLinkURL   string `json:"custom_link_url"`
-		CustomLinkTitle string `json:"custom_link_title"`
-	}
-
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("Invalid request body"))
-		return
-	}
-
-	task, err := vikunjaWidget.createTask(request.Title, request.DueDate, request.LabelIDs, request.ProjectID, request.AffineNoteURL, request.CustomLinkURL, request.CustomLinkTitle)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(fmt.Sprintf("Failed to create task: %v", err)))
-		return
-	}
-
-	if task == nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte("Task was created but response was empty"))
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	if err := json.NewEncoder(w).Encode(task); err != nil {
-		// Log encoding error but response is already sent
-		fmt.Printf("Error encoding task response: %v\n", err)
-	}
-}
-
-func (a *application) handleCloudflareUpdate(w http.ResponseWriter, r *http.Request) {
-	widgetIDStr := r.PathValue("widgetID")
-	widgetID, err := strconv.ParseUint(widgetIDStr, 10, 64)
-	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("Invalid widget ID"))
-		return
-	}
-
-	widget, exists := a.widgetByID[widgetID]
-	if !exists {
-		w.WriteHeader(http.StatusNotFound)
-		w.Write([]byte("Widget not found"))
-		return
-	}
-
-	cfWidget, ok := widget.(*cloudflareWidget)
-	if !ok {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("Widget is not a Cloudflare widget"))
-		return
-	}
-
-	var request struct {
-		TimeRange string `json:"time_range"`
-	}
-
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("Invalid request body"))
-		return
-	}
-
-	if request.TimeRange != "24h" && request.TimeRange != "7d" && request.TimeRange != "30d" {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("Invalid time range"))
-		return
-	}
-
-	cfWidget.TimeRange = request.TimeRange
-	cfWidget.update(context.Background())
-
-	html := cfWidget.Render()
-
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(html))
-}
-
-func (a *application) handleGoogleComputeAction(w http.ResponseWriter, r *http.Request) {
-	widgetIDStr := r.PathValue("widgetID")
-	widgetID, err := strconv.ParseUint(widgetIDStr, 10, 64)
-	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("Invalid widget ID"))
-		return
-	}
-
-	widget, exists := a.widgetByID[widgetID]
-	if !exists {
-		w.WriteHeader(http.StatusNotFound)
-		w.Write([]byte("Widget not found"))
-		return
-	}
-
-	gceWidget, ok := widget.(*googleComputeWidget)
-	if !ok {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("Widget is not a Google Compute widget"))
-		return
-	}
-
-	var request struct {
-		Action   string `json:"action"`
-		Instance string `json:"instance"`
-		Zone     string `json:"zone"`
-	}
-
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("Invalid request body"))
-		return
-	}
-
-	if request.Action != "start" && request.Action != "stop" && request.Action != "restart" {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("Invalid action"))
-		return
-	}
-
-	if request.Instance == "" || request.Zone == "" {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("Instance and zone are required"))
-		return
-	}
-
-	if err := gceWidget.performInstanceAction(r.Context(), request.Action, request.Zone, request.Instance); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(fmt.Sprintf("Failed to perform action: %v", err)))
-		return
-	}
-
-	gceWidget.update(r.Context())
-	html := gceWidget.Render()
-
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(html))
-}
-
-func (a *application) handleBeszelChartData(w http.ResponseWriter, r *http.Request) {
-	widgetIDStr := r.PathValue("widgetID")
-	widgetID, err := strconv.ParseUint(widgetIDStr, 10, 64)
-	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("Invalid widget ID"))
-		return
-	}
-
-	widget, exists := a.widgetByID[widgetID]
-	if !exists {
-		w.WriteHeader(http.StatusNotFound)
-		w.Write([]byte("Widget not found"))
-		return
-	}
-
-	beszelW, ok := widget.(*beszelWidget)
-	if !ok {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("Widget is not a Beszel widget"))
-		return
-	}
-
-	var request struct {
-		SystemID  string `json:"system_id"`
-		Metric    string `json:"metric"`
-		TimeRange string `json:"time_range"`
-	}
-
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("Invalid request body"))
-		return
-	}
-
-	// Walidacja parametrów
-	validMetrics := map[string]bool{"cpu": true, "ram": true, "disk": true, "network": true}
-	if !validMetrics[request.Metric] {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("Invalid metric type"))
-		return
-	}
-
-	validTimeRanges := map[string]bool{"1m": true, "1h": true, "12h": true, "24h": true, "7d": true, "30d": true}
-	if !validTimeRanges[request.TimeRange] {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("Invalid time range"))
-		return
-	}
-
-	chartData, err := beszelW.FetchChartData(r.Context(), request.SystemID, request.Metric, request.TimeRange)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(fmt.Sprintf("Failed to fetch chart data: %v", err)))
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(chartData)
-}
-
 // checkForUpdate sprawdza czy jest dostępna nowsza wersja na GitHub
 func checkForUpdate(currentCommit string) bool {
 	req, err := http.NewRequest("GET", "https://api.github.com/repos/Mord0reK/glance-polski/commits/main", nil)
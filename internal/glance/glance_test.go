@@ -0,0 +1,141 @@
+package glance
+
+import (
+	"context"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type refreshTestWidget struct {
+	widgetBase `yaml:",inline"`
+	updates    int
+	// when set, updates wait for it to be closed
+	release chan struct{}
+}
+
+func (widget *refreshTestWidget) initialize() error {
+	widget.withCacheDuration(time.Hour)
+	return nil
+}
+
+func (widget *refreshTestWidget) update(ctx context.Context) {
+	if widget.release != nil {
+		<-widget.release
+	}
+
+	widget.updates++
+	widget.scheduleNextUpdate()
+}
+
+func (widget *refreshTestWidget) Render() template.HTML {
+	return template.HTML("<div>updated " + strconv.Itoa(widget.updates) + " times</div>")
+}
+
+func TestWidgetRefreshIgnoresCache(t *testing.T) {
+	child := &refreshTestWidget{}
+	child.setID(1001)
+	child.initialize()
+
+	group := &groupWidget{}
+	group.setID(1002)
+	group.Widgets = widgets{child}
+
+	testPage := &page{}
+	app := &application{
+		widgetByID:     map[uint64]widget{1001: child, 1002: group},
+		pageByWidgetID: map[uint64]*page{1001: testPage, 1002: testPage},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/widgets/{widget}/refresh", app.handleWidgetRefreshRequest)
+
+	refresh := func(id string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, httptest.NewRequest("POST", "/api/widgets/"+id+"/refresh", nil))
+		return recorder
+	}
+
+	for i := 1; i <= 2; i++ {
+		response := refresh("1001")
+		if response.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", response.Code)
+		}
+
+		if !strings.Contains(response.Body.String(), "updated "+strconv.Itoa(i)+" times") {
+			t.Errorf("expected the widget to update on every refresh, got %q", response.Body.String())
+		}
+	}
+
+	// the child's cache hasn't expired, so refreshing the group has to force it
+	if response := refresh("1002"); response.Code != http.StatusOK || child.updates != 3 {
+		t.Errorf("expected refreshing the group to update its child, got %d updates", child.updates)
+	}
+
+	child.setUpdating(true)
+	if response := refresh("1001"); response.Code != http.StatusConflict {
+		t.Errorf("expected 409 for a widget that is already updating, got %d", response.Code)
+	}
+
+	// scheduled updates have to leave a widget claimed by a refresh alone
+	child.nextUpdate = time.Time{}
+	testPage.HeadWidgets = widgets{child}
+	testPage.updateOutdatedWidgets()
+	if child.updates != 3 || !child.CheckIsUpdating() {
+		t.Errorf("expected the page update to skip the claimed widget, got %d updates", child.updates)
+	}
+	child.setUpdating(false)
+
+	child.ShowWhen = &widgetVisibility{Condition: `env("GLANCE_TEST_MISSING")`}
+	if err := child.ShowWhen.parse(); err != nil {
+		t.Fatal(err)
+	}
+	if response := refresh("1001"); response.Code != http.StatusNoContent || child.updates != 3 {
+		t.Errorf("expected 204 and no update for a hidden widget, got %d and %d updates", response.Code, child.updates)
+	}
+	child.ShowWhen = nil
+
+	if response := refresh("999"); response.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a widget that doesn't exist, got %d", response.Code)
+	}
+}
+
+func TestWidgetRefreshRejectsConcurrentRefreshes(t *testing.T) {
+	slow := &refreshTestWidget{release: make(chan struct{})}
+	slow.setID(1001)
+	slow.initialize()
+
+	app := &application{
+		widgetByID:     map[uint64]widget{1001: slow},
+		pageByWidgetID: map[uint64]*page{1001: {}},
+	}
+
+	codes := make(chan int, 2)
+	for range 2 {
+		go func() {
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest("POST", "/api/widgets/1001/refresh", nil)
+			request.SetPathValue("widget", "1001")
+			app.handleWidgetRefreshRequest(recorder, request)
+			codes <- recorder.Code
+		}()
+	}
+
+	// one of the refreshes is blocked in update, the other has to be turned away
+	if code := <-codes; code != http.StatusConflict {
+		t.Errorf("expected 409 for the second refresh, got %d", code)
+	}
+
+	close(slow.release)
+	if code := <-codes; code != http.StatusOK {
+		t.Errorf("expected 200 for the first refresh, got %d", code)
+	}
+
+	if slow.updates != 1 {
+		t.Errorf("expected a single update, got %d", slow.updates)
+	}
+}
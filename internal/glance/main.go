@@ -22,6 +22,17 @@ func Main(commitSHAArg string) int {
 		return 1
 	}
 
+	if options.recordDir != "" {
+		err = enableUpstreamFixtures(upstreamFixturesRecord, options.recordDir)
+	} else if options.replayDir != "" {
+		err = enableUpstreamFixtures(upstreamFixturesReplay, options.replayDir)
+	}
+
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+
 	switch options.intent {
 	case cliIntentVersionPrint:
 		fmt.Println(buildVersion)
@@ -36,24 +47,24 @@ func Main(commitSHAArg string) int {
 			return 1
 		}
 	case cliIntentConfigValidate:
-		contents, _, err := parseYAMLIncludes(options.configPath)
+		contents, sources, _, err := parseYAMLIncludesWithSources(options.configPath)
 		if err != nil {
 			fmt.Printf("Could not parse config file: %v\n", err)
 			return 1
 		}
 
-		if _, err := newConfigFromYAML(contents); err != nil {
+		if _, err := newConfigFromYAMLWithSources(contents, sources, true); err != nil {
 			fmt.Printf("Config file is invalid: %v\n", err)
 			return 1
 		}
 	case cliIntentConfigPrint:
-		contents, _, err := parseYAMLIncludes(options.configPath)
-		if err != nil {
-			fmt.Printf("Could not parse config file: %v\n", err)
-			return 1
-		}
-
-		fmt.Println(string(contents))
+		return cliConfigPrint(options.configPath, options.configPrint)
+	case cliIntentWidgetRun:
+		return cliWidgetRun(options.configPath, options.widgetRun)
+	case cliIntentAgent:
+		return cliAgent(options.configPath)
+	case cliIntentConfigSchema:
+		return cliConfigSchemaPrint()
 	case cliIntentSensorsPrint:
 		return cliSensorsPrint()
 	case cliIntentMountpointInfo:
@@ -101,13 +112,14 @@ func serveApp(configPath string) error {
 	hadValidConfigOnStartup := false
 	var stopServer func() error
 	var stopBackgroundUpdates func()
+	var stopWidgetBackgroundTasks func()
 
-	onChange := func(newContents []byte) {
+	onChange := func(newContents []byte, sources configSourceMap) {
 		if stopServer != nil {
 			log.Println("Config file changed, reloading...")
 		}
 
-		config, err := newConfigFromYAML(newContents)
+		config, err := newConfigFromYAMLWithSources(newContents, sources, false)
 		if err != nil {
 			log.Printf("Config has errors: %v", err)
 
@@ -129,6 +141,8 @@ func serveApp(configPath string) error {
 			return
 		}
 
+		app.performInitialWidgetUpdate()
+
 		if !hadValidConfigOnStartup {
 			hadValidConfigOnStartup = true
 		}
@@ -137,6 +151,11 @@ func serveApp(configPath string) error {
 			stopBackgroundUpdates()
 		}
 
+		if stopWidgetBackgroundTasks != nil {
+			stopWidgetBackgroundTasks()
+		}
+		stopWidgetBackgroundTasks = app.startWidgetBackgroundTasks()
+
 		if stopServer != nil {
 			if err := stopServer(); err != nil {
 				log.Printf("Error while trying to stop server: %v", err)
@@ -160,18 +179,18 @@ func serveApp(configPath string) error {
 		log.Printf("Error watching config files: %v", err)
 	}
 
-	configContents, configIncludes, err := parseYAMLIncludes(configPath)
+	configContents, configSources, configIncludes, err := parseYAMLIncludesWithSources(configPath)
 	if err != nil {
 		return fmt.Errorf("parsing config: %w", err)
 	}
 
-	stopWatching, err := configFilesWatcher(configPath, configContents, configIncludes, onChange, onErr)
+	stopWatching, err := configFilesWatcher(configPath, configContents, configSources, configIncludes, onChange, onErr)
 	if err == nil {
 		defer stopWatching()
 	} else {
 		log.Printf("Error starting file watcher, config file changes will require a manual restart. (%v)", err)
 
-		config, err := newConfigFromYAML(configContents)
+		config, err := newConfigFromYAMLWithSources(configContents, configSources, false)
 		if err != nil {
 			return fmt.Errorf("validating config file: %w", err)
 		}
@@ -181,8 +200,11 @@ func serveApp(configPath string) error {
 			return fmt.Errorf("creating application: %w", err)
 		}
 
+		app.performInitialWidgetUpdate()
+
 		// Pętla aktualizacji w tle wyłączona - odświeżanie tylko przy wejściu na stronę
 		// stopBackgroundUpdates = app.startBackgroundUpdates()
+		app.startWidgetBackgroundTasks()
 
 		startServer, _ := app.server()
 		if err := startServer(); err != nil {
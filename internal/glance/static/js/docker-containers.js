@@ -0,0 +1,149 @@
+import { replaceWidget } from './page.js';
+
+let initialized = false;
+
+async function performAction(button) {
+    const widgetElement = button.closest(".widget");
+    const list = button.closest("[data-docker-widget-id]");
+    const actions = button.closest(".docker-container-actions");
+    if (widgetElement === null || list === null || actions === null) return;
+
+    actions.querySelectorAll("button").forEach((b) => (b.disabled = true));
+    const previousText = button.textContent;
+    button.textContent = "...";
+
+    try {
+        const response = await fetch(`${pageData.baseURL}/api/docker-containers/${list.dataset.dockerWidgetId}/action`, {
+            method: "POST",
+            headers: {
+                "Content-Type": "application/json",
+            },
+            body: JSON.stringify({
+                action: button.dataset.action,
+                host: actions.dataset.host,
+                container: actions.dataset.container,
+            }),
+        });
+
+        await replaceWidget(widgetElement, response);
+    } catch (err) {
+        console.error("Failed to perform container action", err);
+        actions.querySelectorAll("button").forEach((b) => (b.disabled = false));
+        button.textContent = previousText;
+    }
+}
+
+function logsURL(logs, follow) {
+    const params = new URLSearchParams({
+        host: logs.dataset.host,
+        container: logs.dataset.container,
+    });
+
+    if (follow) params.set("follow", "1");
+
+    return `${pageData.baseURL}/api/docker-containers/${logs.dataset.widgetId}/logs?${params}`;
+}
+
+function appendLogLines(logs, lines) {
+    const output = logs.querySelector(".docker-container-logs-lines");
+    const scrolledToBottom = output.scrollHeight - output.scrollTop - output.clientHeight < 20;
+
+    for (const line of lines) {
+        const element = document.createElement("div");
+        element.textContent = line.text;
+        if (line.stream === "stderr") element.classList.add("docker-container-logs-stderr");
+        output.append(element);
+    }
+
+    if (scrolledToBottom) output.scrollTop = output.scrollHeight;
+}
+
+function setLogsMessage(logs, message) {
+    logs.querySelector(".docker-container-logs-lines").textContent = message;
+}
+
+async function loadLogs(logs) {
+    setLogsMessage(logs, "Ładowanie...");
+
+    try {
+        const response = await fetch(logsURL(logs, false));
+        if (!response.ok) {
+            throw new Error(await response.text());
+        }
+
+        const data = await response.json();
+        setLogsMessage(logs, data.lines.length == 0 ? "Brak logów" : "");
+        appendLogLines(logs, data.lines);
+    } catch (err) {
+        setLogsMessage(logs, `Nie udało się pobrać logów: ${err.message}`);
+    }
+}
+
+function stopFollowingLogs(logs) {
+    if (logs.eventSource === undefined) return;
+
+    logs.eventSource.close();
+    delete logs.eventSource;
+}
+
+// the tail gets sent again when the stream starts, so it replaces what's shown
+function followLogs(logs) {
+    stopFollowingLogs(logs);
+    setLogsMessage(logs, "");
+
+    const eventSource = new EventSource(logsURL(logs, true));
+    logs.eventSource = eventSource;
+
+    eventSource.onmessage = (event) => appendLogLines(logs, [JSON.parse(event.data)]);
+    eventSource.addEventListener("end", () => {
+        stopFollowingLogs(logs);
+        logs.querySelector(".docker-container-logs-follow input").checked = false;
+    });
+    // also happens when too many logs are already being followed
+    eventSource.onerror = () => {
+        stopFollowingLogs(logs);
+        logs.querySelector(".docker-container-logs-follow input").checked = false;
+
+        if (logs.querySelector(".docker-container-logs-lines").childElementCount == 0) {
+            setLogsMessage(logs, "Nie udało się śledzić logów");
+        }
+    };
+}
+
+export default function setupDockerContainers() {
+    if (initialized) return;
+    initialized = true;
+
+    document.addEventListener("click", (event) => {
+        const button = event.target.closest("button.docker-container-action-button");
+        if (button !== null && !button.disabled) performAction(button);
+    });
+
+    document.addEventListener("popover-show", (event) => {
+        const logs = event.target.querySelector(".docker-container-logs");
+        if (logs === null) return;
+
+        if (logs.querySelector(".docker-container-logs-follow input").checked) {
+            followLogs(logs);
+        } else {
+            loadLogs(logs);
+        }
+    });
+
+    document.addEventListener("popover-hide", (event) => {
+        const logs = event.target.querySelector(".docker-container-logs");
+        if (logs !== null) stopFollowingLogs(logs);
+    });
+
+    document.addEventListener("change", (event) => {
+        const logs = event.target.closest(".docker-container-logs");
+        if (logs === null) return;
+
+        if (event.target.checked) {
+            followLogs(logs);
+        } else {
+            stopFollowingLogs(logs);
+            loadLogs(logs);
+        }
+    });
+}
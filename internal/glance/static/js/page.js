@@ -3,6 +3,18 @@ import { setupMasonries } from './masonry.js';
 import { throttledDebounce, isElementVisible, openURLInNewTab } from './utils.js';
 import { elem, find, findAll } from './templating.js';
 
+// Like querySelectorAll but also includes the root itself, since a refreshed
+// widget is passed as the root and setups often look for its widget-type class
+function findInRoot(root, selector) {
+    const elements = Array.from(root.querySelectorAll(selector));
+
+    if (root instanceof Element && root.matches(selector)) {
+        elements.unshift(root);
+    }
+
+    return elements;
+}
+
 async function fetchPageContent(pageData) {
     // TODO: handle non 200 status codes/time outs
     // TODO: add retries
@@ -12,8 +24,8 @@ async function fetchPageContent(pageData) {
     return content;
 }
 
-function setupCarousels() {
-    const carouselElements = document.getElementsByClassName("carousel-container");
+function setupCarousels(root = document) {
+    const carouselElements = findInRoot(root, ".carousel-container");
 
     if (carouselElements.length == 0) {
         return;
@@ -535,14 +547,15 @@ function setupSearchBoxes() {
 }
 
 function setupDynamicRelativeTime() {
-    const elements = document.querySelectorAll("[data-dynamic-relative-time]");
+    // queried on every update since refreshed widgets replace their elements
+    const findElements = () => document.querySelectorAll("[data-dynamic-relative-time]");
     const updateInterval = 60 * 1000;
     let lastUpdateTime = Date.now();
 
-    updateRelativeTimeForElements(elements);
+    updateRelativeTimeForElements(findElements());
 
     const updateElementsAndTimestamp = () => {
-        updateRelativeTimeForElements(elements);
+        updateRelativeTimeForElements(findElements());
         lastUpdateTime = Date.now();
     };
 
@@ -576,8 +589,8 @@ function setupDynamicRelativeTime() {
     });
 }
 
-function setupGroups() {
-    const groups = document.getElementsByClassName("widget-type-group");
+function setupGroups(root = document) {
+    const groups = findInRoot(root, ".widget-type-group");
 
     if (groups.length == 0) {
         return;
@@ -636,8 +649,8 @@ function setupGroups() {
     }
 }
 
-function setupLazyImages() {
-    const images = document.querySelectorAll("img[loading=lazy]");
+function setupLazyImages(root = document) {
+    const images = root.querySelectorAll("img[loading=lazy]");
 
     if (images.length == 0) {
         return;
@@ -711,8 +724,8 @@ function attachExpandToggleButton(collapsibleContainer) {
 };
 
 
-function setupCollapsibleLists() {
-    const collapsibleLists = document.querySelectorAll(".list.collapsible-container");
+function setupCollapsibleLists(root = document) {
+    const collapsibleLists = root.querySelectorAll(".list.collapsible-container");
 
     if (collapsibleLists.length == 0) {
         return;
@@ -745,8 +758,8 @@ function setupCollapsibleLists() {
     }
 }
 
-function setupCollapsibleGrids() {
-    const collapsibleGridElements = document.querySelectorAll(".cards-grid.collapsible-container");
+function setupCollapsibleGrids(root = document) {
+    const collapsibleGridElements = root.querySelectorAll(".cards-grid.collapsible-container");
 
     if (collapsibleGridElements.length == 0) {
         return;
@@ -821,8 +834,15 @@ function setupCollapsibleGrids() {
 }
 
 const contentReadyCallbacks = [];
+let contentIsReady = false;
 
 function afterContentReady(callback) {
+    // widgets set up after a refresh are already part of a ready page
+    if (contentIsReady) {
+        callback();
+        return;
+    }
+
     contentReadyCallbacks.push(callback);
 }
 
@@ -898,8 +918,8 @@ function zoneDiffText(diffInMinutes) {
     return { text: `${sign}${hours}h~`, title: `${hours} hour${hourSuffix} and ${minutes} minutes ${signText}` };
 }
 
-function setupClocks() {
-    const clocks = document.getElementsByClassName('clock');
+function setupClocks(root = document) {
+    const clocks = root.querySelectorAll('.clock');
 
     if (clocks.length == 0) {
         return;
@@ -948,6 +968,11 @@ function setupClocks() {
     }
 
     const updateClocks = () => {
+        // the clocks were replaced by a refresh of their widget
+        if (!Array.from(clocks).some((clock) => clock.isConnected)) {
+            return;
+        }
+
         const now = new Date();
 
         for (var i = 0; i < updateCallbacks.length; i++)
@@ -959,8 +984,8 @@ function setupClocks() {
     updateClocks();
 }
 
-async function setupCalendars() {
-    const elems = document.getElementsByClassName("calendar");
+async function setupCalendars(root = document) {
+    const elems = root.querySelectorAll(".calendar");
     if (elems.length == 0) return;
 
     // TODO: implement prefetching, currently loads as a nasty waterfall of requests
@@ -970,8 +995,8 @@ async function setupCalendars() {
         calendar.default(elems[i]);
 }
 
-async function setupTodos() {
-    const elems = Array.from(document.getElementsByClassName("todo"));
+async function setupTodos(root = document) {
+    const elems = findInRoot(root, ".todo");
     if (elems.length == 0) return;
 
     const todo = await import ('./todo.js');
@@ -981,8 +1006,8 @@ async function setupTodos() {
     }
 }
 
-async function setupRadyjko() {
-    const elems = Array.from(document.getElementsByClassName("widget-type-radyjko"));
+async function setupRadyjko(root = document) {
+    const elems = findInRoot(root, ".widget-type-radyjko");
     if (elems.length == 0) return;
 
     const radyjko = await import ('./radyjko.js');
@@ -992,8 +1017,8 @@ async function setupRadyjko() {
     }
 }
 
-async function setupNavidrome() {
-    const elems = Array.from(document.getElementsByClassName("widget-type-navidrome"));
+async function setupNavidrome(root = document) {
+    const elems = findInRoot(root, ".widget-type-navidrome");
     if (elems.length == 0) return;
 
     const navidrome = await import ('./navidrome.js');
@@ -1003,8 +1028,8 @@ async function setupNavidrome() {
     }
 }
 
-async function setupVikunja() {
-    const elems = Array.from(document.getElementsByClassName("widget-type-vikunja"));
+async function setupVikunja(root = document) {
+    const elems = findInRoot(root, ".widget-type-vikunja");
     if (elems.length == 0) return;
 
     const vikunja = await import ('./vikunja.js');
@@ -1014,8 +1039,8 @@ async function setupVikunja() {
     }
 }
 
-async function setupCloudflare() {
-    const elems = Array.from(document.getElementsByClassName("widget-type-cloudflare"));
+async function setupCloudflare(root = document) {
+    const elems = findInRoot(root, ".widget-type-cloudflare");
     if (elems.length == 0) return;
 
     const cloudflare = await import ('./cloudflare.js');
@@ -1025,8 +1050,8 @@ async function setupCloudflare() {
     }
 }
 
-async function setupGoogleCompute() {
-    const elems = Array.from(document.getElementsByClassName("widget-type-google-compute"));
+async function setupGoogleCompute(root = document) {
+    const elems = findInRoot(root, ".widget-type-google-compute");
     if (elems.length == 0) return;
 
     const googleCompute = await import ('./google-compute.js');
@@ -1036,8 +1061,15 @@ async function setupGoogleCompute() {
     }
 }
 
-async function setupBeszel() {
-    const elems = Array.from(document.getElementsByClassName("widget-type-beszel"));
+async function setupDockerContainers() {
+    if (document.querySelector("[data-docker-widget-id]") === null) return;
+
+    const dockerContainers = await import ('./docker-containers.js');
+    dockerContainers.default();
+}
+
+async function setupBeszel(root = document) {
+    const elems = findInRoot(root, ".widget-type-beszel");
     if (elems.length == 0) return;
 
     const beszel = await import ('./beszel.js');
@@ -1047,6 +1079,56 @@ async function setupBeszel() {
     }
 }
 
+// Swaps the widget for the markup returned by one of its endpoints, which is
+// how refreshes and widget actions show their outcome
+export async function replaceWidget(widgetElement, response) {
+    if (!response.ok) {
+        throw new Error(`Request failed (${response.status}): ${await response.text()}`);
+    }
+
+    const container = document.createElement("div");
+    container.innerHTML = await response.text();
+    const newWidget = container.firstElementChild;
+
+    if (newWidget === null) {
+        throw new Error("No widget markup returned");
+    }
+
+    widgetElement.replaceWith(newWidget);
+    await setupWidgets(newWidget);
+}
+
+async function refreshWidget(button) {
+    const widgetElement = button.closest(".widget");
+    if (widgetElement === null || button.classList.contains("refreshing")) return;
+
+    button.classList.add("refreshing");
+
+    try {
+        const response = await fetch(`${pageData.baseURL}/api/widgets/${button.dataset.widgetRefresh}/refresh`, {
+            method: "POST",
+        });
+
+        // the widget's show-when rules no longer match
+        if (response.status === 204) {
+            widgetElement.remove();
+            return;
+        }
+
+        await replaceWidget(widgetElement, response);
+    } catch (err) {
+        console.error("Failed to refresh widget", err);
+        button.classList.remove("refreshing");
+    }
+}
+
+function setupWidgetRefreshButtons() {
+    document.addEventListener("click", (event) => {
+        const button = event.target.closest(".widget-refresh-button");
+        if (button !== null) refreshWidget(button);
+    });
+}
+
 function setupTruncatedElementTitles() {
     const elements = document.querySelectorAll(".text-truncate, .single-line-titles .title, .text-truncate-2-lines, .text-truncate-3-lines");
 
@@ -1215,6 +1297,30 @@ function setupSwipeNavigation() {
     document.addEventListener("touchend", touchEnd, { passive: true });
 }
 
+// Everything that has to run again for the new elements of a refreshed widget
+async function setupWidgets(root = document) {
+    setupPopovers(root);
+    setupClocks(root);
+    await Promise.all([
+        setupCalendars(root),
+        setupTodos(root),
+        setupRadyjko(root),
+        setupNavidrome(root),
+        setupVikunja(root),
+        setupCloudflare(root),
+        setupGoogleCompute(root),
+        setupDockerContainers(),
+        setupBeszel(root)
+    ]);
+    setupCarousels(root);
+    setupCollapsibleLists(root);
+    setupCollapsibleGrids(root);
+    setupGroups(root);
+    setupMasonries(root);
+    setupLazyImages(root);
+    updateRelativeTimeForElements(root.querySelectorAll("[data-dynamic-relative-time]"));
+}
+
 async function setupPage() {
     initThemePicker();
 
@@ -1225,30 +1331,15 @@ async function setupPage() {
     pageContentElement.innerHTML = pageContent;
 
     try {
-        setupPopovers();
-        setupClocks()
-        await Promise.all([
-            setupCalendars(),
-            setupTodos(),
-            setupRadyjko(),
-            setupNavidrome(),
-            setupVikunja(),
-            setupCloudflare(),
-            setupGoogleCompute(),
-            setupBeszel()
-        ]);
-        setupCarousels();
+        await setupWidgets();
         setupSearchBoxes();
-        setupCollapsibleLists();
-        setupCollapsibleGrids();
-        setupGroups();
-        setupMasonries();
         setupDynamicRelativeTime();
-        setupLazyImages();
         setupSwipeNavigation();
+        setupWidgetRefreshButtons();
     } finally {
         pageElement.classList.add("content-ready");
         pageElement.setAttribute("aria-busy", "false");
+        contentIsReady = true;
 
         for (let i = 0; i < contentReadyCallbacks.length; i++) {
             contentReadyCallbacks[i]();
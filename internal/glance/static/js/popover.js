@@ -82,7 +82,10 @@ function showPopover() {
         htmlContent.replaceWith(placeholder);
         contentElement.replaceChildren(htmlContent);
         htmlContent.removeAttribute("data-popover-html");
+        // lets content which is loaded on demand know when it's visible
+        htmlContent.dispatchEvent(new CustomEvent("popover-show", { bubbles: true }));
         cleanupOnHidePopover = () => {
+            htmlContent.dispatchEvent(new CustomEvent("popover-hide", { bubbles: true }));
             htmlContent.setAttribute("data-popover-html", "");
             placeholder.replaceWith(htmlContent);
             placeholder.remove();
@@ -184,8 +187,8 @@ function handleHidePopoverOnEscape(event) {
     }
 }
 
-export function setupPopovers() {
-    const targets = document.querySelectorAll("[data-popover-type]");
+export function setupPopovers(root = document) {
+    const targets = root.querySelectorAll("[data-popover-type]");
 
     for (let i = 0; i < targets.length; i++) {
         const target = targets[i];
@@ -0,0 +1,299 @@
+package glance
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+var statusPageTemplate = mustParseTemplate("status-page.html", "document.html", "footer.html")
+
+const (
+	statusPageIncidentsLimit = 10
+	statusPageIncidentsRange = 7 * 24 * time.Hour
+)
+
+// Everything on the status page is served without authentication, so only
+// what's explicitly copied into the structs below ever gets exposed
+type statusPageConfig struct {
+	Enabled   bool   `yaml:"enabled"`
+	Slug      string `yaml:"slug"`
+	Title     string `yaml:"title"`
+	ShowLinks bool   `yaml:"show-links"`
+}
+
+func (c *statusPageConfig) validate() error {
+	if !c.Enabled || c.Slug == "" {
+		return nil
+	}
+
+	if strings.ContainsAny(c.Slug, "/.?# ") {
+		return errors.New("status-page: slug can not contain slashes, dots, spaces, ? or #")
+	}
+
+	if slices.Contains(reservedPageSlugs, c.Slug) || slices.Contains([]string{"api", "static", "assets", "manifest.json"}, c.Slug) {
+		return fmt.Errorf("status-page: slug \"%s\" is reserved", c.Slug)
+	}
+
+	return nil
+}
+
+type statusPageData struct {
+	Title     string               `json:"title"`
+	Status    string               `json:"status"`
+	UpdatedAt time.Time            `json:"updated_at"`
+	Groups    []statusPageGroup    `json:"groups"`
+	Incidents []statusPageIncident `json:"incidents"`
+}
+
+type statusPageGroup struct {
+	Title    string              `json:"title"`
+	Services []statusPageService `json:"services"`
+}
+
+type statusPageService struct {
+	Name           string            `json:"name"`
+	URL            string            `json:"url,omitempty"`
+	Status         string            `json:"status"`
+	ResponseTimeMs int64             `json:"response_ms,omitempty"`
+	Uptime24h      string            `json:"uptime_24h,omitempty"`
+	Uptime7d       string            `json:"uptime_7d,omitempty"`
+	LastCheckedAt  *time.Time        `json:"last_checked_at,omitempty"`
+	RecentChecks   []statusPageCheck `json:"recent_checks"`
+}
+
+type statusPageCheck struct {
+	Time time.Time `json:"time"`
+	Up   bool      `json:"up"`
+}
+
+type statusPageIncident struct {
+	Service   string     `json:"service"`
+	StartedAt time.Time  `json:"started_at"`
+	EndedAt   *time.Time `json:"ended_at,omitempty"`
+	Ongoing   bool       `json:"ongoing"`
+}
+
+const (
+	statusPageUp      = "up"
+	statusPageDown    = "down"
+	statusPageUnknown = "unknown"
+)
+
+func (s *statusPageService) StatusText() string {
+	switch s.Status {
+	case statusPageUp:
+		return "Działa"
+	case statusPageDown:
+		return "Awaria"
+	}
+
+	return "Brak danych"
+}
+
+func (d *statusPageData) StatusText() string {
+	switch d.Status {
+	case statusPageUp:
+		return "Wszystkie usługi działają"
+	case statusPageDown:
+		return "Część usług ma problemy"
+	}
+
+	return "Brak danych o stanie usług"
+}
+
+func (i *statusPageIncident) DurationText() string {
+	end := time.Now()
+	if i.EndedAt != nil {
+		end = *i.EndedAt
+	}
+
+	return formatStatusPageDuration(end.Sub(i.StartedAt))
+}
+
+func formatStatusPageDuration(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return "< 1 min"
+	case d < time.Hour:
+		return fmt.Sprintf("%d min", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%d godz. %d min", int(d.Hours()), int(d.Minutes())%60)
+	}
+
+	return fmt.Sprintf("%s %d godz.", formatPolishPlural(int(d.Hours()/24), "dzień", "dni", "dni"), int(d.Hours())%24)
+}
+
+func (a *application) collectStatusPageData() *statusPageData {
+	data := &statusPageData{
+		Title:     a.Config.StatusPage.Title,
+		UpdatedAt: time.Now(),
+		Groups:    []statusPageGroup{},
+		Incidents: []statusPageIncident{},
+	}
+
+	for p := range a.Config.Pages {
+		page := &a.Config.Pages[p]
+		page.mu.RLock()
+
+		var monitors []*monitorWidget
+		collect := func(ws widgets) {
+			for _, w := range ws {
+				for _, descendant := range append(widgets{w}, widgetDescendants(w)...) {
+					if monitor, ok := descendant.(*monitorWidget); ok {
+						monitors = append(monitors, monitor)
+					}
+				}
+			}
+		}
+
+		collect(page.HeadWidgets)
+		for c := range page.Columns {
+			collect(page.Columns[c].Widgets)
+		}
+
+		for _, monitor := range monitors {
+			group, incidents := newStatusPageGroup(monitor, a.Config.StatusPage.ShowLinks, data.UpdatedAt)
+			if len(group.Services) > 0 {
+				data.Groups = append(data.Groups, group)
+			}
+			data.Incidents = append(data.Incidents, incidents...)
+		}
+
+		page.mu.RUnlock()
+	}
+
+	slices.SortStableFunc(data.Incidents, func(a, b statusPageIncident) int {
+		return b.StartedAt.Compare(a.StartedAt)
+	})
+	data.Incidents = data.Incidents[:min(len(data.Incidents), statusPageIncidentsLimit)]
+
+	data.Status = statusPageUnknown
+	for g := range data.Groups {
+		for _, service := range data.Groups[g].Services {
+			if service.Status == statusPageDown {
+				data.Status = statusPageDown
+			} else if service.Status == statusPageUp && data.Status == statusPageUnknown {
+				data.Status = statusPageUp
+			}
+		}
+	}
+
+	return data
+}
+
+// Credentials and tokens tend to end up in the userinfo and the query, so only
+// the scheme, host and path are published, and only for http links since site
+// URLs can come from container labels
+func publicStatusPageURL(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return ""
+	}
+
+	public := url.URL{Scheme: parsed.Scheme, Host: parsed.Host, Path: parsed.Path}
+	return public.String()
+}
+
+func newStatusPageGroup(monitor *monitorWidget, showLinks bool, now time.Time) (statusPageGroup, []statusPageIncident) {
+	group := statusPageGroup{Title: monitor.Title, Services: make([]statusPageService, 0, len(monitor.Sites))}
+	var incidents []statusPageIncident
+
+	for i := range monitor.Sites {
+		site := &monitor.Sites[i]
+		service := statusPageService{Name: site.Title, Status: statusPageUnknown}
+
+		// the check url and anything behind basic auth are never shown, only
+		// the public address of the site and only when it was opted into
+		if showLinks && site.DefaultURL != "" && site.BasicAuth.Username == "" && site.BasicAuth.Password == "" {
+			service.URL = publicStatusPageURL(site.DefaultURL)
+		}
+
+		checks := site.History.values()
+		if len(checks) > 0 {
+			last := checks[len(checks)-1]
+			service.Status = ternary(last.Up, statusPageUp, statusPageDown)
+			service.LastCheckedAt = &last.Time
+			if last.Up {
+				service.ResponseTimeMs = last.ResponseTimeMs
+			}
+		}
+
+		service.Uptime24h = site.History.Uptime24h()
+		service.Uptime7d = site.History.Uptime7d()
+
+		recent := site.History.RecentChecks()
+		service.RecentChecks = make([]statusPageCheck, len(recent))
+		for j := range recent {
+			service.RecentChecks[j] = statusPageCheck{Time: recent[j].Time, Up: recent[j].Up}
+		}
+
+		group.Services = append(group.Services, service)
+		incidents = append(incidents, incidentsFromChecks(site.Title, checks, now.Add(-statusPageIncidentsRange))...)
+	}
+
+	return group, incidents
+}
+
+// An incident spans consecutive failed checks, it ends with the first
+// successful check after them
+func incidentsFromChecks(service string, checks []monitorCheck, since time.Time) []statusPageIncident {
+	var incidents []statusPageIncident
+	var current *statusPageIncident
+
+	for i := range checks {
+		check := &checks[i]
+
+		if !check.Up {
+			if current == nil {
+				incidents = append(incidents, statusPageIncident{Service: service, StartedAt: check.Time, Ongoing: true})
+				current = &incidents[len(incidents)-1]
+			}
+			continue
+		}
+
+		if current != nil {
+			endedAt := check.Time
+			current.EndedAt = &endedAt
+			current.Ongoing = false
+			current = nil
+		}
+	}
+
+	return slices.DeleteFunc(incidents, func(incident statusPageIncident) bool {
+		return !incident.Ongoing && incident.EndedAt.Before(since)
+	})
+}
+
+func (a *application) handleStatusPageRequest(w http.ResponseWriter, r *http.Request) {
+	data := struct {
+		templateData
+		Status *statusPageData
+	}{
+		templateData: templateData{App: a},
+		Status:       a.collectStatusPageData(),
+	}
+	a.populateTemplateRequestData(&data.Request, r)
+
+	var responseBytes bytes.Buffer
+	err := statusPageTemplate.Execute(&responseBytes, data)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	w.Write(responseBytes.Bytes())
+}
+
+func (a *application) handleStatusPageJSONRequest(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	json.NewEncoder(w).Encode(a.collectStatusPageData())
+}
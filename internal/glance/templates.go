@@ -72,6 +72,26 @@ var globalTemplateFunctions = template.FuncMap{
 			label = "TB"
 		}
 
+		return template.HTML(value + ` <span class="color-base size-h5">` + label + `</span>`)
+	},
+	"formatServerBytesPerSecond": func(bytes uint64) template.HTML {
+		var value string
+		var label string
+
+		if bytes < 1_000 {
+			value = strconv.FormatUint(bytes, 10)
+			label = "B/s"
+		} else if bytes < 1_000_000 {
+			value = strconv.FormatUint(bytes/1_000, 10)
+			label = "KB/s"
+		} else if bytes < 1_000_000_000 {
+			value = fmt.Sprintf("%.1f", float64(bytes)/1_000_000)
+			label = "MB/s"
+		} else {
+			value = fmt.Sprintf("%.1f", float64(bytes)/1_000_000_000)
+			label = "GB/s"
+		}
+
 		return template.HTML(value + ` <span class="color-base size-h5">` + label + `</span>`)
 	},
 }
@@ -0,0 +1,417 @@
+package glance
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type upstreamFixturesMode int
+
+const (
+	upstreamFixturesDisabled upstreamFixturesMode = iota
+	upstreamFixturesRecord
+	upstreamFixturesReplay
+)
+
+// Set once on startup through --record-dir or --replay-dir, before any requests are made
+var upstreamFixtures = struct {
+	mode upstreamFixturesMode
+	dir  string
+}{}
+
+func enableUpstreamFixtures(mode upstreamFixturesMode, dir string) error {
+	if mode == upstreamFixturesRecord {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("creating record directory: %v", err)
+		}
+	} else if stat, err := os.Stat(dir); err != nil || !stat.IsDir() {
+		return fmt.Errorf("replay directory %s does not exist", dir)
+	}
+
+	upstreamFixtures.mode = mode
+	upstreamFixtures.dir = dir
+
+	return nil
+}
+
+// Wraps the transport of every client used to talk to upstream services so
+// that requests get logged and can be recorded or replayed
+func newUpstreamTransport(underlying http.RoundTripper) http.RoundTripper {
+	return &debugTransport{
+		underlying: &fixtureTransport{underlying: underlying},
+	}
+}
+
+// fixtureTransport saves every request/response pair to disk when recording and
+// serves them back without touching the network when replaying
+type fixtureTransport struct {
+	underlying http.RoundTripper
+}
+
+type upstreamFixture struct {
+	Method string `json:"method"`
+	// with credentials such as API keys in the query replaced
+	URL string `json:"url"`
+	// the body of the request itself isn't saved since it may contain credentials
+	RequestBodyHash string      `json:"request-body-sha256,omitempty"`
+	StatusCode      int         `json:"status"`
+	Header          http.Header `json:"header,omitempty"`
+	Body            string      `json:"body"`
+	BodyBase64      bool        `json:"body-base64,omitempty"`
+}
+
+func (t *fixtureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if upstreamFixtures.mode == upstreamFixturesDisabled {
+		return t.underlying.RoundTrip(req)
+	}
+
+	requestBody, err := readAndRestoreRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	path := upstreamFixturePath(upstreamFixtures.dir, req, requestBody)
+
+	if upstreamFixtures.mode == upstreamFixturesReplay {
+		return replayUpstreamFixture(req, requestBody, path)
+	}
+
+	resp, err := t.underlying.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if isStreamingUpstreamResponse(req, resp) {
+		return resp, nil
+	}
+
+	return recordUpstreamFixture(req, requestBody, resp, path)
+}
+
+// Responses which keep going for as long as the client is listening, such as followed
+// container logs, can't be read in full before being returned so they aren't recorded
+func isStreamingUpstreamResponse(req *http.Request, resp *http.Response) bool {
+	query := req.URL.Query()
+	for _, name := range []string{"follow", "stream"} {
+		if streaming, _ := strconv.ParseBool(query.Get(name)); streaming {
+			return true
+		}
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return mediaType == "text/event-stream" || mediaType == "application/x-ndjson"
+}
+
+func readAndRestoreRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("reading request body: %v", err)
+	}
+
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+var unsafeFixturePathCharsPattern = regexp.MustCompile(`[^a-zA-Z0-9.-]+`)
+
+// Names of query parameters, response headers and JSON fields which tend to hold credentials
+var sensitiveUpstreamNamePattern = regexp.MustCompile(`(?i)(token|pass(word|wd)?|secret|api[-_]?key|access[-_]?key|auth([-_]|$|orization|entic)|signature|session|cookie|^key$|^appid$|^sig$)`)
+
+// Fixtures are meant to be committed, so credentials in the query and the
+// userinfo are replaced before the URL gets written or hashed
+func redactedUpstreamURL(u *url.URL) string {
+	redacted := *u
+	redacted.User = nil
+
+	query := redacted.Query()
+	for name, values := range query {
+		if sensitiveUpstreamNamePattern.MatchString(name) {
+			for i := range values {
+				values[i] = configRedactedValue
+			}
+		}
+	}
+	redacted.RawQuery = query.Encode()
+
+	return redacted.String()
+}
+
+// Responses of login and token endpoints contain credentials as well, the body is
+// returned as is when it isn't JSON or has nothing that needs to be redacted
+func redactedUpstreamJSONBody(body []byte) []byte {
+	if !json.Valid(body) {
+		return body
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil || !redactUpstreamJSONValue(value) {
+		return body
+	}
+
+	var redacted bytes.Buffer
+	encoder := json.NewEncoder(&redacted)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return body
+	}
+
+	return bytes.TrimSuffix(redacted.Bytes(), []byte("\n"))
+}
+
+func redactUpstreamJSONValue(value any) bool {
+	redacted := false
+
+	switch value := value.(type) {
+	case map[string]any:
+		for name, field := range value {
+			if _, isString := field.(string); isString && sensitiveUpstreamNamePattern.MatchString(name) {
+				value[name] = configRedactedValue
+				redacted = true
+				continue
+			}
+
+			redacted = redactUpstreamJSONValue(field) || redacted
+		}
+	case []any:
+		for _, item := range value {
+			redacted = redactUpstreamJSONValue(item) || redacted
+		}
+	}
+
+	return redacted
+}
+
+func upstreamRequestBodyHash(body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+
+	hash := sha256.Sum256(body)
+	return hex.EncodeToString(hash[:])
+}
+
+// Requests are identified by their method, URL and body, headers are deliberately left
+// out since they tend to contain credentials which differ between environments, and
+// so are credentials in the URL
+func upstreamFixturePath(dir string, req *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(req.Method + " " + redactedUpstreamURL(req.URL) + "\n"))
+	hash.Write(body)
+
+	host := unsafeFixturePathCharsPattern.ReplaceAllString(req.URL.Host, "_")
+	if host == "" {
+		host = "_"
+	}
+
+	return filepath.Join(dir, host, req.Method+"-"+hex.EncodeToString(hash.Sum(nil))[:24]+".json")
+}
+
+func recordUpstreamFixture(req *http.Request, requestBody []byte, resp *http.Response, path string) (*http.Response, error) {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("reading response body for recording: %v", err)
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	fixture := upstreamFixture{
+		Method:          req.Method,
+		URL:             redactedUpstreamURL(req.URL),
+		RequestBodyHash: upstreamRequestBodyHash(requestBody),
+		StatusCode:      resp.StatusCode,
+		Header:          resp.Header.Clone(),
+	}
+	fixture.Header.Del("Set-Cookie")
+	for name := range fixture.Header {
+		if sensitiveUpstreamNamePattern.MatchString(name) {
+			fixture.Header.Set(name, configRedactedValue)
+		}
+	}
+
+	if utf8.Valid(body) {
+		fixture.Body = string(redactedUpstreamJSONBody(body))
+	} else {
+		fixture.Body = base64.StdEncoding.EncodeToString(body)
+		fixture.BodyBase64 = true
+	}
+
+	if err := writeUpstreamFixture(path, &fixture); err != nil {
+		// failing to record shouldn't affect the widget
+		log.Printf("Failed to record response for %s %s: %v", req.Method, fixture.URL, err)
+	}
+
+	return resp, nil
+}
+
+func writeUpstreamFixture(path string, fixture *upstreamFixture) error {
+	encoded, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(path), ".fixture-*")
+	if err != nil {
+		return err
+	}
+
+	if _, err := temp.Write(encoded); err != nil {
+		temp.Close()
+		os.Remove(temp.Name())
+		return err
+	}
+	temp.Close()
+
+	return os.Rename(temp.Name(), path)
+}
+
+func replayUpstreamFixture(req *http.Request, requestBody []byte, path string) (*http.Response, error) {
+	fixture, err := readUpstreamFixture(path)
+	if errors.Is(err, os.ErrNotExist) {
+		fixture, err = findSimilarUpstreamFixture(req, requestBody, filepath.Dir(path))
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("replaying %s %s: %w", req.Method, redactedUpstreamURL(req.URL), err)
+	}
+
+	body := []byte(fixture.Body)
+	if fixture.BodyBase64 {
+		body, err = base64.StdEncoding.DecodeString(fixture.Body)
+		if err != nil {
+			return nil, fmt.Errorf("decoding recorded body: %v", err)
+		}
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", fixture.StatusCode, http.StatusText(fixture.StatusCode)),
+		StatusCode:    fixture.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        ternary(fixture.Header == nil, http.Header{}, fixture.Header),
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+func readUpstreamFixture(path string) (*upstreamFixture, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	fixture := &upstreamFixture{}
+	if err := json.Unmarshal(contents, fixture); err != nil {
+		return nil, fmt.Errorf("parsing %s: %v", path, err)
+	}
+
+	return fixture, nil
+}
+
+// Some requests contain values which change between runs, such as timestamps in
+// the query, in which case we fall back to a recording of the same method, path,
+// body and query parameters, preferring the one with the most matching values
+func findSimilarUpstreamFixture(req *http.Request, requestBody []byte, dir string) (*upstreamFixture, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, errors.New("no recorded response")
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), req.Method+"-") && strings.HasSuffix(entry.Name(), ".json") {
+			names = append(names, entry.Name())
+		}
+	}
+	slices.Sort(names)
+
+	requestURL, err := url.Parse(redactedUpstreamURL(req.URL))
+	if err != nil {
+		return nil, err
+	}
+	requestQuery := requestURL.Query()
+	bodyHash := upstreamRequestBodyHash(requestBody)
+
+	var best *upstreamFixture
+	bestScore := -1
+
+	for _, name := range names {
+		fixture, err := readUpstreamFixture(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+
+		if fixture.Method != req.Method || fixture.RequestBodyHash != bodyHash {
+			continue
+		}
+
+		fixtureURL, err := url.Parse(fixture.URL)
+		if err != nil || fixtureURL.Path != requestURL.Path {
+			continue
+		}
+
+		score, ok := matchingQueryValues(requestQuery, fixtureURL.Query())
+		if ok && score > bestScore {
+			best, bestScore = fixture, score
+		}
+	}
+
+	if best == nil {
+		return nil, errors.New("no recorded response")
+	}
+
+	return best, nil
+}
+
+// Both queries need to have the same parameters, the number of them which
+// also have the same values is returned
+func matchingQueryValues(a, b url.Values) (int, bool) {
+	if len(a) != len(b) {
+		return 0, false
+	}
+
+	matching := 0
+	for name, values := range a {
+		other, ok := b[name]
+		if !ok {
+			return 0, false
+		}
+
+		if slices.Equal(values, other) {
+			matching++
+		}
+	}
+
+	return matching, true
+}
@@ -0,0 +1,146 @@
+package glance
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUpstreamFixturesReplayRecordedResponsesWithoutNetwork(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"access_token": "body-secret", "expires_in": 300, "user": {"name": "admin", "password": "body-secret"}}`))
+			return
+		}
+
+		if r.URL.Path == "/logs" {
+			// never ends on its own, like followed logs
+			w.Write([]byte("first line\n"))
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Session-Token", "response-secret")
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(`{"path":"` + r.URL.Path + `","body":"` + string(body) + `"}`))
+	}))
+
+	dir := t.TempDir()
+	client := &http.Client{Transport: newUpstreamTransport(http.DefaultTransport)}
+
+	t.Cleanup(func() { upstreamFixtures.mode = upstreamFixturesDisabled })
+
+	do := func(method, path, body string) (*http.Response, string, error) {
+		t.Helper()
+
+		request, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		response, err := client.Do(request)
+		if err != nil {
+			return nil, "", err
+		}
+		defer response.Body.Close()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		return response, string(responseBody), nil
+	}
+
+	if err := enableUpstreamFixtures(upstreamFixturesRecord, dir); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := do("GET", "/items?since=1&api_key=query-secret", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := do("POST", "/login", "user"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, body, err := do("GET", "/token", ""); err != nil || !strings.Contains(body, "body-secret") {
+		t.Errorf("expected the response to be returned as is while recording, got %q, %v", body, err)
+	}
+
+	logsRequest, _ := http.NewRequest("GET", server.URL+"/logs?follow=1", nil)
+	logsResponse, err := client.Do(logsRequest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := make(chan string, 1)
+	go func() {
+		line, _ := bufio.NewReader(logsResponse.Body).ReadString('\n')
+		lines <- line
+	}()
+
+	select {
+	case line := <-lines:
+		if line != "first line\n" {
+			t.Errorf("unexpected streamed line %q", line)
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("expected streaming responses to be passed through without waiting for them to end")
+	}
+	logsResponse.Body.Close()
+
+	server.CloseClientConnections()
+	server.Close()
+
+	files, _ := os.ReadDir(dir)
+	for _, file := range files {
+		contents, _ := os.ReadFile(filepath.Join(dir, file.Name()))
+		if strings.Contains(string(contents), "query-secret") || strings.Contains(string(contents), "response-secret") || strings.Contains(string(contents), "body-secret") {
+			t.Errorf("expected credentials to be redacted from %s, got:\n%s", file.Name(), contents)
+		}
+	}
+
+	if err := enableUpstreamFixtures(upstreamFixturesReplay, dir); err != nil {
+		t.Fatal(err)
+	}
+
+	response, body, err := do("POST", "/login", "user")
+	if err != nil {
+		t.Fatalf("expected the recorded response to be replayed, got: %v", err)
+	}
+
+	if response.StatusCode != http.StatusAccepted || response.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("unexpected status or headers: %d %v", response.StatusCode, response.Header)
+	}
+
+	if body != `{"path":"/login","body":"user"}` {
+		t.Errorf("unexpected body: %s", body)
+	}
+
+	if _, body, err = do("GET", "/items?since=2&api_key=other-secret", ""); err != nil || !strings.Contains(body, "/items") {
+		t.Errorf("expected a recording with the same path to be used, got %q, %v", body, err)
+	}
+
+	if _, _, err := do("GET", "/items?page=2", ""); err == nil {
+		t.Error("expected an error for a request with different query parameters")
+	}
+
+	if _, _, err := do("POST", "/login", "admin"); err == nil {
+		t.Error("expected an error for a request with a different body")
+	}
+
+	if _, body, err := do("GET", "/token", ""); err != nil || !strings.Contains(body, `"expires_in":300`) || !strings.Contains(body, `"name":"admin"`) {
+		t.Errorf("expected only the credentials to be redacted from the body, got %q, %v", body, err)
+	}
+
+	if _, _, err := do("GET", "/missing", ""); err == nil {
+		t.Error("expected an error for a request that was never recorded")
+	}
+}
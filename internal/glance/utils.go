@@ -252,3 +252,39 @@ func hslToHex(h, s, l float64) string {
 
 	return fmt.Sprintf("#%02x%02x%02x", ir, ig, ib)
 }
+
+// Fixed capacity buffer which overwrites the oldest item once full
+type ringBuffer[T any] struct {
+	items []T
+	start int
+	size  int
+}
+
+func newRingBuffer[T any](capacity int) *ringBuffer[T] {
+	return &ringBuffer[T]{items: make([]T, capacity)}
+}
+
+func (r *ringBuffer[T]) push(item T) {
+	if len(r.items) == 0 {
+		return
+	}
+
+	if r.size < len(r.items) {
+		r.items[(r.start+r.size)%len(r.items)] = item
+		r.size++
+		return
+	}
+
+	r.items[r.start] = item
+	r.start = (r.start + 1) % len(r.items)
+}
+
+// Returns the items ordered from oldest to newest
+func (r *ringBuffer[T]) values() []T {
+	values := make([]T, r.size)
+	for i := range r.size {
+		values[i] = r.items[(r.start+i)%len(r.items)]
+	}
+
+	return values
+}
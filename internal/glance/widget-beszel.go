@@ -18,6 +18,12 @@ import (
 
 var beszelWidgetTemplate = mustParseTemplate("beszel.html", "widget-base.html")
 
+func init() {
+	registerWidget("beszel", func() widget { return &beszelWidget{} },
+		newWidgetAction("POST", "chart", (*beszelWidget).handleChartRequest),
+	)
+}
+
 type beszelWidget struct {
 	widgetBase     `yaml:",inline"`
 	URL            string         `yaml:"url"`
@@ -440,3 +446,43 @@ func generateBeszelAxisLabels(series []beszelChartSeriesPoint, timeRange string)
 
 	return labels
 }
+
+func (widget *beszelWidget) handleChartRequest(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		SystemID  string `json:"system_id"`
+		Metric    string `json:"metric"`
+		TimeRange string `json:"time_range"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid request body"))
+		return
+	}
+
+	// Walidacja parametrów
+	validMetrics := map[string]bool{"cpu": true, "ram": true, "disk": true, "network": true}
+	if !validMetrics[request.Metric] {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid metric type"))
+		return
+	}
+
+	validTimeRanges := map[string]bool{"1m": true, "1h": true, "12h": true, "24h": true, "7d": true, "30d": true}
+	if !validTimeRanges[request.TimeRange] {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid time range"))
+		return
+	}
+
+	chartData, err := widget.FetchChartData(r.Context(), request.SystemID, request.Metric, request.TimeRange)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(fmt.Sprintf("Failed to fetch chart data: %v", err)))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(chartData)
+}
@@ -1,37 +1,52 @@
 package glance
 
 import (
+	"context"
+	"fmt"
 	"html/template"
+	"log/slog"
+	"time"
 )
 
 var bookmarksWidgetTemplate = mustParseTemplate("bookmarks.html", "widget-base.html")
 
+func init() {
+	registerWidget("bookmarks", func() widget { return &bookmarksWidget{} })
+}
+
 type bookmarksWidget struct {
 	widgetBase `yaml:",inline"`
-	cachedHTML template.HTML `yaml:"-"`
-	Groups     []struct {
-		Title     string         `yaml:"title"`
-		Color     *hslColorField `yaml:"color"`
-		SameTab   bool           `yaml:"same-tab"`
-		HideArrow bool           `yaml:"hide-arrow"`
-		Target    string         `yaml:"target"`
-		Links     []struct {
-			Title       string          `yaml:"title"`
-			URL         string          `yaml:"url"`
-			Description string          `yaml:"description"`
-			Icon        customIconField `yaml:"icon"`
-			// we need a pointer to bool to know whether a value was provided,
-			// however there's no way to dereference a pointer in a template so
-			// {{ if not .SameTab }} would return true for any non-nil pointer
-			// which leaves us with no way of checking if the value is true or
-			// false, hence the duplicated fields below
-			SameTabRaw   *bool  `yaml:"same-tab"`
-			SameTab      bool   `yaml:"-"`
-			HideArrowRaw *bool  `yaml:"hide-arrow"`
-			HideArrow    bool   `yaml:"-"`
-			Target       string `yaml:"target"`
-		} `yaml:"links"`
-	} `yaml:"groups"`
+	cachedHTML template.HTML    `yaml:"-"`
+	Groups     []bookmarksGroup `yaml:"groups"`
+}
+
+type bookmarksGroup struct {
+	Title      string                 `yaml:"title"`
+	Color      *hslColorField         `yaml:"color"`
+	SameTab    bool                   `yaml:"same-tab"`
+	HideArrow  bool                   `yaml:"hide-arrow"`
+	Target     string                 `yaml:"target"`
+	Links      []bookmarkLink         `yaml:"links"`
+	FromDocker *dockerDiscoveryConfig `yaml:"from-docker"`
+	// the links from the config, which the discovered ones get appended to
+	staticLinks []bookmarkLink
+}
+
+type bookmarkLink struct {
+	Title       string          `yaml:"title"`
+	URL         string          `yaml:"url"`
+	Description string          `yaml:"description"`
+	Icon        customIconField `yaml:"icon"`
+	// we need a pointer to bool to know whether a value was provided,
+	// however there's no way to dereference a pointer in a template so
+	// {{ if not .SameTab }} would return true for any non-nil pointer
+	// which leaves us with no way of checking if the value is true or
+	// false, hence the duplicated fields below
+	SameTabRaw   *bool  `yaml:"same-tab"`
+	SameTab      bool   `yaml:"-"`
+	HideArrowRaw *bool  `yaml:"hide-arrow"`
+	HideArrow    bool   `yaml:"-"`
+	Target       string `yaml:"target"`
 }
 
 func (widget *bookmarksWidget) initialize() error {
@@ -40,38 +55,115 @@ func (widget *bookmarksWidget) initialize() error {
 	for g := range widget.Groups {
 		group := &widget.Groups[g]
 		for l := range group.Links {
-			link := &group.Links[l]
-			if link.SameTabRaw == nil {
-				link.SameTab = group.SameTab
-			} else {
-				link.SameTab = *link.SameTabRaw
+			group.applyDefaults(&group.Links[l])
+		}
+
+		if group.FromDocker != nil {
+			if err := group.FromDocker.initialize(); err != nil {
+				return fmt.Errorf("group %d: %v", g+1, err)
 			}
 
-			if link.HideArrowRaw == nil {
-				link.HideArrow = group.HideArrow
+			group.staticLinks = group.Links
+		}
+	}
+
+	if widget.discoversFromDocker() {
+		widget.withCacheDuration(1 * time.Minute)
+		return nil
+	}
+
+	widget.cachedHTML = widget.renderTemplate(widget, bookmarksWidgetTemplate)
+
+	return nil
+}
+
+func (group *bookmarksGroup) applyDefaults(link *bookmarkLink) {
+	if link.SameTabRaw == nil {
+		link.SameTab = group.SameTab
+	} else {
+		link.SameTab = *link.SameTabRaw
+	}
+
+	if link.HideArrowRaw == nil {
+		link.HideArrow = group.HideArrow
+	} else {
+		link.HideArrow = *link.HideArrowRaw
+	}
+
+	if link.Target == "" {
+		if group.Target != "" {
+			link.Target = group.Target
+		} else {
+			if link.SameTab {
+				link.Target = ""
 			} else {
-				link.HideArrow = *link.HideArrowRaw
+				link.Target = "_blank"
 			}
+		}
+	}
+}
+
+func (widget *bookmarksWidget) discoversFromDocker() bool {
+	for g := range widget.Groups {
+		if widget.Groups[g].FromDocker != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (widget *bookmarksWidget) update(ctx context.Context) {
+	failed := 0
+
+	for g := range widget.Groups {
+		group := &widget.Groups[g]
+		if group.FromDocker == nil {
+			continue
+		}
 
-			if link.Target == "" {
-				if group.Target != "" {
-					link.Target = group.Target
-				} else {
-					if link.SameTab {
-						link.Target = ""
-					} else {
-						link.Target = "_blank"
-					}
-				}
+		services, err := group.FromDocker.discover()
+		if err != nil {
+			failed++
+			slog.Error("Failed to discover bookmarks from Docker", "group", group.Title, "error", err)
+			continue
+		}
+
+		links := make([]bookmarkLink, 0, len(group.staticLinks)+len(services))
+		links = append(links, group.staticLinks...)
+
+		for i := range services {
+			service := &services[i]
+			link := bookmarkLink{
+				Title:       service.Title,
+				URL:         service.URL,
+				Description: service.Description,
+				SameTabRaw:  service.SameTab,
 			}
+
+			if service.Icon != "" {
+				link.Icon = newCustomIconField(service.Icon)
+			}
+
+			group.applyDefaults(&link)
+			links = append(links, link)
 		}
+
+		group.Links = links
 	}
 
-	widget.cachedHTML = widget.renderTemplate(widget, bookmarksWidgetTemplate)
+	var err error
+	if failed > 0 {
+		err = fmt.Errorf("%w: could not discover containers for %d group(s)", errPartialContent, failed)
+	}
 
-	return nil
+	widget.canContinueUpdateAfterHandlingErr(err)
 }
 
 func (widget *bookmarksWidget) Render() template.HTML {
+	if widget.cachedHTML == "" {
+		return widget.renderTemplate(widget, bookmarksWidgetTemplate)
+	}
+
 	return widget.cachedHTML
 }
@@ -18,6 +18,10 @@ var calendarWeekdaysToInt = map[string]time.Weekday{
 	"saturday":  time.Saturday,
 }
 
+func init() {
+	registerWidget("calendar", func() widget { return &calendarWidget{} })
+}
+
 type calendarWidget struct {
 	widgetBase     `yaml:",inline"`
 	FirstDayOfWeek string        `yaml:"first-day-of-week"`
@@ -13,6 +13,10 @@ import (
 
 var changeDetectionWidgetTemplate = mustParseTemplate("change-detection.html", "widget-base.html")
 
+func init() {
+	registerWidget("change-detection", func() widget { return &changeDetectionWidget{} })
+}
+
 type changeDetectionWidget struct {
 	widgetBase       `yaml:",inline"`
 	ChangeDetections changeDetectionWatchList `yaml:"-"`
@@ -9,6 +9,10 @@ import (
 
 var clockWidgetTemplate = mustParseTemplate("clock.html", "widget-base.html")
 
+func init() {
+	registerWidget("clock", func() widget { return &clockWidget{} })
+}
+
 type clockWidget struct {
 	widgetBase `yaml:",inline"`
 	cachedHTML template.HTML `yaml:"-"`
@@ -22,6 +22,10 @@ func init() {
 	if err != nil {
 		defaultLocation = time.UTC
 	}
+
+	registerWidget("cloudflare", func() widget { return &cloudflareWidget{} },
+		newWidgetAction("POST", "update", (*cloudflareWidget).handleUpdateRequest),
+	)
 }
 
 type cloudflareWidget struct {
@@ -425,3 +429,30 @@ func buildCloudflareAxisLabels(series []cloudflareSeriesPoint, timeRange string)
 
 	return axisLabels
 }
+
+func (widget *cloudflareWidget) handleUpdateRequest(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		TimeRange string `json:"time_range"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid request body"))
+		return
+	}
+
+	if request.TimeRange != "24h" && request.TimeRange != "7d" && request.TimeRange != "30d" {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid time range"))
+		return
+	}
+
+	widget.TimeRange = request.TimeRange
+	updateWidget(context.Background(), widget)
+
+	html := widget.Render()
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(html))
+}
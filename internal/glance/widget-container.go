@@ -34,13 +34,29 @@ func (widget *containerWidgetBase) _update(ctx context.Context) {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			widget.update(ctx)
+			updateWidget(ctx, widget)
 		}()
 	}
 
 	wg.Wait()
 }
 
+func (widget *containerWidgetBase) childWidgets() widgets {
+	return widget.Widgets
+}
+
+// Used by the templates, children hidden through show-when are left out
+func (widget *containerWidgetBase) VisibleWidgets() widgets {
+	visible := make(widgets, 0, len(widget.Widgets))
+	for i := range widget.Widgets {
+		if widget.Widgets[i].IsVisible() {
+			visible = append(visible, widget.Widgets[i])
+		}
+	}
+
+	return visible
+}
+
 func (widget *containerWidgetBase) _setProviders(providers *widgetProviders) {
 	for i := range widget.Widgets {
 		widget.Widgets[i].setProviders(providers)
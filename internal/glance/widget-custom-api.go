@@ -37,6 +37,10 @@ type CustomAPIRequest struct {
 	httpRequest        *http.Request        `yaml:"-"`
 }
 
+func init() {
+	registerWidget("custom-api", func() widget { return &customAPIWidget{} })
+}
+
 type customAPIWidget struct {
 	widgetBase        `yaml:",inline"`
 	*CustomAPIRequest `yaml:",inline"`             // the primary request
@@ -24,6 +24,10 @@ const (
 	dnsStatsHoursPerBar int = dnsStatsHoursSpan / dnsStatsBars
 )
 
+func init() {
+	registerWidget("dns-stats", func() widget { return &dnsStatsWidget{} })
+}
+
 type dnsStatsWidget struct {
 	widgetBase `yaml:",inline"`
 
@@ -111,6 +115,12 @@ func (widget *dnsStatsWidget) update(ctx context.Context) {
 		}
 	}
 
+	if err != nil && !errors.Is(err, errPartialContent) {
+		widget.emitAlertEvent("", true, err.Error())
+	} else {
+		widget.emitAlertEvent("", false, "")
+	}
+
 	if !widget.canContinueUpdateAfterHandlingErr(err) {
 		return
 	}
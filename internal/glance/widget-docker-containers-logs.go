@@ -0,0 +1,292 @@
+package glance
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	dockerContainerDefaultLogLines = 100
+	dockerContainerMaxLogLines     = 1000
+	// Longer lines get cut off
+	dockerContainerMaxLogLineSize = 16 << 10
+	// Out of the maxOpenConnsPerHost connections each host gets
+	dockerMaxLogFollowersPerHost = 5
+)
+
+type dockerLogLine struct {
+	Stream string `json:"stream"`
+	Text   string `json:"text"`
+}
+
+// Color codes, cursor movement and terminal titles
+var ansiEscapeSequencePattern = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)|\x1b[@-_]`)
+
+func stripANSI(text string) string {
+	return ansiEscapeSequencePattern.ReplaceAllString(text, "")
+}
+
+// Unless the container has a TTY, Docker prefixes every chunk of the output
+// with an 8 byte header holding the stream it came from and its length
+func readDockerLogLines(reader io.Reader, framed bool, emit func(dockerLogLine) error) error {
+	if !framed {
+		scanner := bufio.NewScanner(reader)
+		scanner.Buffer(make([]byte, 0, 4096), dockerContainerMaxLogLineSize)
+
+		for scanner.Scan() {
+			if err := emitDockerLogLine("stdout", scanner.Text(), emit); err != nil {
+				return err
+			}
+		}
+
+		return scanner.Err()
+	}
+
+	pending := map[string]*strings.Builder{"stdout": {}, "stderr": {}}
+	header := make([]byte, 8)
+	// the size comes from the stream and can be up to 4 GiB, so anything past
+	// the longest line we'd show of a single chunk gets skipped
+	payload := make([]byte, dockerContainerMaxLogLineSize)
+
+	for {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return err
+		}
+
+		stream := ternary(header[0] == 2, "stderr", "stdout")
+		size := binary.BigEndian.Uint32(header[4:])
+
+		read := min(size, uint32(len(payload)))
+		if _, err := io.ReadFull(reader, payload[:read]); err != nil {
+			return err
+		}
+
+		if size > read {
+			if _, err := io.CopyN(io.Discard, reader, int64(size-read)); err != nil {
+				return err
+			}
+		}
+
+		buffer := pending[stream]
+		for _, chunk := range strings.SplitAfter(string(payload[:read]), "\n") {
+			if !strings.HasSuffix(chunk, "\n") {
+				if buffer.Len() < dockerContainerMaxLogLineSize {
+					buffer.WriteString(chunk)
+				}
+				continue
+			}
+
+			buffer.WriteString(strings.TrimSuffix(chunk, "\n"))
+			if err := emitDockerLogLine(stream, buffer.String(), emit); err != nil {
+				return err
+			}
+			buffer.Reset()
+		}
+	}
+
+	for _, stream := range []string{"stdout", "stderr"} {
+		if pending[stream].Len() > 0 {
+			if err := emitDockerLogLine(stream, pending[stream].String(), emit); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func emitDockerLogLine(stream, text string, emit func(dockerLogLine) error) error {
+	text = stripANSI(strings.TrimSuffix(text, "\r"))
+	if len(text) > dockerContainerMaxLogLineSize {
+		text = text[:dockerContainerMaxLogLineSize]
+	}
+
+	return emit(dockerLogLine{Stream: stream, Text: text})
+}
+
+func (h *dockerHost) containerHasTTY(ctx context.Context, id string) (bool, error) {
+	request, err := http.NewRequestWithContext(ctx, "GET", h.baseURL+"/containers/"+url.PathEscape(id)+"/json", nil)
+	if err != nil {
+		return false, err
+	}
+
+	response, err := h.client.Do(request)
+	if err != nil {
+		return false, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return false, errors.New(dockerErrorMessage(response))
+	}
+
+	var container struct {
+		Config struct {
+			Tty bool `json:"Tty"`
+		} `json:"Config"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&container); err != nil {
+		return false, fmt.Errorf("decoding response: %w", err)
+	}
+
+	return container.Config.Tty, nil
+}
+
+func (h *dockerHost) containerLogs(ctx context.Context, id string, lines int, follow bool) (io.ReadCloser, bool, error) {
+	tty, err := h.containerHasTTY(ctx, id)
+	if err != nil {
+		return nil, false, err
+	}
+
+	query := url.Values{
+		"stdout": {"1"},
+		"stderr": {"1"},
+		"tail":   {strconv.Itoa(lines)},
+	}
+	if follow {
+		query.Set("follow", "1")
+	}
+
+	request, err := http.NewRequestWithContext(ctx, "GET", h.baseURL+"/containers/"+url.PathEscape(id)+"/logs?"+query.Encode(), nil)
+	if err != nil {
+		return nil, false, err
+	}
+
+	response, err := h.client.Do(request)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		defer response.Body.Close()
+		return nil, false, errors.New(dockerErrorMessage(response))
+	}
+
+	return response.Body, !tty, nil
+}
+
+// The page lock is only held while looking up the container, not for the
+// duration of the response, which can be streamed for as long as the popover is open
+func (widget *dockerContainersWidget) handleLogsRequest(pageLock sync.Locker, w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	pageLock.Lock()
+	showLogs, maxLines := widget.ShowLogs, widget.LogLines
+	var host *dockerHost
+	var id string
+	if container := widget.findContainer(query.Get("host"), query.Get("container")); container != nil {
+		host, id = container.host, container.ID
+	}
+	pageLock.Unlock()
+
+	if !showLogs {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("Logs are not enabled for this widget"))
+		return
+	}
+
+	if host == nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Container not found"))
+		return
+	}
+
+	lines := maxLines
+	if value, err := strconv.Atoi(query.Get("lines")); err == nil && value > 0 {
+		lines = min(value, maxLines)
+	}
+
+	follow := query.Get("follow") == "1"
+	ctx := r.Context()
+
+	if follow {
+		select {
+		case host.logFollowers <- struct{}{}:
+			defer func() { <-host.logFollowers }()
+		default:
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte("Too many logs are being followed at once"))
+			return
+		}
+	} else {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+	}
+
+	logs, framed, err := host.containerLogs(ctx, id, lines, follow)
+	if err != nil {
+		w.WriteHeader(http.StatusBadGateway)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	defer logs.Close()
+
+	if follow {
+		widget.streamLogs(w, logs, framed)
+		return
+	}
+
+	result := make([]dockerLogLine, 0, lines)
+	err = readDockerLogLines(logs, framed, func(line dockerLogLine) error {
+		result = append(result, line)
+		return nil
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusBadGateway)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"lines": result})
+}
+
+// Every line is sent as a separate server-sent event until either the
+// client disconnects or the container stops
+func (widget *dockerContainersWidget) streamLogs(w http.ResponseWriter, logs io.Reader, framed bool) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Streaming is not supported"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	readDockerLogLines(logs, framed, func(line dockerLogLine) error {
+		data, err := json.Marshal(line)
+		if err != nil {
+			return err
+		}
+
+		if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+			return err
+		}
+
+		flusher.Flush()
+		return nil
+	})
+
+	fmt.Fprint(w, "event: end\ndata: {}\n\n")
+	flusher.Flush()
+}
@@ -0,0 +1,484 @@
+package glance
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+type dockerImageUpdatesConfig struct {
+	Enabled       bool                         `yaml:"enabled"`
+	CacheDuration durationField                `yaml:"cache"`
+	Registries    []*dockerRegistryCredentials `yaml:"registries"`
+}
+
+type dockerRegistryCredentials struct {
+	Host     string `yaml:"host"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	// Talk to the registry over plain HTTP
+	Insecure bool `yaml:"insecure"`
+}
+
+const dockerHubRegistry = "docker.io"
+
+func (c *dockerImageUpdatesConfig) initialize() error {
+	if c.CacheDuration == 0 {
+		c.CacheDuration = durationField(6 * time.Hour)
+	} else if time.Duration(c.CacheDuration) < time.Minute {
+		return errors.New("image-updates: cache must be at least 1m")
+	}
+
+	for i, registry := range c.Registries {
+		if registry.Host == "" {
+			return fmt.Errorf("image-updates: registry %d: host is required", i+1)
+		}
+		registry.Host = normalizeDockerRegistryHost(registry.Host)
+	}
+
+	return nil
+}
+
+func (c *dockerImageUpdatesConfig) registry(host string) *dockerRegistryCredentials {
+	for _, registry := range c.Registries {
+		if registry.Host == host {
+			return registry
+		}
+	}
+
+	return &dockerRegistryCredentials{Host: host}
+}
+
+// Widgets can use different credentials for the same registry, which can see
+// different images, so lookups are cached separately for each of them
+func (r *dockerRegistryCredentials) cacheKey() string {
+	hash := sha256.Sum256([]byte(r.Host + "\x00" + r.Username + "\x00" + r.Password + "\x00" + fmt.Sprint(r.Insecure)))
+	return hex.EncodeToString(hash[:8])
+}
+
+func normalizeDockerRegistryHost(host string) string {
+	switch host {
+	case "index.docker.io", "registry-1.docker.io", "registry.hub.docker.com":
+		return dockerHubRegistry
+	}
+
+	return host
+}
+
+// A reference to an image by tag, such as ghcr.io/owner/app:1.2
+type dockerImageReference struct {
+	Registry   string
+	Repository string
+	Tag        string
+}
+
+// Images referenced by digest or by their ID can't have updates
+func parseDockerImageReference(image string) (dockerImageReference, bool) {
+	if image == "" || strings.HasPrefix(image, "sha256:") || strings.Contains(image, "@") {
+		return dockerImageReference{}, false
+	}
+
+	ref := dockerImageReference{Registry: dockerHubRegistry, Tag: "latest"}
+	name := image
+
+	// the first part is the registry only if it looks like a host
+	if first, rest, found := strings.Cut(image, "/"); found && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		ref.Registry = normalizeDockerRegistryHost(first)
+		name = rest
+	}
+
+	if i := strings.LastIndex(name, ":"); i != -1 {
+		ref.Tag = name[i+1:]
+		name = name[:i]
+	}
+
+	if ref.Registry == dockerHubRegistry && !strings.Contains(name, "/") {
+		name = "library/" + name
+	}
+
+	ref.Repository = name
+
+	return ref, true
+}
+
+func (r dockerImageReference) String() string {
+	return r.Registry + "/" + r.Repository + ":" + r.Tag
+}
+
+// Failures such as a registry being briefly down or rate limiting shouldn't
+// hide updates for as long as the digests are cached
+const dockerImageErrorCacheDuration = 5 * time.Minute
+
+type dockerImageCacheEntry[T any] struct {
+	value     T
+	err       error
+	fetchedAt time.Time
+	expiresAt time.Time
+}
+
+type dockerImageCache[T any] struct {
+	mu      sync.Mutex
+	entries map[string]dockerImageCacheEntry[T]
+}
+
+func newDockerImageCache[T any]() *dockerImageCache[T] {
+	return &dockerImageCache[T]{entries: make(map[string]dockerImageCacheEntry[T])}
+}
+
+// Shared by all widgets and kept across config reloads so that the same image
+// isn't looked up again for every widget it's used on
+var (
+	// keyed by the image reference, such as ghcr.io/owner/app:1.2, and the credentials used
+	dockerImageDigests = newDockerImageCache[string]()
+	// keyed by the host and the image ID, which identifies the contents of the image
+	dockerImageRepoDigests = newDockerImageCache[[]string]()
+)
+
+func (c *dockerImageCache[T]) get(key string, maxAge time.Duration) (dockerImageCacheEntry[T], bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if entry.err != nil {
+		maxAge = min(maxAge, dockerImageErrorCacheDuration)
+	}
+
+	now := time.Now()
+	if !ok || now.Sub(entry.fetchedAt) > maxAge || now.After(entry.expiresAt) {
+		return dockerImageCacheEntry[T]{}, false
+	}
+
+	return entry, true
+}
+
+// Expired entries are removed on every write, otherwise the cache would keep
+// growing with every image and tag that was ever used
+func (c *dockerImageCache[T]) set(key string, value T, err error, maxAge time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err != nil {
+		maxAge = min(maxAge, dockerImageErrorCacheDuration)
+	}
+
+	now := time.Now()
+	for existing, entry := range c.entries {
+		if now.After(entry.expiresAt) {
+			delete(c.entries, existing)
+		}
+	}
+
+	c.entries[key] = dockerImageCacheEntry[T]{value: value, err: err, fetchedAt: now, expiresAt: now.Add(maxAge)}
+}
+
+func (c *dockerImageCache[T]) getOrFetch(key string, maxAge time.Duration, fetch func() (T, error)) (T, error) {
+	if entry, ok := c.get(key, maxAge); ok {
+		return entry.value, entry.err
+	}
+
+	value, err := fetch()
+	c.set(key, value, err, maxAge)
+
+	return value, err
+}
+
+// Marks the containers whose image has a different digest in the registry
+// than the one they were pulled with
+func (c *dockerImageUpdatesConfig) checkContainers(containers dockerContainerList) {
+	type lookup struct {
+		ref        dockerImageReference
+		containers []*dockerContainer
+	}
+
+	lookups := make(map[string]*lookup)
+	var keys []string
+
+	for i := range containers {
+		container := &containers[i]
+
+		ref, ok := parseDockerImageReference(container.Image)
+		if !ok {
+			continue
+		}
+
+		key := ref.String()
+		if _, exists := lookups[key]; !exists {
+			lookups[key] = &lookup{ref: ref}
+			keys = append(keys, key)
+		}
+		lookups[key].containers = append(lookups[key].containers, container)
+	}
+
+	job := newJob(func(key string) (string, error) {
+		registry := c.registry(lookups[key].ref.Registry)
+
+		return dockerImageDigests.getOrFetch(key+"\x00"+registry.cacheKey(), time.Duration(c.CacheDuration), func() (string, error) {
+			return fetchDockerRegistryDigest(registry, lookups[key].ref)
+		})
+	}, keys)
+
+	digests, errs, err := workerPoolDo(job)
+	if err != nil {
+		return
+	}
+
+	for i, key := range keys {
+		if errs[i] != nil {
+			slog.Warn("Failed to check for image updates", "image", key, "error", errs[i])
+			continue
+		}
+
+		for _, container := range lookups[key].containers {
+			local, err := dockerImageRepoDigests.getOrFetch(container.host.baseURL+"\x00"+container.imageID, time.Duration(c.CacheDuration), func() ([]string, error) {
+				return container.host.imageRepoDigests(container.imageID)
+			})
+			if err != nil {
+				slog.Warn("Failed to inspect image", "image", key, "host", container.Host, "error", err)
+				continue
+			}
+
+			// images which were built locally have no digest to compare with
+			if len(local) == 0 {
+				continue
+			}
+
+			container.UpdateAvailable = !dockerRepoDigestsContain(local, digests[i])
+		}
+	}
+}
+
+// Repo digests are in the form of repository@sha256:...
+func dockerRepoDigestsContain(repoDigests []string, digest string) bool {
+	for _, repoDigest := range repoDigests {
+		if _, d, ok := strings.Cut(repoDigest, "@"); ok && d == digest {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (h *dockerHost) imageRepoDigests(imageID string) ([]string, error) {
+	if imageID == "" {
+		return nil, errors.New("missing image id")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, "GET", h.baseURL+"/images/"+url.PathEscape(imageID)+"/json", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := h.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, errors.New(dockerErrorMessage(response))
+	}
+
+	var image struct {
+		RepoDigests []string `json:"RepoDigests"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&image); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+
+	return image.RepoDigests, nil
+}
+
+var dockerManifestMediaTypes = strings.Join([]string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+}, ", ")
+
+var dockerRegistryHTTPClient = &http.Client{
+	Timeout:   15 * time.Second,
+	Transport: newUpstreamTransport(&userAgentTransport{underlying: http.DefaultTransport}),
+}
+
+// Returns the digest of the manifest which the tag currently points to,
+// going through the token authentication used by Docker Hub and GHCR when
+// the registry asks for it
+func fetchDockerRegistryDigest(registry *dockerRegistryCredentials, ref dockerImageReference) (string, error) {
+	host := ternary(ref.Registry == dockerHubRegistry, "registry-1.docker.io", ref.Registry)
+	manifestURL := ternary(registry.Insecure, "http://", "https://") + host + "/v2/" + ref.Repository + "/manifests/" + url.PathEscape(ref.Tag)
+
+	// HEAD requests don't count towards the rate limits of Docker Hub
+	response, err := sendDockerRegistryRequest("HEAD", manifestURL, registry, "")
+	if err != nil {
+		return "", err
+	}
+	response.Body.Close()
+
+	authorization := ""
+	if response.StatusCode == http.StatusUnauthorized {
+		authorization, err = dockerRegistryAuthorization(response.Header.Get("WWW-Authenticate"), registry)
+		if err != nil {
+			return "", err
+		}
+
+		response, err = sendDockerRegistryRequest("HEAD", manifestURL, registry, authorization)
+		if err != nil {
+			return "", err
+		}
+		response.Body.Close()
+	}
+
+	if response.StatusCode == http.StatusOK {
+		if digest := response.Header.Get("Docker-Content-Digest"); digest != "" {
+			return digest, nil
+		}
+	} else if response.StatusCode != http.StatusMethodNotAllowed {
+		return "", fmt.Errorf("unexpected status code %d from %s", response.StatusCode, host)
+	}
+
+	// not every registry includes the digest in the response to a HEAD request
+	response, err = sendDockerRegistryRequest("GET", manifestURL, registry, authorization)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status code %d from %s", response.StatusCode, host)
+	}
+
+	if digest := response.Header.Get("Docker-Content-Digest"); digest != "" {
+		return digest, nil
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, io.LimitReader(response.Body, 4<<20)); err != nil {
+		return "", err
+	}
+
+	return "sha256:" + hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func sendDockerRegistryRequest(method, url string, registry *dockerRegistryCredentials, authorization string) (*http.Response, error) {
+	request, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header.Set("Accept", dockerManifestMediaTypes)
+	if authorization != "" {
+		request.Header.Set("Authorization", authorization)
+	} else if registry.Username != "" {
+		request.SetBasicAuth(registry.Username, registry.Password)
+	}
+
+	return dockerRegistryHTTPClient.Do(request)
+}
+
+// Handles both bearer challenges, where a token has to be requested from
+// the realm, and basic ones
+func dockerRegistryAuthorization(challenge string, registry *dockerRegistryCredentials) (string, error) {
+	scheme, params, _ := strings.Cut(challenge, " ")
+
+	if strings.EqualFold(scheme, "basic") {
+		if registry.Username == "" {
+			return "", errors.New("registry requires credentials")
+		}
+
+		request, _ := http.NewRequest("GET", "/", nil)
+		request.SetBasicAuth(registry.Username, registry.Password)
+		return request.Header.Get("Authorization"), nil
+	}
+
+	if !strings.EqualFold(scheme, "bearer") {
+		return "", fmt.Errorf("unsupported authentication challenge %q", challenge)
+	}
+
+	values := parseDockerAuthChallengeParams(params)
+	realm := values["realm"]
+	if realm == "" {
+		return "", errors.New("authentication challenge has no realm")
+	}
+
+	query := url.Values{}
+	if values["service"] != "" {
+		query.Set("service", values["service"])
+	}
+	if values["scope"] != "" {
+		query.Set("scope", values["scope"])
+	}
+
+	request, err := http.NewRequest("GET", realm+"?"+query.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+
+	if registry.Username != "" {
+		request.SetBasicAuth(registry.Username, registry.Password)
+	}
+
+	response, err := dockerRegistryHTTPClient.Do(request)
+	if err != nil {
+		return "", fmt.Errorf("requesting token: %w", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("requesting token: unexpected status code %d", response.StatusCode)
+	}
+
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("decoding token: %w", err)
+	}
+
+	return "Bearer " + ternary(token.Token != "", token.Token, token.AccessToken), nil
+}
+
+// Parses key="value" pairs separated by commas
+func parseDockerAuthChallengeParams(params string) map[string]string {
+	values := make(map[string]string)
+
+	for params != "" {
+		key, rest, found := strings.Cut(params, "=")
+		if !found {
+			break
+		}
+
+		key = strings.ToLower(strings.TrimSpace(key))
+		var value string
+
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end == -1 {
+				break
+			}
+			value = rest[1 : end+1]
+			rest = rest[end+2:]
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+			rest = "," + rest
+		}
+
+		values[key] = value
+		params = strings.TrimPrefix(strings.TrimSpace(rest), ",")
+	}
+
+	return values
+}
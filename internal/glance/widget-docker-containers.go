@@ -2,18 +2,33 @@ package glance
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
+	"io"
+	"log/slog"
 	"net"
 	"net/http"
 	"net/url"
+	"os"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 )
 
-var dockerContainersWidgetTemplate = mustParseTemplate("docker-containers.html", "widget-base.html")
+var dockerContainersWidgetTemplate = mustParseTemplate("docker-containers.html", "widget-base.html", "state-icon.html")
+
+func init() {
+	registerWidget("docker-containers", func() widget { return &dockerContainersWidget{} },
+		newWidgetAction("POST", "action", (*dockerContainersWidget).handleActionRequest),
+		newStreamingWidgetAction("GET", "logs", (*dockerContainersWidget).handleLogsRequest),
+	)
+}
 
 type dockerContainersWidget struct {
 	widgetBase           `yaml:",inline"`
@@ -24,33 +39,124 @@ type dockerContainersWidget struct {
 	FormatContainerNames bool                         `yaml:"format-container-names"`
 	Containers           dockerContainerList          `yaml:"-"`
 	LabelOverrides       map[string]map[string]string `yaml:"containers"`
+	AllowActions         bool                         `yaml:"allow-actions"`
+	Hosts                []*dockerHost                `yaml:"hosts"`
+	UnavailableHosts     []string                     `yaml:"-"`
+	ShowStats            bool                         `yaml:"show-stats"`
+	SortBy               string                       `yaml:"sort-by"`
+	ImageUpdates         dockerImageUpdatesConfig     `yaml:"image-updates"`
+	ShowLogs             bool                         `yaml:"show-logs"`
+	LogLines             int                          `yaml:"log-lines"`
 }
 
 func (widget *dockerContainersWidget) initialize() error {
 	widget.withTitle("Docker Containers").withCacheDuration(1 * time.Minute)
 
-	if widget.SockPath == "" {
-		widget.SockPath = "/var/run/docker.sock"
+	if widget.SortBy == "" {
+		widget.SortBy = "state"
+	} else if widget.SortBy != "state" && widget.SortBy != "cpu" && widget.SortBy != "memory" {
+		return errors.New("sort-by must be one of state, cpu or memory")
+	}
+
+	if widget.SortBy != "state" && !widget.ShowStats {
+		return errors.New("show-stats has to be enabled to sort by " + widget.SortBy)
+	}
+
+	if len(widget.Hosts) > 0 && widget.SockPath != "" {
+		return errors.New("sock-path and hosts can not be used together")
+	}
+
+	if len(widget.Hosts) == 0 {
+		if widget.SockPath == "" {
+			widget.SockPath = "/var/run/docker.sock"
+		}
+
+		widget.Hosts = []*dockerHost{{URL: widget.SockPath}}
+	}
+
+	if widget.LogLines == 0 {
+		widget.LogLines = dockerContainerDefaultLogLines
+	} else if widget.LogLines < 0 || widget.LogLines > dockerContainerMaxLogLines {
+		return fmt.Errorf("log-lines must be between 1 and %d", dockerContainerMaxLogLines)
+	}
+
+	if widget.ImageUpdates.Enabled {
+		if err := widget.ImageUpdates.initialize(); err != nil {
+			return err
+		}
+	}
+
+	names := make(map[string]struct{}, len(widget.Hosts))
+	for i, host := range widget.Hosts {
+		if len(widget.Hosts) > 1 {
+			if host.Name == "" {
+				return fmt.Errorf("host %d: name is required when there are multiple hosts", i+1)
+			}
+
+			if _, exists := names[host.Name]; exists {
+				return fmt.Errorf("host %d: name %s is used more than once", i+1, host.Name)
+			}
+			names[host.Name] = struct{}{}
+		}
+
+		if err := host.initialize(); err != nil {
+			return fmt.Errorf("host %d: %v", i+1, err)
+		}
 	}
 
 	return nil
 }
 
+// Whether containers get labeled with the name of their host
+func (widget *dockerContainersWidget) MultipleHosts() bool {
+	return len(widget.Hosts) > 1
+}
+
 func (widget *dockerContainersWidget) update(ctx context.Context) {
-	containers, err := fetchDockerContainers(
-		widget.SockPath,
+	containers, unavailable, err := fetchDockerContainers(
+		widget.Hosts,
 		widget.HideByDefault,
 		widget.Category,
 		widget.RunningOnly,
 		widget.FormatContainerNames,
 		widget.LabelOverrides,
+		widget.AllowActions,
 	)
 	if !widget.canContinueUpdateAfterHandlingErr(err) {
 		return
 	}
 
-	containers.sortByStateIconThenTitle()
+	if widget.ShowStats {
+		fetchDockerContainersStats(containers)
+	}
+
+	if widget.ImageUpdates.Enabled {
+		widget.ImageUpdates.checkContainers(containers)
+	}
+
+	switch widget.SortBy {
+	case "cpu":
+		containers.sortByUsage(func(s *dockerContainerStats) float64 { return s.CPUPercent })
+	case "memory":
+		containers.sortByUsage(func(s *dockerContainerStats) float64 { return float64(s.MemoryUsed) })
+	default:
+		containers.sortByStateIconThenTitle()
+	}
+
 	widget.Containers = containers
+	widget.UnavailableHosts = unavailable
+
+	for i := range containers {
+		container := &containers[i]
+		failing := container.State == "exited" || container.State == "dead" || container.State == "restarting"
+
+		subject := container.Name
+		if widget.MultipleHosts() {
+			subject += "@" + container.Host
+		}
+
+		widget.emitAlertEvent(subject, failing, fmt.Sprintf("%s (%s): %s", subject, container.Image, container.StateText))
+	}
 }
 
 func (widget *dockerContainersWidget) Render() template.HTML {
@@ -61,12 +167,15 @@ const (
 	dockerContainerLabelHide        = "glance.hide"
 	dockerContainerLabelName        = "glance.name"
 	dockerContainerLabelURL         = "glance.url"
+	dockerContainerLabelCheckURL    = "glance.check-url"
 	dockerContainerLabelDescription = "glance.description"
 	dockerContainerLabelSameTab     = "glance.same-tab"
 	dockerContainerLabelIcon        = "glance.icon"
 	dockerContainerLabelID          = "glance.id"
 	dockerContainerLabelParent      = "glance.parent"
 	dockerContainerLabelCategory    = "glance.category"
+	dockerContainerLabelActions     = "glance.actions"
+	dockerContainerLabelUpdateHook  = "glance.update-hook"
 )
 
 const (
@@ -84,11 +193,13 @@ var dockerContainerStateIconPriorities = map[string]int{
 }
 
 type dockerContainerJsonResponse struct {
-	Names  []string              `json:"Names"`
-	Image  string                `json:"Image"`
-	State  string                `json:"State"`
-	Status string                `json:"Status"`
-	Labels dockerContainerLabels `json:"Labels"`
+	ID      string                `json:"Id"`
+	Names   []string              `json:"Names"`
+	Image   string                `json:"Image"`
+	ImageID string                `json:"ImageID"`
+	State   string                `json:"State"`
+	Status  string                `json:"Status"`
+	Labels  dockerContainerLabels `json:"Labels"`
 }
 
 type dockerContainerLabels map[string]string
@@ -111,6 +222,9 @@ func (l *dockerContainerLabels) getOrDefault(label, def string) string {
 }
 
 type dockerContainer struct {
+	ID          string
+	Host        string
+	host        *dockerHost
 	Name        string
 	URL         string
 	SameTab     bool
@@ -121,6 +235,15 @@ type dockerContainer struct {
 	Description string
 	Icon        customIconField
 	Children    dockerContainerList
+	Actions     []string
+	Stats       *dockerContainerStats
+	// Whether the registry has a newer image for the tag the container uses
+	UpdateAvailable bool
+	imageID         string
+	updateHook      string
+	// The outcome of the last action, shown until the next update
+	ActionResult string
+	ActionFailed bool
 }
 
 type dockerContainerList []dockerContainer
@@ -137,6 +260,19 @@ func (containers dockerContainerList) sortByStateIconThenTitle() {
 	})
 }
 
+// Containers without stats, such as stopped ones, go last and are sorted by state
+func (containers dockerContainerList) sortByUsage(usage func(*dockerContainerStats) float64) {
+	containers.sortByStateIconThenTitle()
+
+	sort.SliceStable(containers, func(a, b int) bool {
+		if containers[a].Stats == nil || containers[b].Stats == nil {
+			return containers[a].Stats != nil && containers[b].Stats == nil
+		}
+
+		return usage(containers[a].Stats) > usage(containers[b].Stats)
+	})
+}
+
 func dockerContainerStateToStateIcon(state string) string {
 	switch state {
 	case "running":
@@ -150,19 +286,60 @@ func dockerContainerStateToStateIcon(state string) string {
 	}
 }
 
+// Hosts which can't be reached are returned separately, the containers of
+// the rest are still shown
 func fetchDockerContainers(
-	socketPath string,
+	hosts []*dockerHost,
 	hideByDefault bool,
 	category string,
 	runningOnly bool,
 	formatNames bool,
 	labelOverrides map[string]map[string]string,
-) (dockerContainerList, error) {
-	containers, err := fetchDockerContainersFromSource(socketPath, category, runningOnly, labelOverrides)
+	allowActions bool,
+) (dockerContainerList, []string, error) {
+	job := newJob(func(host *dockerHost) ([]dockerContainerJsonResponse, error) {
+		return fetchDockerContainersFromSource(host, category, runningOnly, labelOverrides)
+	}, hosts)
+
+	results, errs, err := workerPoolDo(job)
 	if err != nil {
-		return nil, fmt.Errorf("fetching containers: %w", err)
+		return nil, nil, err
+	}
+
+	var dockerContainers dockerContainerList
+	var unavailable []string
+	var lastErr error
+
+	for i, host := range hosts {
+		if errs[i] != nil {
+			unavailable = append(unavailable, host.Name)
+			lastErr = errs[i]
+			slog.Error("Failed to fetch Docker containers", "host", host.Name, "error", errs[i])
+			continue
+		}
+
+		dockerContainers = append(dockerContainers, buildDockerContainers(host, results[i], hideByDefault, formatNames, allowActions)...)
+	}
+
+	if len(unavailable) == len(hosts) {
+		return nil, nil, fmt.Errorf("fetching containers: %w", lastErr)
+	}
+
+	if len(unavailable) > 0 {
+		return dockerContainers, unavailable, fmt.Errorf("%w: could not reach %d host(s)", errPartialContent, len(unavailable))
 	}
 
+	return dockerContainers, nil, nil
+}
+
+// Parents and children are matched within a single host
+func buildDockerContainers(
+	host *dockerHost,
+	containers []dockerContainerJsonResponse,
+	hideByDefault bool,
+	formatNames bool,
+	allowActions bool,
+) dockerContainerList {
 	containers, children := groupDockerContainerChildren(containers, hideByDefault)
 	dockerContainers := make(dockerContainerList, 0, len(containers))
 
@@ -170,11 +347,15 @@ func fetchDockerContainers(
 		container := &containers[i]
 
 		dc := dockerContainer{
+			ID:          container.ID,
+			Host:        host.Name,
+			host:        host,
 			Name:        deriveDockerContainerName(container, formatNames),
 			URL:         container.Labels.getOrDefault(dockerContainerLabelURL, ""),
 			Description: container.Labels.getOrDefault(dockerContainerLabelDescription, ""),
 			SameTab:     stringToBool(container.Labels.getOrDefault(dockerContainerLabelSameTab, "false")),
 			Image:       container.Image,
+			imageID:     container.ImageID,
 			State:       strings.ToLower(container.State),
 			StateText:   strings.ToLower(container.Status),
 			Icon:        newCustomIconField(container.Labels.getOrDefault(dockerContainerLabelIcon, "si:docker")),
@@ -193,6 +374,11 @@ func fetchDockerContainers(
 			}
 		}
 
+		if allowActions {
+			dc.updateHook = container.Labels.getOrDefault(dockerContainerLabelUpdateHook, "")
+			dc.Actions = dockerContainerAllowedActions(container, dc.State, dc.updateHook != "")
+		}
+
 		dc.Children.sortByStateIconThenTitle()
 
 		stateIconSupersededByChild := false
@@ -210,7 +396,7 @@ func fetchDockerContainers(
 		dockerContainers = append(dockerContainers, dc)
 	}
 
-	return dockerContainers, nil
+	return dockerContainers
 }
 
 func deriveDockerContainerName(container *dockerContainerJsonResponse, formatNames bool) string {
@@ -279,65 +465,21 @@ func isDockerContainerHidden(container *dockerContainerJsonResponse, hideByDefau
 }
 
 func fetchDockerContainersFromSource(
-	source string,
+	host *dockerHost,
 	category string,
 	runningOnly bool,
 	labelOverrides map[string]map[string]string,
 ) ([]dockerContainerJsonResponse, error) {
-	var hostname string
-
-	var client *http.Client
-	if strings.HasPrefix(source, "tcp://") || strings.HasPrefix(source, "http://") {
-		client = &http.Client{
-			Transport: &userAgentTransport{
-				underlying: &http.Transport{
-					MaxIdleConns:        maxIdleConns,
-					MaxIdleConnsPerHost: maxIdleConnsPerHost,
-					MaxConnsPerHost:     maxOpenConnsPerHost,
-					IdleConnTimeout:     idleConnTimeout,
-					DisableKeepAlives:   false,
-				},
-			},
-		}
-		parsed, err := url.Parse(source)
-		if err != nil {
-			return nil, fmt.Errorf("parsing URL: %w", err)
-		}
-
-		port := parsed.Port()
-		if port == "" {
-			port = "80"
-		}
-
-		hostname = parsed.Hostname() + ":" + port
-	} else {
-		hostname = "docker"
-		client = &http.Client{
-			Transport: &userAgentTransport{
-				underlying: &http.Transport{
-					MaxIdleConns:        maxIdleConns,
-					MaxIdleConnsPerHost: maxIdleConnsPerHost,
-					MaxConnsPerHost:     maxOpenConnsPerHost,
-					IdleConnTimeout:     idleConnTimeout,
-					DisableKeepAlives:   false,
-					DialContext: func(_ context.Context, _, _ string) (net.Conn, error) {
-						return net.Dial("unix", source)
-					},
-				},
-			},
-		}
-	}
-
 	fetchAll := ternary(runningOnly, "false", "true")
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	request, err := http.NewRequestWithContext(ctx, "GET", "http://"+hostname+"/containers/json?all="+fetchAll, nil)
+	request, err := http.NewRequestWithContext(ctx, "GET", host.baseURL+"/containers/json?all="+fetchAll, nil)
 	if err != nil {
 		return nil, fmt.Errorf("creating request: %w", err)
 	}
 
-	response, err := client.Do(request)
+	response, err := host.client.Do(request)
 	if err != nil {
 		return nil, fmt.Errorf("sending request to socket: %w", err)
 	}
@@ -354,6 +496,10 @@ func fetchDockerContainersFromSource(
 
 	for i := range containers {
 		container := &containers[i]
+		// the hook gets called by glance itself, so it's only taken from the config
+		// since anyone who can start a container could otherwise point it anywhere
+		delete(container.Labels, dockerContainerLabelUpdateHook)
+
 		name := strings.TrimLeft(itemAtIndexOrDefault(container.Names, 0, ""), "/")
 
 		if name == "" {
@@ -392,3 +538,431 @@ func fetchDockerContainersFromSource(
 
 	return containers, nil
 }
+
+// A Docker or Podman API, reached either through a socket or over the network
+type dockerHost struct {
+	Name string `yaml:"name"`
+	URL  string `yaml:"url"`
+	TLS  struct {
+		CA            string `yaml:"ca"`
+		Cert          string `yaml:"cert"`
+		Key           string `yaml:"key"`
+		AllowInsecure bool   `yaml:"allow-insecure"`
+	} `yaml:"tls"`
+
+	client  *http.Client
+	baseURL string
+	// followed logs keep a connection open for as long as they're shown, so
+	// their number is limited to leave connections for everything else
+	logFollowers chan struct{}
+}
+
+// The URL is either the path to a socket, optionally prefixed with unix://,
+// or a tcp://, http:// or https:// address. tcp:// addresses use TLS when a
+// certificate or CA is configured.
+func (h *dockerHost) initialize() error {
+	if h.URL == "" {
+		return errors.New("url is required")
+	}
+
+	transport := &http.Transport{
+		MaxIdleConns:        maxIdleConns,
+		MaxIdleConnsPerHost: maxIdleConnsPerHost,
+		MaxConnsPerHost:     maxOpenConnsPerHost,
+		IdleConnTimeout:     idleConnTimeout,
+		DisableKeepAlives:   false,
+	}
+
+	if socket, ok := strings.CutPrefix(h.URL, "unix://"); ok || !strings.Contains(h.URL, "://") {
+		socket = ternary(ok, socket, h.URL)
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", socket)
+		}
+		h.baseURL = "http://docker"
+	} else {
+		parsed, err := url.Parse(h.URL)
+		if err != nil {
+			return fmt.Errorf("parsing url: %v", err)
+		}
+
+		useTLS := parsed.Scheme == "https" || h.TLS.CA != "" || h.TLS.Cert != "" || h.TLS.AllowInsecure
+
+		switch parsed.Scheme {
+		case "tcp", "http", "https":
+		default:
+			return fmt.Errorf("unsupported url scheme %s", parsed.Scheme)
+		}
+
+		if parsed.Scheme == "http" && useTLS {
+			return errors.New("tls can not be used with an http:// url")
+		}
+
+		port := parsed.Port()
+		if port == "" {
+			port = ternary(useTLS, "443", "80")
+		}
+
+		h.baseURL = ternary(useTLS, "https://", "http://") + net.JoinHostPort(parsed.Hostname(), port) + strings.TrimRight(parsed.Path, "/")
+
+		if useTLS {
+			config, err := h.tlsConfig()
+			if err != nil {
+				return err
+			}
+			transport.TLSClientConfig = config
+		}
+	}
+
+	h.client = &http.Client{
+		Transport: newUpstreamTransport(&userAgentTransport{underlying: transport}),
+	}
+	h.logFollowers = make(chan struct{}, dockerMaxLogFollowersPerHost)
+
+	return nil
+}
+
+func (h *dockerHost) tlsConfig() (*tls.Config, error) {
+	config := &tls.Config{InsecureSkipVerify: h.TLS.AllowInsecure}
+
+	if h.TLS.CA != "" {
+		contents, err := os.ReadFile(h.TLS.CA)
+		if err != nil {
+			return nil, fmt.Errorf("reading tls ca: %v", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(contents) {
+			return nil, fmt.Errorf("no certificates found in %s", h.TLS.CA)
+		}
+		config.RootCAs = pool
+	}
+
+	if (h.TLS.Cert == "") != (h.TLS.Key == "") {
+		return nil, errors.New("tls cert and key have to be set together")
+	}
+
+	if h.TLS.Cert != "" {
+		certificate, err := tls.LoadX509KeyPair(h.TLS.Cert, h.TLS.Key)
+		if err != nil {
+			return nil, fmt.Errorf("loading tls cert: %v", err)
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	}
+
+	return config, nil
+}
+
+const (
+	dockerContainerActionStart   = "start"
+	dockerContainerActionStop    = "stop"
+	dockerContainerActionRestart = "restart"
+	dockerContainerActionUpdate  = "update"
+)
+
+var dockerContainerActions = []string{
+	dockerContainerActionStart,
+	dockerContainerActionStop,
+	dockerContainerActionRestart,
+	dockerContainerActionUpdate,
+}
+
+// The actions which can currently be performed on the container. The label
+// limits them to a comma separated list, "none" disables them entirely.
+func dockerContainerAllowedActions(container *dockerContainerJsonResponse, state string, hasUpdateHook bool) []string {
+	allowed := dockerContainerActions
+	if v := container.Labels.getOrDefault(dockerContainerLabelActions, ""); v != "" {
+		allowed = strings.Split(strings.ReplaceAll(strings.ToLower(v), " ", ""), ",")
+	}
+
+	actions := make([]string, 0, len(dockerContainerActions))
+	for _, action := range dockerContainerActions {
+		if !slices.Contains(allowed, action) {
+			continue
+		}
+
+		switch action {
+		case dockerContainerActionStart:
+			if state == "running" || state == "restarting" {
+				continue
+			}
+		case dockerContainerActionStop, dockerContainerActionRestart:
+			if state != "running" && state != "restarting" && state != "paused" {
+				continue
+			}
+		case dockerContainerActionUpdate:
+			if !hasUpdateHook {
+				continue
+			}
+		}
+
+		actions = append(actions, action)
+	}
+
+	return actions
+}
+
+func (widget *dockerContainersWidget) findContainer(host, id string) *dockerContainer {
+	for i := range widget.Containers {
+		if widget.Containers[i].Host == host && widget.Containers[i].ID == id {
+			return &widget.Containers[i]
+		}
+	}
+
+	return nil
+}
+
+func (widget *dockerContainersWidget) handleActionRequest(w http.ResponseWriter, r *http.Request) {
+	if !widget.AllowActions {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("Actions are not enabled for this widget"))
+		return
+	}
+
+	var request struct {
+		Action    string `json:"action"`
+		Host      string `json:"host"`
+		Container string `json:"container"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid request body"))
+		return
+	}
+
+	// only containers shown by the widget can be acted upon, which also
+	// keeps hidden containers and disallowed actions out of reach
+	container := widget.findContainer(request.Host, request.Container)
+	if container == nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Container not found"))
+		return
+	}
+
+	if !slices.Contains(container.Actions, request.Action) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Action is not allowed for this container"))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Minute)
+	defer cancel()
+
+	var err error
+	if request.Action == dockerContainerActionUpdate {
+		err = callDockerContainerUpdateHook(ctx, container.updateHook)
+	} else {
+		err = performDockerContainerAction(ctx, container.host, container.ID, request.Action)
+	}
+
+	updateWidget(r.Context(), widget)
+
+	if updated := widget.findContainer(request.Host, request.Container); updated != nil {
+		updated.ActionFailed = err != nil
+		if err != nil {
+			updated.ActionResult = "Błąd: " + err.Error()
+		} else {
+			updated.ActionResult = dockerContainerActionResultText(request.Action)
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(widget.Render()))
+}
+
+func dockerContainerActionResultText(action string) string {
+	switch action {
+	case dockerContainerActionStart:
+		return "Uruchomiono"
+	case dockerContainerActionStop:
+		return "Zatrzymano"
+	case dockerContainerActionRestart:
+		return "Zrestartowano"
+	}
+
+	return "Zlecono aktualizację"
+}
+
+func performDockerContainerAction(ctx context.Context, host *dockerHost, id, action string) error {
+	request, err := http.NewRequestWithContext(ctx, "POST", host.baseURL+"/containers/"+url.PathEscape(id)+"/"+action, nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+
+	response, err := host.client.Do(request)
+	if err != nil {
+		return fmt.Errorf("sending request to socket: %w", err)
+	}
+	defer response.Body.Close()
+
+	// 304 means that the container already was in the requested state
+	if response.StatusCode != http.StatusNoContent && response.StatusCode != http.StatusNotModified {
+		return errors.New(dockerErrorMessage(response))
+	}
+
+	return nil
+}
+
+// Hooks are an URL of something that pulls the image and recreates the
+// container, such as a Portainer or Komodo webhook
+func callDockerContainerUpdateHook(ctx context.Context, hook string) error {
+	request, err := http.NewRequestWithContext(ctx, "POST", hook, nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+
+	response, err := defaultHTTPClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("calling update hook: %w", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("update hook responded with %s", response.Status)
+	}
+
+	return nil
+}
+
+func dockerErrorMessage(response *http.Response) string {
+	var body struct {
+		Message string `json:"message"`
+	}
+
+	if json.NewDecoder(io.LimitReader(response.Body, 4096)).Decode(&body) == nil && body.Message != "" {
+		return body.Message
+	}
+
+	return response.Status
+}
+
+type dockerContainerStats struct {
+	CPUPercent  float64
+	MemoryUsed  uint64
+	MemoryLimit uint64
+}
+
+func (s *dockerContainerStats) CPUText() string {
+	return strconv.FormatFloat(s.CPUPercent, 'f', ternary(s.CPUPercent < 10, 1, 0), 64) + "%"
+}
+
+func (s *dockerContainerStats) MemoryText() string {
+	if s.MemoryLimit == 0 {
+		return formatBytes(int64(s.MemoryUsed))
+	}
+
+	return formatBytes(int64(s.MemoryUsed)) + " / " + formatBytes(int64(s.MemoryLimit))
+}
+
+func (s *dockerContainerStats) MemoryPercent() int {
+	if s.MemoryLimit == 0 {
+		return 0
+	}
+
+	return int(float64(s.MemoryUsed) / float64(s.MemoryLimit) * 100)
+}
+
+type dockerContainerStatsResponse struct {
+	CPUStats    dockerContainerCPUStats `json:"cpu_stats"`
+	PreCPUStats dockerContainerCPUStats `json:"precpu_stats"`
+	MemoryStats struct {
+		Usage uint64            `json:"usage"`
+		Limit uint64            `json:"limit"`
+		Stats map[string]uint64 `json:"stats"`
+	} `json:"memory_stats"`
+}
+
+type dockerContainerCPUStats struct {
+	CPUUsage struct {
+		TotalUsage  uint64   `json:"total_usage"`
+		PercpuUsage []uint64 `json:"percpu_usage"`
+	} `json:"cpu_usage"`
+	SystemCPUUsage uint64 `json:"system_cpu_usage"`
+	OnlineCPUs     uint32 `json:"online_cpus"`
+}
+
+// Calculated the same way as by the docker stats command
+func (r *dockerContainerStatsResponse) stats() *dockerContainerStats {
+	stats := &dockerContainerStats{MemoryLimit: r.MemoryStats.Limit}
+
+	cpuDelta := float64(r.CPUStats.CPUUsage.TotalUsage) - float64(r.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(r.CPUStats.SystemCPUUsage) - float64(r.PreCPUStats.SystemCPUUsage)
+	cpus := float64(r.CPUStats.OnlineCPUs)
+	if cpus == 0 {
+		cpus = float64(len(r.CPUStats.CPUUsage.PercpuUsage))
+	}
+
+	if cpuDelta > 0 && systemDelta > 0 {
+		stats.CPUPercent = cpuDelta / systemDelta * cpus * 100
+	}
+
+	// the page cache isn't counted as used, the key depends on the cgroup version
+	cache, ok := r.MemoryStats.Stats["total_inactive_file"]
+	if !ok {
+		cache = r.MemoryStats.Stats["inactive_file"]
+	}
+
+	if cache < r.MemoryStats.Usage {
+		stats.MemoryUsed = r.MemoryStats.Usage - cache
+	}
+
+	return stats
+}
+
+const dockerContainerStatsWorkers = 10
+
+// Fetching the stats of a container takes a while since Docker waits for a
+// second sample to calculate the CPU usage, so only running containers are
+// fetched and those that fail are left without stats
+func fetchDockerContainersStats(containers dockerContainerList) {
+	running := make([]*dockerContainer, 0, len(containers))
+	for i := range containers {
+		if containers[i].State == "running" {
+			running = append(running, &containers[i])
+		}
+	}
+
+	job := newJob(fetchDockerContainerStats, running).withWorkers(dockerContainerStatsWorkers)
+	stats, errs, err := workerPoolDo(job)
+	if err != nil {
+		return
+	}
+
+	for i := range running {
+		if errs[i] != nil {
+			slog.Warn("Failed to fetch Docker container stats", "container", running[i].Name, "host", running[i].Host, "error", errs[i])
+			continue
+		}
+
+		running[i].Stats = stats[i]
+	}
+}
+
+func fetchDockerContainerStats(container *dockerContainer) (*dockerContainerStats, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, "GET", container.host.baseURL+"/containers/"+url.PathEscape(container.ID)+"/stats?stream=false", nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+
+	response, err := container.host.client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("sending request: %w", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, errors.New(dockerErrorMessage(response))
+	}
+
+	var stats dockerContainerStatsResponse
+	if err := json.NewDecoder(response.Body).Decode(&stats); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+
+	return stats.stats(), nil
+}
@@ -0,0 +1,477 @@
+package glance
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDockerContainerActions(t *testing.T) {
+	var performed []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" && r.URL.Path == "/containers/json" {
+			w.Write([]byte(`[
+				{"Id": "aaa", "Names": ["/jellyfin"], "State": "running", "Status": "Up 2 hours"},
+				{"Id": "bbb", "Names": ["/db"], "State": "running", "Status": "Up 2 hours", "Labels": {"glance.actions": "none"}},
+				{"Id": "ccc", "Names": ["/secret"], "State": "exited", "Status": "Exited", "Labels": {"glance.hide": "true"}},
+				{"Id": "ddd", "Names": ["/sonarr"], "State": "running", "Status": "Up 2 hours", "Labels": {"glance.update-hook": "http://169.254.169.254/latest"}}
+			]`))
+			return
+		}
+
+		performed = append(performed, r.Method+" "+r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	widget := &dockerContainersWidget{
+		SockPath:       server.URL,
+		AllowActions:   true,
+		LabelOverrides: map[string]map[string]string{"jellyfin": {"update-hook": server.URL + "/hook"}},
+	}
+	widget.initialize()
+	widget.update(t.Context())
+
+	request := func(body string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		widget.handleActionRequest(recorder, httptest.NewRequest("POST", "/", strings.NewReader(body)))
+		return recorder
+	}
+
+	if response := request(`{"action": "start", "container": "aaa"}`); response.Code != http.StatusBadRequest {
+		t.Errorf("expected starting a running container to be rejected, got %d", response.Code)
+	}
+
+	if response := request(`{"action": "restart", "container": "bbb"}`); response.Code != http.StatusBadRequest {
+		t.Errorf("expected actions disabled through the label to be rejected, got %d", response.Code)
+	}
+
+	if response := request(`{"action": "start", "container": "ccc"}`); response.Code != http.StatusNotFound {
+		t.Errorf("expected hidden containers to be out of reach, got %d", response.Code)
+	}
+
+	response := request(`{"action": "restart", "container": "aaa"}`)
+	if response.Code != http.StatusOK || !strings.Contains(response.Body.String(), "Zrestartowano") {
+		t.Errorf("expected the result to be shown inline, got %d: %s", response.Code, response.Body.String())
+	}
+
+	if len(performed) != 1 || performed[0] != "POST /containers/aaa/restart" {
+		t.Errorf("unexpected requests to the Docker API: %v", performed)
+	}
+
+	if response := request(`{"action": "update", "container": "ddd"}`); response.Code != http.StatusBadRequest {
+		t.Errorf("expected update hooks from container labels to be ignored, got %d", response.Code)
+	}
+
+	if response := request(`{"action": "update", "container": "aaa"}`); response.Code != http.StatusOK || performed[len(performed)-1] != "POST /hook" {
+		t.Errorf("expected the update hook from the config to be called, got %d and %v", response.Code, performed)
+	}
+
+	widget.AllowActions = false
+	if response := request(`{"action": "restart", "container": "aaa"}`); response.Code != http.StatusForbidden {
+		t.Errorf("expected 403 when actions aren't enabled, got %d", response.Code)
+	}
+}
+
+func TestDockerContainersFromMultipleHosts(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"Id": "aaa", "Names": ["/jellyfin"], "State": "running", "Status": "Up 2 hours"}]`))
+	}))
+	defer server.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	certificate := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, certificate, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	secure := &dockerHost{Name: "nas", URL: "tcp://" + server.Listener.Addr().String()}
+	secure.TLS.CA = caFile
+
+	widget := &dockerContainersWidget{Hosts: []*dockerHost{
+		secure,
+		{Name: "offline", URL: filepath.Join(t.TempDir(), "missing.sock")},
+	}}
+
+	if err := widget.initialize(); err != nil {
+		t.Fatal(err)
+	}
+
+	widget.update(t.Context())
+
+	if len(widget.Containers) != 1 || widget.Containers[0].Host != "nas" {
+		t.Fatalf("expected the container of the reachable host, got %+v", widget.Containers)
+	}
+
+	if len(widget.UnavailableHosts) != 1 || widget.UnavailableHosts[0] != "offline" {
+		t.Errorf("expected the offline host to be reported, got %v", widget.UnavailableHosts)
+	}
+
+	if widget.Error != nil || widget.Notice == nil {
+		t.Errorf("expected a notice rather than an error, got %v / %v", widget.Error, widget.Notice)
+	}
+
+	widget = &dockerContainersWidget{Hosts: []*dockerHost{{URL: "tcp://localhost:2376"}, {URL: "unix:///run/podman.sock"}}}
+	if err := widget.initialize(); err == nil {
+		t.Error("expected hosts without names to be rejected")
+	}
+}
+
+func TestDockerContainerStats(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/containers/json":
+			w.Write([]byte(`[
+				{"Id": "aaa", "Names": ["/jellyfin"], "State": "running"},
+				{"Id": "bbb", "Names": ["/sonarr"], "State": "running"},
+				{"Id": "ccc", "Names": ["/backup"], "State": "exited"}
+			]`))
+		case "/containers/aaa/stats":
+			w.Write([]byte(`{
+				"cpu_stats": {"cpu_usage": {"total_usage": 300}, "system_cpu_usage": 2000, "online_cpus": 4},
+				"precpu_stats": {"cpu_usage": {"total_usage": 100}, "system_cpu_usage": 1000},
+				"memory_stats": {"usage": 3145728, "limit": 8388608, "stats": {"inactive_file": 1048576}}
+			}`))
+		case "/containers/bbb/stats":
+			w.Write([]byte(`{
+				"cpu_stats": {"cpu_usage": {"total_usage": 110}, "system_cpu_usage": 2000, "online_cpus": 4},
+				"precpu_stats": {"cpu_usage": {"total_usage": 100}, "system_cpu_usage": 1000},
+				"memory_stats": {"usage": 4194304, "limit": 8388608}
+			}`))
+		default:
+			t.Errorf("unexpected request to %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	widget := &dockerContainersWidget{SockPath: server.URL, ShowStats: true, SortBy: "cpu"}
+	if err := widget.initialize(); err != nil {
+		t.Fatal(err)
+	}
+	widget.update(t.Context())
+
+	names := make([]string, len(widget.Containers))
+	for i := range widget.Containers {
+		names[i] = widget.Containers[i].Name
+	}
+
+	if strings.Join(names, ",") != "jellyfin,sonarr,backup" {
+		t.Fatalf("expected the containers to be sorted by cpu usage, got %v", names)
+	}
+
+	stats := widget.Containers[0].Stats
+	if stats.CPUText() != "80%" || stats.MemoryText() != "2.0 MB / 8.0 MB" {
+		t.Errorf("unexpected stats %s, %s", stats.CPUText(), stats.MemoryText())
+	}
+
+	if widget.Containers[2].Stats != nil {
+		t.Error("expected no stats for a stopped container")
+	}
+
+	widget = &dockerContainersWidget{SortBy: "memory"}
+	if err := widget.initialize(); err == nil {
+		t.Error("expected sorting by usage without show-stats to be rejected")
+	}
+}
+
+func TestParseDockerImageReference(t *testing.T) {
+	tests := map[string]string{
+		"nginx":                      "docker.io/library/nginx:latest",
+		"linuxserver/sonarr:4":       "docker.io/linuxserver/sonarr:4",
+		"ghcr.io/owner/app:1.2":      "ghcr.io/owner/app:1.2",
+		"localhost:5000/app":         "localhost:5000/app:latest",
+		"registry.lan:5000/a/b:edge": "registry.lan:5000/a/b:edge",
+	}
+
+	for image, expected := range tests {
+		ref, ok := parseDockerImageReference(image)
+		if !ok || ref.String() != expected {
+			t.Errorf("%s: expected %s, got %s", image, expected, ref.String())
+		}
+	}
+
+	for _, image := range []string{"sha256:abc", "nginx@sha256:abc"} {
+		if _, ok := parseDockerImageReference(image); ok {
+			t.Errorf("%s: expected images pinned to a digest to be skipped", image)
+		}
+	}
+}
+
+func TestDockerImageUpdates(t *testing.T) {
+	const currentDigest = "sha256:1111"
+	var manifestRequests, inspectRequests int
+
+	previousDigests, previousRepoDigests := dockerImageDigests, dockerImageRepoDigests
+	dockerImageDigests, dockerImageRepoDigests = newDockerImageCache[string](), newDockerImageCache[[]string]()
+	t.Cleanup(func() { dockerImageDigests, dockerImageRepoDigests = previousDigests, previousRepoDigests })
+
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			if username, password, _ := r.BasicAuth(); username != "glance" || password != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if r.URL.Query().Get("scope") != "repository:app:pull" {
+				t.Errorf("unexpected scope %s", r.URL.Query().Get("scope"))
+			}
+			w.Write([]byte(`{"token": "abc"}`))
+		case "/v2/app/manifests/1.0":
+			manifestRequests++
+			if r.Header.Get("Authorization") != "Bearer abc" {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="http://`+r.Host+`/token",service="registry",scope="repository:app:pull"`)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Header().Set("Docker-Content-Digest", currentDigest)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer registry.Close()
+
+	image := strings.TrimPrefix(registry.URL, "http://") + "/app:1.0"
+
+	docker := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/images/") {
+			inspectRequests++
+		}
+
+		switch r.URL.Path {
+		case "/containers/json":
+			w.Write([]byte(`[
+				{"Id": "a", "Names": ["/current"], "State": "running", "Image": "` + image + `", "ImageID": "sha256:aaa"},
+				{"Id": "b", "Names": ["/outdated"], "State": "running", "Image": "` + image + `", "ImageID": "sha256:bbb"},
+				{"Id": "c", "Names": ["/local"], "State": "running", "Image": "` + image + `", "ImageID": "sha256:ccc"}
+			]`))
+		case "/images/sha256:aaa/json":
+			w.Write([]byte(`{"RepoDigests": ["` + image + `@` + currentDigest + `"]}`))
+		case "/images/sha256:bbb/json":
+			w.Write([]byte(`{"RepoDigests": ["` + image + `@sha256:0000"]}`))
+		case "/images/sha256:ccc/json":
+			w.Write([]byte(`{"RepoDigests": []}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer docker.Close()
+
+	// a failed lookup without credentials mustn't be used by widgets that have them
+	anonymous := &dockerContainersWidget{SockPath: docker.URL}
+	anonymous.ImageUpdates.Enabled = true
+	anonymous.ImageUpdates.Registries = []*dockerRegistryCredentials{{Host: strings.TrimPrefix(registry.URL, "http://"), Insecure: true}}
+	if err := anonymous.initialize(); err != nil {
+		t.Fatal(err)
+	}
+
+	anonymous.update(t.Context())
+	for i := range anonymous.Containers {
+		if anonymous.Containers[i].UpdateAvailable {
+			t.Errorf("expected no updates without access to the registry, got one for %s", anonymous.Containers[i].Name)
+		}
+	}
+
+	widget := &dockerContainersWidget{SockPath: docker.URL}
+	widget.ImageUpdates.Enabled = true
+	widget.ImageUpdates.Registries = []*dockerRegistryCredentials{{
+		Host:     strings.TrimPrefix(registry.URL, "http://"),
+		Username: "glance",
+		Password: "secret",
+		Insecure: true,
+	}}
+
+	if err := widget.initialize(); err != nil {
+		t.Fatal(err)
+	}
+
+	widget.update(t.Context())
+
+	updates := make(map[string]bool)
+	for i := range widget.Containers {
+		updates[widget.Containers[i].Name] = widget.Containers[i].UpdateAvailable
+	}
+
+	if updates["current"] || !updates["outdated"] || updates["local"] {
+		t.Errorf("unexpected update status %v", updates)
+	}
+
+	// a widget created by a config reload uses the same cache
+	reloaded := &dockerContainersWidget{SockPath: docker.URL, ImageUpdates: widget.ImageUpdates}
+	if err := reloaded.initialize(); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded.update(t.Context())
+	if manifestRequests != 3 || inspectRequests != 3 {
+		t.Errorf("expected the digests to be cached, got %d manifest and %d inspect requests", manifestRequests, inspectRequests)
+	}
+}
+
+func TestDockerImageCacheExpiresErrorsSooner(t *testing.T) {
+	cache := newDockerImageCache[string]()
+	fetchedAt := time.Now().Add(-time.Hour)
+	cache.entries["ok"] = dockerImageCacheEntry[string]{value: "sha256:1111", fetchedAt: fetchedAt, expiresAt: fetchedAt.Add(6 * time.Hour)}
+	cache.entries["failed"] = dockerImageCacheEntry[string]{err: errors.New("rate limited"), fetchedAt: fetchedAt, expiresAt: fetchedAt.Add(6 * time.Hour)}
+	cache.entries["stale"] = dockerImageCacheEntry[string]{value: "sha256:0000", fetchedAt: fetchedAt, expiresAt: fetchedAt.Add(time.Minute)}
+
+	if _, ok := cache.get("ok", 6*time.Hour); !ok {
+		t.Error("expected the digest to still be cached")
+	}
+
+	if _, ok := cache.get("failed", 6*time.Hour); ok {
+		t.Error("expected the error to have expired")
+	}
+
+	cache.set("new", "sha256:2222", nil, 6*time.Hour)
+	if _, exists := cache.entries["stale"]; exists {
+		t.Error("expected expired entries to be removed")
+	}
+
+	if _, exists := cache.entries["ok"]; !exists {
+		t.Error("expected entries which haven't expired to be kept")
+	}
+}
+
+func dockerLogFrame(stream byte, payload string) []byte {
+	frame := make([]byte, 8, 8+len(payload))
+	frame[0] = stream
+	binary.BigEndian.PutUint32(frame[4:], uint32(len(payload)))
+	return append(frame, payload...)
+}
+
+func TestReadDockerLogLines(t *testing.T) {
+	var stream bytes.Buffer
+	stream.Write(dockerLogFrame(1, "\x1b[32mstarting\x1b[0m\nlistening on "))
+	stream.Write(dockerLogFrame(2, "warning: no config\r\n"))
+	stream.Write(dockerLogFrame(1, ":8080\nunterminated"))
+
+	var lines []dockerLogLine
+	err := readDockerLogLines(&stream, true, func(line dockerLogLine) error {
+		lines = append(lines, line)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []dockerLogLine{
+		{Stream: "stdout", Text: "starting"},
+		{Stream: "stderr", Text: "warning: no config"},
+		{Stream: "stdout", Text: "listening on :8080"},
+		{Stream: "stdout", Text: "unterminated"},
+	}
+
+	if len(lines) != len(expected) {
+		t.Fatalf("expected %d lines, got %d: %v", len(expected), len(lines), lines)
+	}
+
+	for i := range expected {
+		if lines[i] != expected[i] {
+			t.Errorf("line %d: expected %v, got %v", i, expected[i], lines[i])
+		}
+	}
+
+	// chunks longer than a line can be are cut off rather than read whole
+	stream.Reset()
+	stream.Write(dockerLogFrame(1, strings.Repeat("x", dockerContainerMaxLogLineSize+100)))
+	stream.Write(dockerLogFrame(1, "\nnext\n"))
+
+	lines = nil
+	if err := readDockerLogLines(&stream, true, func(line dockerLogLine) error {
+		lines = append(lines, line)
+		return nil
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(lines) != 2 || len(lines[0].Text) != dockerContainerMaxLogLineSize || lines[1].Text != "next" {
+		t.Errorf("expected the long chunk to be cut off, got %d lines", len(lines))
+	}
+
+	// a header claiming a huge chunk mustn't get it allocated
+	stream.Reset()
+	stream.Write([]byte{1, 0, 0, 0, 0xff, 0xff, 0xff, 0xff})
+	stream.WriteString("short")
+	if err := readDockerLogLines(&stream, true, func(dockerLogLine) error { return nil }); err == nil {
+		t.Error("expected an error for a truncated chunk")
+	}
+}
+
+func TestDockerContainerLogs(t *testing.T) {
+	var tail string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/containers/json":
+			w.Write([]byte(`[{"Id": "aaa", "Names": ["/jellyfin"], "State": "running", "Status": "Up 2 hours"}]`))
+		case "/containers/aaa/json":
+			w.Write([]byte(`{"Config": {"Tty": false}}`))
+		case "/containers/aaa/logs":
+			tail = r.URL.Query().Get("tail")
+			w.Write(dockerLogFrame(1, "hello\n"))
+			w.Write(dockerLogFrame(2, "\x1b[31mfailed\x1b[0m\n"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	widget := &dockerContainersWidget{SockPath: server.URL, LogLines: 50}
+	widget.initialize()
+	widget.update(t.Context())
+
+	request := func(query string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		widget.handleLogsRequest(&sync.Mutex{}, recorder, httptest.NewRequest("GET", "/?"+query, nil))
+		return recorder
+	}
+
+	if response := request("container=aaa"); response.Code != http.StatusForbidden {
+		t.Errorf("expected 403 when logs aren't enabled, got %d", response.Code)
+	}
+
+	widget.ShowLogs = true
+
+	if response := request("container=zzz"); response.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown container, got %d", response.Code)
+	}
+
+	response := request("container=aaa&lines=500")
+	expected := `{"lines":[{"stream":"stdout","text":"hello"},{"stream":"stderr","text":"failed"}]}`
+	if response.Code != http.StatusOK || strings.TrimSpace(response.Body.String()) != expected {
+		t.Errorf("unexpected response %d: %s", response.Code, response.Body.String())
+	}
+
+	if tail != "50" {
+		t.Errorf("expected the requested lines to be capped at log-lines, got tail=%s", tail)
+	}
+
+	response = request("container=aaa&follow=1")
+	if !strings.Contains(response.Body.String(), `data: {"stream":"stderr","text":"failed"}`) || !strings.HasSuffix(response.Body.String(), "event: end\ndata: {}\n\n") {
+		t.Errorf("unexpected event stream: %s", response.Body.String())
+	}
+
+	host := widget.Containers[0].host
+	for range dockerMaxLogFollowersPerHost {
+		host.logFollowers <- struct{}{}
+	}
+
+	if response := request("container=aaa&follow=1"); response.Code != http.StatusTooManyRequests {
+		t.Errorf("expected 429 when too many logs are followed, got %d", response.Code)
+	}
+
+	if response := request("container=aaa"); response.Code != http.StatusOK {
+		t.Errorf("expected logs to still load without following, got %d", response.Code)
+	}
+}
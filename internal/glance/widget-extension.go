@@ -17,6 +17,10 @@ var extensionWidgetTemplate = mustParseTemplate("extension.html", "widget-base.h
 
 const extensionWidgetDefaultTitle = "Extension"
 
+func init() {
+	registerWidget("extension", func() widget { return &extensionWidget{} })
+}
+
 type extensionWidget struct {
 	widgetBase          `yaml:",inline"`
 	URL                 string               `yaml:"url"`
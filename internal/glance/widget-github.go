@@ -10,6 +10,10 @@ import (
 
 var githubWidgetTemplate = mustParseTemplate("github.html", "widget-base.html")
 
+func init() {
+	registerWidget("github", func() widget { return &githubWidget{} })
+}
+
 type githubWidget struct {
 	widgetBase    `yaml:",inline"`
 	Token         string       `yaml:"token"`
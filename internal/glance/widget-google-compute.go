@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"html/template"
+	"net/http"
 	"os"
 	"path"
 	"path/filepath"
@@ -19,6 +20,12 @@ import (
 
 var googleComputeWidgetTemplate = mustParseTemplate("google-compute.html", "widget-base.html")
 
+func init() {
+	registerWidget("google-compute", func() widget { return &googleComputeWidget{} },
+		newWidgetAction("POST", "action", (*googleComputeWidget).handleActionRequest),
+	)
+}
+
 type googleComputeWidget struct {
 	widgetBase        `yaml:",inline"`
 	ProjectID         string        `yaml:"project-id"`
@@ -271,3 +278,42 @@ func (widget *googleComputeWidget) isZoneAllowed(zone string, allowedZones map[s
 	_, ok := allowedZones[strings.ToLower(zone)]
 	return ok
 }
+
+func (widget *googleComputeWidget) handleActionRequest(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Action   string `json:"action"`
+		Instance string `json:"instance"`
+		Zone     string `json:"zone"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid request body"))
+		return
+	}
+
+	if request.Action != "start" && request.Action != "stop" && request.Action != "restart" {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid action"))
+		return
+	}
+
+	if request.Instance == "" || request.Zone == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Instance and zone are required"))
+		return
+	}
+
+	if err := widget.performInstanceAction(r.Context(), request.Action, request.Zone, request.Instance); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(fmt.Sprintf("Failed to perform action: %v", err)))
+		return
+	}
+
+	updateWidget(r.Context(), widget)
+	html := widget.Render()
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(html))
+}
@@ -9,6 +9,10 @@ import (
 
 var groupWidgetTemplate = mustParseTemplate("group.html", "widget-base.html")
 
+func init() {
+	registerWidget("group", func() widget { return &groupWidget{} })
+}
+
 type groupWidget struct {
 	widgetBase          `yaml:",inline"`
 	containerWidgetBase `yaml:",inline"`
@@ -44,6 +48,10 @@ func (widget *groupWidget) setProviders(providers *widgetProviders) {
 }
 
 func (widget *groupWidget) requiresUpdate(now *time.Time) bool {
+	if !widget.isVisibleAt(*now) {
+		return false
+	}
+
 	return widget.containerWidgetBase._requiresUpdate(now)
 }
 
@@ -11,6 +11,10 @@ import (
 	"time"
 )
 
+func init() {
+	registerWidget("hacker-news", func() widget { return &hackerNewsWidget{} })
+}
+
 type hackerNewsWidget struct {
 	widgetBase          `yaml:",inline"`
 	Posts               forumPostList `yaml:"-"`
@@ -4,6 +4,10 @@ import (
 	"html/template"
 )
 
+func init() {
+	registerWidget("html", func() widget { return &htmlWidget{} })
+}
+
 type htmlWidget struct {
 	widgetBase `yaml:",inline"`
 	Source     template.HTML `yaml:"source"`
@@ -9,6 +9,10 @@ import (
 
 var iframeWidgetTemplate = mustParseTemplate("iframe.html", "widget-base.html")
 
+func init() {
+	registerWidget("iframe", func() widget { return &iframeWidget{} })
+}
+
 type iframeWidget struct {
 	widgetBase `yaml:",inline"`
 	cachedHTML template.HTML `yaml:"-"`
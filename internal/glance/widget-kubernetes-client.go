@@ -0,0 +1,302 @@
+package glance
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Where the token and CA of the service account are mounted inside of pods,
+// a variable so that tests can point it elsewhere
+var kubernetesServiceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// A minimal client for the read-only parts of the Kubernetes API that the
+// widget needs, configured either from a kubeconfig or from the service
+// account of the pod Glance runs in
+type kubernetesClient struct {
+	server   string
+	client   *http.Client
+	token    string
+	username string
+	password string
+	// read on every request since the tokens of service accounts get rotated
+	tokenFile string
+}
+
+type kubeconfigFile struct {
+	CurrentContext string `yaml:"current-context"`
+	Clusters       []struct {
+		Name    string `yaml:"name"`
+		Cluster struct {
+			Server                   string `yaml:"server"`
+			CertificateAuthority     string `yaml:"certificate-authority"`
+			CertificateAuthorityData string `yaml:"certificate-authority-data"`
+			InsecureSkipTLSVerify    bool   `yaml:"insecure-skip-tls-verify"`
+			TLSServerName            string `yaml:"tls-server-name"`
+		} `yaml:"cluster"`
+	} `yaml:"clusters"`
+	Users []struct {
+		Name string `yaml:"name"`
+		User struct {
+			Token                 string    `yaml:"token"`
+			TokenFile             string    `yaml:"tokenFile"`
+			ClientCertificate     string    `yaml:"client-certificate"`
+			ClientCertificateData string    `yaml:"client-certificate-data"`
+			ClientKey             string    `yaml:"client-key"`
+			ClientKeyData         string    `yaml:"client-key-data"`
+			Username              string    `yaml:"username"`
+			Password              string    `yaml:"password"`
+			Exec                  yaml.Node `yaml:"exec"`
+			AuthProvider          yaml.Node `yaml:"auth-provider"`
+		} `yaml:"user"`
+	} `yaml:"users"`
+	Contexts []struct {
+		Name    string `yaml:"name"`
+		Context struct {
+			Cluster string `yaml:"cluster"`
+			User    string `yaml:"user"`
+		} `yaml:"context"`
+	} `yaml:"contexts"`
+}
+
+func newKubernetesClientFromKubeconfig(path, contextName string) (*kubernetesClient, error) {
+	if strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(home, path[2:])
+	}
+
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading kubeconfig: %v", err)
+	}
+
+	var config kubeconfigFile
+	if err := yaml.Unmarshal(contents, &config); err != nil {
+		return nil, fmt.Errorf("parsing kubeconfig: %v", err)
+	}
+
+	if contextName == "" {
+		contextName = config.CurrentContext
+	}
+
+	if contextName == "" {
+		return nil, errors.New("kubeconfig has no current-context, specify one through context")
+	}
+
+	var clusterName, userName string
+	found := false
+	for i := range config.Contexts {
+		if config.Contexts[i].Name == contextName {
+			clusterName = config.Contexts[i].Context.Cluster
+			userName = config.Contexts[i].Context.User
+			found = true
+			break
+		}
+	}
+
+	if !found {
+		return nil, fmt.Errorf("context %s not found in kubeconfig", contextName)
+	}
+
+	// paths of files referenced from a kubeconfig are relative to it
+	resolve := func(file string) string {
+		if file == "" || filepath.IsAbs(file) {
+			return file
+		}
+		return filepath.Join(filepath.Dir(path), file)
+	}
+
+	tlsConfig := &tls.Config{}
+	client := &kubernetesClient{}
+
+	found = false
+	for i := range config.Clusters {
+		if config.Clusters[i].Name != clusterName {
+			continue
+		}
+
+		cluster := &config.Clusters[i].Cluster
+		client.server = strings.TrimRight(cluster.Server, "/")
+		tlsConfig.InsecureSkipVerify = cluster.InsecureSkipTLSVerify
+		tlsConfig.ServerName = cluster.TLSServerName
+
+		ca, err := readKubeconfigData(cluster.CertificateAuthorityData, resolve(cluster.CertificateAuthority))
+		if err != nil {
+			return nil, fmt.Errorf("reading certificate authority: %v", err)
+		}
+
+		if ca != nil {
+			tlsConfig.RootCAs = x509.NewCertPool()
+			if !tlsConfig.RootCAs.AppendCertsFromPEM(ca) {
+				return nil, errors.New("certificate authority does not contain any valid certificates")
+			}
+		}
+
+		found = true
+		break
+	}
+
+	if !found {
+		return nil, fmt.Errorf("cluster %s not found in kubeconfig", clusterName)
+	}
+
+	if client.server == "" {
+		return nil, fmt.Errorf("cluster %s has no server", clusterName)
+	}
+
+	for i := range config.Users {
+		if config.Users[i].Name != userName {
+			continue
+		}
+
+		user := &config.Users[i].User
+		if !user.Exec.IsZero() || !user.AuthProvider.IsZero() {
+			return nil, fmt.Errorf("user %s uses a credential plugin, which is not supported, use a token or a client certificate instead", userName)
+		}
+
+		client.token = user.Token
+		client.tokenFile = resolve(user.TokenFile)
+		client.username = user.Username
+		client.password = user.Password
+
+		cert, err := readKubeconfigData(user.ClientCertificateData, resolve(user.ClientCertificate))
+		if err != nil {
+			return nil, fmt.Errorf("reading client certificate: %v", err)
+		}
+
+		key, err := readKubeconfigData(user.ClientKeyData, resolve(user.ClientKey))
+		if err != nil {
+			return nil, fmt.Errorf("reading client key: %v", err)
+		}
+
+		if cert != nil || key != nil {
+			pair, err := tls.X509KeyPair(cert, key)
+			if err != nil {
+				return nil, fmt.Errorf("loading client certificate: %v", err)
+			}
+			tlsConfig.Certificates = []tls.Certificate{pair}
+		}
+
+		break
+	}
+
+	client.client = newKubernetesHTTPClient(tlsConfig)
+	return client, nil
+}
+
+func readKubeconfigData(data, file string) ([]byte, error) {
+	if data != "" {
+		return base64.StdEncoding.DecodeString(data)
+	}
+
+	if file != "" {
+		return os.ReadFile(file)
+	}
+
+	return nil, nil
+}
+
+func isRunningInKubernetes() bool {
+	_, err := os.Stat(filepath.Join(kubernetesServiceAccountDir, "token"))
+	return os.Getenv("KUBERNETES_SERVICE_HOST") != "" && err == nil
+}
+
+func newKubernetesClientInCluster() (*kubernetesClient, error) {
+	ca, err := os.ReadFile(filepath.Join(kubernetesServiceAccountDir, "ca.crt"))
+	if err != nil {
+		return nil, fmt.Errorf("reading service account CA: %v", err)
+	}
+
+	tlsConfig := &tls.Config{RootCAs: x509.NewCertPool()}
+	if !tlsConfig.RootCAs.AppendCertsFromPEM(ca) {
+		return nil, errors.New("service account CA does not contain any valid certificates")
+	}
+
+	return &kubernetesClient{
+		server:    "https://" + net.JoinHostPort(os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")),
+		client:    newKubernetesHTTPClient(tlsConfig),
+		tokenFile: filepath.Join(kubernetesServiceAccountDir, "token"),
+	}, nil
+}
+
+func newKubernetesHTTPClient(tlsConfig *tls.Config) *http.Client {
+	transport := &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		TLSClientConfig:     tlsConfig,
+		MaxIdleConns:        maxIdleConns,
+		MaxIdleConnsPerHost: maxIdleConnsPerHost,
+		MaxConnsPerHost:     maxOpenConnsPerHost,
+		IdleConnTimeout:     idleConnTimeout,
+	}
+
+	return &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: newUpstreamTransport(&userAgentTransport{underlying: transport}),
+	}
+}
+
+func (c *kubernetesClient) list(ctx context.Context, path string, query url.Values, out any) error {
+	request, err := http.NewRequestWithContext(ctx, "GET", c.server+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Accept", "application/json")
+
+	token := c.token
+	if c.tokenFile != "" {
+		contents, err := os.ReadFile(c.tokenFile)
+		if err != nil {
+			return fmt.Errorf("reading token: %v", err)
+		}
+		token = strings.TrimSpace(string(contents))
+	}
+
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	} else if c.username != "" {
+		request.SetBasicAuth(c.username, c.password)
+	}
+
+	response, err := c.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		// errors are returned as a Status object with a readable message
+		var status struct {
+			Message string `json:"message"`
+		}
+		body, _ := io.ReadAll(io.LimitReader(response.Body, 64<<10))
+		if json.Unmarshal(body, &status) == nil && status.Message != "" {
+			return fmt.Errorf("%s: %s", response.Status, status.Message)
+		}
+
+		return fmt.Errorf("unexpected status code %d from %s", response.StatusCode, path)
+	}
+
+	if err := json.NewDecoder(response.Body).Decode(out); err != nil {
+		return fmt.Errorf("decoding response: %v", err)
+	}
+
+	return nil
+}
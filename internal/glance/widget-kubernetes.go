@@ -0,0 +1,455 @@
+package glance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+var kubernetesWidgetTemplate = mustParseTemplate("kubernetes.html", "widget-base.html", "state-icon.html")
+
+func init() {
+	registerWidget("kubernetes", func() widget { return &kubernetesWidget{} })
+}
+
+const (
+	kubernetesKindDeployments  = "deployments"
+	kubernetesKindStatefulSets = "statefulsets"
+	kubernetesKindPods         = "pods"
+)
+
+var kubernetesKinds = []string{kubernetesKindDeployments, kubernetesKindStatefulSets, kubernetesKindPods}
+
+type kubernetesWidget struct {
+	widgetBase    `yaml:",inline"`
+	Kubeconfig    string                 `yaml:"kubeconfig"`
+	Context       string                 `yaml:"context"`
+	Namespaces    []string               `yaml:"namespaces"`
+	Kinds         []string               `yaml:"kinds"`
+	LabelSelector string                 `yaml:"label-selector"`
+	HideByDefault bool                   `yaml:"hide-by-default"`
+	Workloads     kubernetesWorkloadList `yaml:"-"`
+
+	client *kubernetesClient
+}
+
+func (widget *kubernetesWidget) initialize() error {
+	widget.withTitle("Kubernetes").withCacheDuration(1 * time.Minute)
+
+	if len(widget.Kinds) == 0 {
+		widget.Kinds = []string{kubernetesKindDeployments, kubernetesKindStatefulSets}
+	}
+
+	for _, kind := range widget.Kinds {
+		if !slices.Contains(kubernetesKinds, kind) {
+			return fmt.Errorf("kinds must only contain %s", strings.Join(kubernetesKinds, ", "))
+		}
+	}
+
+	var err error
+	switch {
+	case widget.Kubeconfig != "":
+		widget.client, err = newKubernetesClientFromKubeconfig(widget.Kubeconfig, widget.Context)
+	case isRunningInKubernetes():
+		widget.client, err = newKubernetesClientInCluster()
+	default:
+		return errors.New("kubeconfig is required when Glance is not running inside of a cluster")
+	}
+
+	return err
+}
+
+// Whether workloads get labeled with their namespace
+func (widget *kubernetesWidget) MultipleNamespaces() bool {
+	return len(widget.Namespaces) != 1
+}
+
+func (widget *kubernetesWidget) update(ctx context.Context) {
+	workloads, err := fetchKubernetesWorkloads(widget.client, widget.Namespaces, widget.Kinds, widget.LabelSelector, widget.HideByDefault)
+	if !widget.canContinueUpdateAfterHandlingErr(err) {
+		return
+	}
+
+	workloads.sortByStateIconThenTitle()
+	widget.Workloads = workloads
+
+	for i := range workloads {
+		workload := &workloads[i]
+		subject := workload.Namespace + "/" + workload.Name
+		widget.emitAlertEvent(subject, workload.StateIcon == dockerContainerStateIconWarn, fmt.Sprintf("%s %s: %s", workload.Kind, subject, workload.StateText))
+	}
+}
+
+func (widget *kubernetesWidget) Render() template.HTML {
+	return widget.renderTemplate(widget, kubernetesWidgetTemplate)
+}
+
+const (
+	kubernetesAnnotationHide        = "glance.hide"
+	kubernetesAnnotationName        = "glance.name"
+	kubernetesAnnotationURL         = "glance.url"
+	kubernetesAnnotationDescription = "glance.description"
+	kubernetesAnnotationSameTab     = "glance.same-tab"
+	kubernetesAnnotationIcon        = "glance.icon"
+)
+
+type kubernetesWorkload struct {
+	Kind        string
+	Namespace   string
+	Name        string
+	Title       string
+	URL         string
+	SameTab     bool
+	Description string
+	Icon        customIconField
+	Ready       int
+	Desired     int
+	Restarts    int
+	Phase       string
+	StateText   string
+	StateIcon   string
+}
+
+func (w *kubernetesWorkload) RestartsText() string {
+	return formatPolishPlural(w.Restarts, "restart", "restarty", "restartów")
+}
+
+type kubernetesWorkloadList []kubernetesWorkload
+
+func (workloads kubernetesWorkloadList) sortByStateIconThenTitle() {
+	p := &dockerContainerStateIconPriorities
+
+	sort.SliceStable(workloads, func(a, b int) bool {
+		if workloads[a].StateIcon != workloads[b].StateIcon {
+			return (*p)[workloads[a].StateIcon] < (*p)[workloads[b].StateIcon]
+		}
+
+		return strings.ToLower(workloads[a].Title) < strings.ToLower(workloads[b].Title)
+	})
+}
+
+type kubernetesObjectMeta struct {
+	Name            string            `json:"name"`
+	Namespace       string            `json:"namespace"`
+	Labels          map[string]string `json:"labels"`
+	Annotations     map[string]string `json:"annotations"`
+	OwnerReferences []struct {
+		Kind string `json:"kind"`
+		Name string `json:"name"`
+	} `json:"ownerReferences"`
+}
+
+// Used for both deployments and statefulsets, which have the same fields
+// for what's shown
+type kubernetesReplicatedResponse struct {
+	Items []struct {
+		Metadata kubernetesObjectMeta `json:"metadata"`
+		Spec     struct {
+			Replicas *int `json:"replicas"`
+		} `json:"spec"`
+		Status struct {
+			ReadyReplicas int `json:"readyReplicas"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+type kubernetesPodsResponse struct {
+	Items []kubernetesPod `json:"items"`
+}
+
+type kubernetesPod struct {
+	Metadata kubernetesObjectMeta `json:"metadata"`
+	Status   struct {
+		Phase             string `json:"phase"`
+		ContainerStatuses []struct {
+			Ready        bool `json:"ready"`
+			RestartCount int  `json:"restartCount"`
+			State        struct {
+				Waiting *struct {
+					Reason string `json:"reason"`
+				} `json:"waiting"`
+			} `json:"state"`
+		} `json:"containerStatuses"`
+	} `json:"status"`
+}
+
+func (pod *kubernetesPod) restarts() int {
+	restarts := 0
+	for _, status := range pod.Status.ContainerStatuses {
+		restarts += status.RestartCount
+	}
+
+	return restarts
+}
+
+// The pods of a deployment are owned by one of its replica sets, which are
+// named after the deployment followed by the hash of the pod template
+func (pod *kubernetesPod) isOwnedBy(kind, name string) bool {
+	for _, owner := range pod.Metadata.OwnerReferences {
+		switch {
+		case kind == kubernetesKindStatefulSets && owner.Kind == "StatefulSet" && owner.Name == name:
+			return true
+		case kind == kubernetesKindDeployments && owner.Kind == "ReplicaSet" && owner.Name == name+"-"+pod.Metadata.Labels["pod-template-hash"]:
+			return true
+		}
+	}
+
+	return false
+}
+
+func kubernetesListPath(namespace, kind string) string {
+	group := ternary(kind == kubernetesKindPods, "/api/v1", "/apis/apps/v1")
+	if namespace == "" {
+		return group + "/" + kind
+	}
+
+	return group + "/namespaces/" + url.PathEscape(namespace) + "/" + kind
+}
+
+// Namespaces which can't be listed, such as ones the account has no access
+// to, are skipped and the workloads of the rest are still shown
+func fetchKubernetesWorkloads(
+	client *kubernetesClient,
+	namespaces []string,
+	kinds []string,
+	labelSelector string,
+	hideByDefault bool,
+) (kubernetesWorkloadList, error) {
+	if len(namespaces) == 0 {
+		// an empty namespace lists the resources across all of them
+		namespaces = []string{""}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	workloads := make(kubernetesWorkloadList, 0)
+	var lastErr error
+	failed := 0
+
+	for _, namespace := range namespaces {
+		fetched, err := fetchKubernetesNamespaceWorkloads(ctx, client, namespace, kinds, labelSelector)
+		if err != nil {
+			failed++
+			lastErr = err
+			continue
+		}
+
+		for i := range fetched {
+			if !isKubernetesWorkloadHidden(&fetched[i], hideByDefault) {
+				workloads = append(workloads, fetched[i].workload)
+			}
+		}
+	}
+
+	if failed == len(namespaces) {
+		return nil, lastErr
+	}
+
+	if failed > 0 {
+		return workloads, fmt.Errorf("%w: could not list workloads of %d namespace(s): %v", errPartialContent, failed, lastErr)
+	}
+
+	return workloads, nil
+}
+
+type kubernetesFetchedWorkload struct {
+	workload    kubernetesWorkload
+	annotations map[string]string
+}
+
+func isKubernetesWorkloadHidden(fetched *kubernetesFetchedWorkload, hideByDefault bool) bool {
+	if v := fetched.annotations[kubernetesAnnotationHide]; v != "" {
+		return stringToBool(v)
+	}
+
+	return hideByDefault
+}
+
+func fetchKubernetesNamespaceWorkloads(
+	ctx context.Context,
+	client *kubernetesClient,
+	namespace string,
+	kinds []string,
+	labelSelector string,
+) ([]kubernetesFetchedWorkload, error) {
+	query := url.Values{}
+	if labelSelector != "" {
+		query.Set("labelSelector", labelSelector)
+	}
+
+	// the restarts of deployments and statefulsets are counted from their
+	// pods, which don't necessarily match the label selector
+	var pods kubernetesPodsResponse
+	if slices.Contains(kinds, kubernetesKindDeployments) || slices.Contains(kinds, kubernetesKindStatefulSets) {
+		if err := client.list(ctx, kubernetesListPath(namespace, kubernetesKindPods), url.Values{}, &pods); err != nil {
+			return nil, err
+		}
+	}
+
+	var fetched []kubernetesFetchedWorkload
+
+	for _, kind := range kinds {
+		if kind == kubernetesKindPods {
+			var selected kubernetesPodsResponse
+			if err := client.list(ctx, kubernetesListPath(namespace, kind), query, &selected); err != nil {
+				return nil, err
+			}
+
+			for i := range selected.Items {
+				fetched = append(fetched, newKubernetesPodWorkload(&selected.Items[i]))
+			}
+			continue
+		}
+
+		var response kubernetesReplicatedResponse
+		if err := client.list(ctx, kubernetesListPath(namespace, kind), query, &response); err != nil {
+			return nil, err
+		}
+
+		for i := range response.Items {
+			item := &response.Items[i]
+
+			desired := 1
+			if item.Spec.Replicas != nil {
+				desired = *item.Spec.Replicas
+			}
+
+			restarts := 0
+			for p := range pods.Items {
+				pod := &pods.Items[p]
+				if pod.Metadata.Namespace == item.Metadata.Namespace && pod.isOwnedBy(kind, item.Metadata.Name) {
+					restarts += pod.restarts()
+				}
+			}
+
+			workload := kubernetesFetchedWorkload{
+				workload: kubernetesWorkload{
+					Kind:      ternary(kind == kubernetesKindDeployments, "Deployment", "StatefulSet"),
+					Ready:     item.Status.ReadyReplicas,
+					Desired:   desired,
+					Restarts:  restarts,
+					Phase:     kubernetesReplicasPhase(item.Status.ReadyReplicas, desired),
+					StateIcon: kubernetesReplicasStateIcon(item.Status.ReadyReplicas, desired),
+				},
+				annotations: item.Metadata.Annotations,
+			}
+			workload.workload.StateText = kubernetesPhaseText(workload.workload.Phase)
+			applyKubernetesMetadata(&workload, &item.Metadata)
+
+			fetched = append(fetched, workload)
+		}
+	}
+
+	return fetched, nil
+}
+
+func newKubernetesPodWorkload(pod *kubernetesPod) kubernetesFetchedWorkload {
+	ready := 0
+	phase := pod.Status.Phase
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Ready {
+			ready++
+		}
+
+		// reasons such as CrashLoopBackOff say more than the phase does
+		if status.State.Waiting != nil && status.State.Waiting.Reason != "" && status.State.Waiting.Reason != "ContainerCreating" {
+			phase = status.State.Waiting.Reason
+		}
+	}
+
+	workload := kubernetesFetchedWorkload{
+		workload: kubernetesWorkload{
+			Kind:     "Pod",
+			Ready:    ready,
+			Desired:  len(pod.Status.ContainerStatuses),
+			Restarts: pod.restarts(),
+			Phase:    phase,
+		},
+		annotations: pod.Metadata.Annotations,
+	}
+
+	switch {
+	case phase == "Running" && ready == len(pod.Status.ContainerStatuses):
+		workload.workload.StateIcon = dockerContainerStateIconOK
+	case phase == "Succeeded":
+		workload.workload.StateIcon = dockerContainerStateIconPaused
+	case phase == "Pending" || phase == "Running" || phase == "Unknown":
+		workload.workload.StateIcon = dockerContainerStateIconOther
+	default:
+		workload.workload.StateIcon = dockerContainerStateIconWarn
+	}
+
+	workload.workload.StateText = kubernetesPhaseText(phase)
+	applyKubernetesMetadata(&workload, &pod.Metadata)
+
+	return workload
+}
+
+func applyKubernetesMetadata(fetched *kubernetesFetchedWorkload, metadata *kubernetesObjectMeta) {
+	workload := &fetched.workload
+	annotations := dockerContainerLabels(metadata.Annotations)
+
+	workload.Namespace = metadata.Namespace
+	workload.Name = metadata.Name
+	workload.Title = annotations.getOrDefault(kubernetesAnnotationName, metadata.Name)
+	workload.URL = annotations.getOrDefault(kubernetesAnnotationURL, "")
+	workload.Description = annotations.getOrDefault(kubernetesAnnotationDescription, "")
+	workload.SameTab = stringToBool(annotations.getOrDefault(kubernetesAnnotationSameTab, "false"))
+	workload.Icon = newCustomIconField(annotations.getOrDefault(kubernetesAnnotationIcon, "si:kubernetes"))
+}
+
+func kubernetesReplicasPhase(ready, desired int) string {
+	switch {
+	case desired == 0:
+		return "ScaledDown"
+	case ready >= desired:
+		return "Available"
+	case ready == 0:
+		return "Unavailable"
+	}
+
+	return "Progressing"
+}
+
+func kubernetesReplicasStateIcon(ready, desired int) string {
+	switch {
+	case desired == 0:
+		return dockerContainerStateIconPaused
+	case ready >= desired:
+		return dockerContainerStateIconOK
+	case ready == 0:
+		return dockerContainerStateIconWarn
+	}
+
+	return dockerContainerStateIconOther
+}
+
+func kubernetesPhaseText(phase string) string {
+	switch phase {
+	case "Available", "Running":
+		return "Działa"
+	case "Progressing":
+		return "Częściowo gotowy"
+	case "Unavailable":
+		return "Niedostępny"
+	case "ScaledDown":
+		return "Przeskalowany do zera"
+	case "Pending":
+		return "Oczekuje"
+	case "Succeeded":
+		return "Zakończony"
+	case "Failed":
+		return "Błąd"
+	case "Unknown":
+		return "Nieznany stan"
+	}
+
+	return phase
+}
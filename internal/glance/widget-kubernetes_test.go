@@ -0,0 +1,159 @@
+package glance
+
+import (
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newFakeKubernetesAPIServer(t *testing.T, token string) *httptest.Server {
+	responses := map[string]string{
+		"/apis/apps/v1/namespaces/media/deployments": `{"items": [
+			{"metadata": {"name": "jellyfin", "namespace": "media", "annotations": {"glance.name": "Jellyfin", "glance.url": "https://jellyfin.lan", "glance.icon": "si:jellyfin"}}, "spec": {"replicas": 2}, "status": {"readyReplicas": 1}},
+			{"metadata": {"name": "sonarr", "namespace": "media"}, "spec": {"replicas": 1}, "status": {"readyReplicas": 1}},
+			{"metadata": {"name": "hidden", "namespace": "media", "annotations": {"glance.hide": "true"}}, "spec": {"replicas": 1}, "status": {"readyReplicas": 1}}
+		]}`,
+		"/apis/apps/v1/namespaces/media/statefulsets": `{"items": [
+			{"metadata": {"name": "postgres", "namespace": "media"}, "spec": {"replicas": 1}, "status": {}}
+		]}`,
+		"/api/v1/namespaces/media/pods": `{"items": [
+			{"metadata": {"name": "jellyfin-7d9f-abcde", "namespace": "media", "labels": {"pod-template-hash": "7d9f"}, "ownerReferences": [{"kind": "ReplicaSet", "name": "jellyfin-7d9f"}]},
+				"status": {"phase": "Running", "containerStatuses": [{"ready": true, "restartCount": 3, "state": {}}]}},
+			{"metadata": {"name": "postgres-0", "namespace": "media", "ownerReferences": [{"kind": "StatefulSet", "name": "postgres"}]},
+				"status": {"phase": "Running", "containerStatuses": [{"ready": false, "restartCount": 5, "state": {"waiting": {"reason": "CrashLoopBackOff"}}}]}}
+		]}`,
+	}
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("User-Agent") != glanceUserAgentString {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"kind": "Status", "message": "unexpected user agent"}`))
+			return
+		}
+
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"kind": "Status", "message": "Unauthorized"}`))
+			return
+		}
+
+		response, ok := responses[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"kind": "Status", "message": "namespaces \"private\" is forbidden"}`))
+			return
+		}
+
+		w.Write([]byte(response))
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func fakeKubernetesServerCA(server *httptest.Server) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+}
+
+func TestKubernetesWorkloadsFromKubeconfig(t *testing.T) {
+	server := newFakeKubernetesAPIServer(t, "secret-token")
+
+	kubeconfig := filepath.Join(t.TempDir(), "config")
+	os.WriteFile(kubeconfig, fmt.Appendf(nil, `
+apiVersion: v1
+kind: Config
+current-context: k3s
+clusters:
+  - name: k3s
+    cluster:
+      server: %s
+      certificate-authority-data: %s
+users:
+  - name: admin
+    user:
+      token: secret-token
+contexts:
+  - name: k3s
+    context:
+      cluster: k3s
+      user: admin
+`, server.URL, base64.StdEncoding.EncodeToString(fakeKubernetesServerCA(server))), 0o600)
+
+	widget := &kubernetesWidget{Kubeconfig: kubeconfig, Namespaces: []string{"media", "private"}}
+	if err := widget.initialize(); err != nil {
+		t.Fatalf("initializing widget: %v", err)
+	}
+	widget.update(t.Context())
+
+	if widget.Error != nil {
+		t.Fatalf("unexpected error: %v", widget.Error)
+	}
+
+	if widget.Notice == nil {
+		t.Errorf("expected a notice about the namespace that couldn't be listed")
+	}
+
+	workloads := widget.Workloads
+	if len(workloads) != 3 {
+		t.Fatalf("expected 3 workloads, got %+v", workloads)
+	}
+
+	postgres, jellyfin, sonarr := &workloads[0], &workloads[1], &workloads[2]
+
+	if postgres.Name != "postgres" || postgres.StateIcon != "warn" || postgres.Restarts != 5 || postgres.Ready != 0 || postgres.Desired != 1 {
+		t.Errorf("unexpected statefulset: %+v", postgres)
+	}
+
+	if jellyfin.Title != "Jellyfin" || jellyfin.URL != "https://jellyfin.lan" || jellyfin.Restarts != 3 || jellyfin.Ready != 1 || jellyfin.Desired != 2 || jellyfin.StateIcon != "other" {
+		t.Errorf("unexpected deployment: %+v", jellyfin)
+	}
+
+	if sonarr.Title != "sonarr" || sonarr.StateIcon != "ok" || sonarr.Restarts != 0 {
+		t.Errorf("unexpected deployment: %+v", sonarr)
+	}
+}
+
+func TestKubernetesPodsInCluster(t *testing.T) {
+	server := newFakeKubernetesAPIServer(t, "service-account-token")
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "token"), []byte("service-account-token\n"), 0o600)
+	os.WriteFile(filepath.Join(dir, "ca.crt"), fakeKubernetesServerCA(server), 0o600)
+
+	previousDir := kubernetesServiceAccountDir
+	kubernetesServiceAccountDir = dir
+	t.Cleanup(func() { kubernetesServiceAccountDir = previousDir })
+
+	host, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+	t.Setenv("KUBERNETES_SERVICE_HOST", host)
+	t.Setenv("KUBERNETES_SERVICE_PORT", port)
+
+	widget := &kubernetesWidget{Namespaces: []string{"media"}, Kinds: []string{"pods"}}
+	if err := widget.initialize(); err != nil {
+		t.Fatalf("initializing widget: %v", err)
+	}
+	widget.update(t.Context())
+
+	if widget.Error != nil {
+		t.Fatalf("unexpected error: %v", widget.Error)
+	}
+
+	if len(widget.Workloads) != 2 {
+		t.Fatalf("expected 2 pods, got %d", len(widget.Workloads))
+	}
+
+	crashing := &widget.Workloads[0]
+	if crashing.Name != "postgres-0" || crashing.Phase != "CrashLoopBackOff" || crashing.StateIcon != "warn" {
+		t.Errorf("unexpected pod: %+v", crashing)
+	}
+
+	if widget.Workloads[1].StateText != "Działa" {
+		t.Errorf("expected the running pod to be shown as such, got %s", widget.Workloads[1].StateText)
+	}
+}
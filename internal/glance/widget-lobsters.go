@@ -8,6 +8,10 @@ import (
 	"time"
 )
 
+func init() {
+	registerWidget("lobsters", func() widget { return &lobstersWidget{} })
+}
+
 type lobstersWidget struct {
 	widgetBase     `yaml:",inline"`
 	Posts          forumPostList `yaml:"-"`
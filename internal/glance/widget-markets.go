@@ -14,6 +14,11 @@ import (
 
 var marketsWidgetTemplate = mustParseTemplate("markets.html", "widget-base.html")
 
+func init() {
+	registerWidget("markets", func() widget { return &marketsWidget{} })
+	registerWidget("stocks", func() widget { return &marketsWidget{} })
+}
+
 type marketsWidget struct {
 	widgetBase         `yaml:",inline"`
 	StocksRequests     []marketRequest `yaml:"stocks"`
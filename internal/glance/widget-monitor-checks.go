@@ -0,0 +1,320 @@
+package glance
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	siteCheckHTTP    = "http"
+	siteCheckTCP     = "tcp"
+	siteCheckDNS     = "dns"
+	siteCheckTLS     = "tls"
+	siteCheckKeyword = "keyword"
+)
+
+var siteCheckTypes = []string{siteCheckHTTP, siteCheckTCP, siteCheckDNS, siteCheckTLS, siteCheckKeyword}
+
+var siteDNSRecordTypes = []string{"A", "AAAA", "CNAME", "MX", "NS", "TXT"}
+
+// Only this much of the body gets searched for the keyword
+const siteKeywordMaxBodySize = 5 << 20
+
+func (r *SiteStatusRequest) initialize() error {
+	if r.Check == "" {
+		r.Check = siteCheckHTTP
+	}
+
+	if !slices.Contains(siteCheckTypes, r.Check) {
+		return fmt.Errorf("check must be one of %s", strings.Join(siteCheckTypes, ", "))
+	}
+
+	switch r.Check {
+	case siteCheckTCP:
+		if r.Address == "" {
+			return errors.New("address is required for tcp checks")
+		}
+
+		if _, _, err := net.SplitHostPort(r.Address); err != nil {
+			return fmt.Errorf("address must contain a host and a port: %v", err)
+		}
+	case siteCheckDNS:
+		if r.DNSName == "" {
+			return errors.New("dns-name is required for dns checks")
+		}
+
+		r.DNSRecordType = strings.ToUpper(r.DNSRecordType)
+		if r.DNSRecordType == "" {
+			r.DNSRecordType = "A"
+		} else if !slices.Contains(siteDNSRecordTypes, r.DNSRecordType) {
+			return fmt.Errorf("dns-record-type must be one of %s", strings.Join(siteDNSRecordTypes, ", "))
+		}
+
+		if r.DNSServer != "" {
+			if _, _, err := net.SplitHostPort(r.DNSServer); err != nil {
+				r.DNSServer = net.JoinHostPort(r.DNSServer, "53")
+			}
+		}
+	case siteCheckTLS:
+		if r.ExpiryWarningDays == 0 {
+			r.ExpiryWarningDays = 14
+		} else if r.ExpiryWarningDays < 0 {
+			return errors.New("expiry-warning-days must be positive")
+		}
+
+		if r.Address == "" {
+			address, err := tlsAddressFromURL(ternary(r.CheckURL != "", r.CheckURL, r.DefaultURL))
+			if err != nil {
+				return err
+			}
+			r.Address = address
+		}
+	case siteCheckKeyword:
+		if r.Keyword == "" && r.Regex == "" {
+			return errors.New("keyword or regex is required for keyword checks")
+		}
+	}
+
+	if r.Regex != "" {
+		regex, err := regexp.Compile(r.Regex)
+		if err != nil {
+			return fmt.Errorf("invalid regex: %v", err)
+		}
+		r.regex = regex
+	}
+
+	if (r.Check == siteCheckHTTP || r.Check == siteCheckKeyword) && r.DefaultURL == "" && r.CheckURL == "" {
+		return errors.New("url is required")
+	}
+
+	return nil
+}
+
+func tlsAddressFromURL(rawURL string) (string, error) {
+	if rawURL == "" {
+		return "", errors.New("url or address is required for tls checks")
+	}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Hostname() == "" {
+		return "", fmt.Errorf("could not get the host from url %s", rawURL)
+	}
+
+	return net.JoinHostPort(parsed.Hostname(), ternary(parsed.Port() != "", parsed.Port(), "443")), nil
+}
+
+func (r *SiteStatusRequest) checkType() string {
+	return strings.ToUpper(r.Check)
+}
+
+// What gets checked, used in alerts
+func (r *SiteStatusRequest) target() string {
+	switch r.Check {
+	case siteCheckTCP, siteCheckTLS:
+		return r.Address
+	case siteCheckDNS:
+		return r.DNSName
+	}
+
+	return ternary(r.CheckURL != "", r.CheckURL, r.DefaultURL)
+}
+
+func (r *SiteStatusRequest) timeout() time.Duration {
+	return ternary(r.Timeout > 0, time.Duration(r.Timeout), 3*time.Second)
+}
+
+func siteStatusFromError(status siteStatus, err error) siteStatus {
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		status.TimedOut = true
+	}
+
+	status.Error = err
+	return status
+}
+
+func checkTCPStatus(r *SiteStatusRequest) siteStatus {
+	start := time.Now()
+	conn, err := net.DialTimeout("tcp", r.Address, r.timeout())
+	status := siteStatus{ResponseTime: time.Since(start)}
+
+	if err != nil {
+		return siteStatusFromError(status, err)
+	}
+
+	conn.Close()
+	status.Text = "Port otwarty"
+
+	return status
+}
+
+func checkDNSStatus(r *SiteStatusRequest) siteStatus {
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout())
+	defer cancel()
+
+	resolver := net.DefaultResolver
+	if r.DNSServer != "" {
+		resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, network, r.DNSServer)
+			},
+		}
+	}
+
+	start := time.Now()
+	answers, err := lookupDNSRecords(ctx, resolver, r.DNSRecordType, r.DNSName)
+	status := siteStatus{ResponseTime: time.Since(start)}
+
+	if err != nil {
+		return siteStatusFromError(status, err)
+	}
+
+	if len(answers) == 0 {
+		status.Failed = true
+		status.Text = "Brak odpowiedzi"
+		return status
+	}
+
+	if r.Expect != "" {
+		expected := normalizeDNSAnswer(r.Expect)
+		if !slices.ContainsFunc(answers, func(answer string) bool { return normalizeDNSAnswer(answer) == expected }) {
+			status.Failed = true
+			status.Text = "Nieoczekiwana odpowiedź: " + strings.Join(answers, ", ")
+			return status
+		}
+	}
+
+	status.Text = answers[0]
+	if len(answers) > 1 {
+		status.Text += fmt.Sprintf(" (+%d)", len(answers)-1)
+	}
+
+	return status
+}
+
+func normalizeDNSAnswer(answer string) string {
+	return strings.ToLower(strings.TrimSuffix(answer, "."))
+}
+
+func lookupDNSRecords(ctx context.Context, resolver *net.Resolver, recordType, name string) ([]string, error) {
+	var answers []string
+
+	switch recordType {
+	case "A", "AAAA":
+		ips, err := resolver.LookupIP(ctx, ternary(recordType == "A", "ip4", "ip6"), name)
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range ips {
+			answers = append(answers, ip.String())
+		}
+	case "CNAME":
+		cname, err := resolver.LookupCNAME(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		answers = append(answers, cname)
+	case "MX":
+		records, err := resolver.LookupMX(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			answers = append(answers, record.Host)
+		}
+	case "NS":
+		records, err := resolver.LookupNS(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			answers = append(answers, record.Host)
+		}
+	case "TXT":
+		records, err := resolver.LookupTXT(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		answers = records
+	}
+
+	return answers, nil
+}
+
+func checkTLSStatus(r *SiteStatusRequest) siteStatus {
+	host, _, _ := net.SplitHostPort(r.Address)
+	dialer := &net.Dialer{Timeout: r.timeout()}
+
+	start := time.Now()
+	conn, err := tls.DialWithDialer(dialer, "tcp", r.Address, &tls.Config{
+		ServerName:         host,
+		InsecureSkipVerify: r.AllowInsecure,
+	})
+	status := siteStatus{ResponseTime: time.Since(start)}
+
+	// expiry is what this check is about, so it's reported as such rather
+	// than as a generic handshake error
+	var invalidErr x509.CertificateInvalidError
+	if errors.As(err, &invalidErr) && invalidErr.Reason == x509.Expired {
+		status.Failed = true
+		status.Text = "Certyfikat wygasł"
+		return status
+	}
+
+	if err != nil {
+		return siteStatusFromError(status, err)
+	}
+	defer conn.Close()
+
+	certificates := conn.ConnectionState().PeerCertificates
+	if len(certificates) == 0 {
+		status.Error = errors.New("no certificates were presented")
+		return status
+	}
+
+	daysLeft := int(time.Until(certificates[0].NotAfter).Hours() / 24)
+
+	switch {
+	case daysLeft < 0:
+		status.Failed = true
+		status.Text = "Certyfikat wygasł"
+	case daysLeft < r.ExpiryWarningDays:
+		status.Failed = true
+		status.Text = fmt.Sprintf("Certyfikat wygasa za %d dni", daysLeft)
+	default:
+		status.Text = fmt.Sprintf("Ważny jeszcze %d dni", daysLeft)
+	}
+
+	return status
+}
+
+func (r *SiteStatusRequest) checkResponseBody(body io.Reader, status *siteStatus) {
+	contents, err := io.ReadAll(io.LimitReader(body, siteKeywordMaxBodySize))
+	if err != nil {
+		*status = siteStatusFromError(*status, err)
+		return
+	}
+
+	if r.Keyword != "" && !strings.Contains(string(contents), r.Keyword) {
+		status.Failed = true
+		status.Text = "Brak oczekiwanej treści"
+		return
+	}
+
+	if r.regex != nil && !r.regex.Match(contents) {
+		status.Failed = true
+		status.Text = "Brak oczekiwanej treści"
+	}
+}
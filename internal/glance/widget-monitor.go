@@ -2,12 +2,19 @@ package glance
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"html/template"
+	"log/slog"
+	"math"
 	"net/http"
 	"net/http/httptrace"
+	"os"
+	"regexp"
 	"slices"
 	"strconv"
+	"sync"
 	"time"
 )
 
@@ -16,32 +23,216 @@ var (
 	monitorWidgetCompactTemplate = mustParseTemplate("monitor-compact.html", "widget-base.html")
 )
 
+func init() {
+	registerWidget("monitor", func() widget { return &monitorWidget{} })
+}
+
 type monitorWidget struct {
-	widgetBase `yaml:",inline"`
-	Sites      []struct {
-		*SiteStatusRequest `yaml:",inline"`
-		Status             *siteStatus     `yaml:"-"`
-		URL                string          `yaml:"-"`
-		ErrorURL           string          `yaml:"error-url"`
-		Title              string          `yaml:"title"`
-		Icon               customIconField `yaml:"icon"`
-		SameTab            bool            `yaml:"same-tab"`
-		StatusText         string          `yaml:"-"`
-		StatusStyle        string          `yaml:"-"`
-		AltStatusCodes     []int           `yaml:"alt-status-codes"`
-	} `yaml:"sites"`
-	Style           string `yaml:"style"`
-	ShowFailingOnly bool   `yaml:"show-failing-only"`
-	HasFailing      bool   `yaml:"-"`
+	widgetBase      `yaml:",inline"`
+	Sites           []monitorSite `yaml:"sites"`
+	Style           string        `yaml:"style"`
+	ShowFailingOnly bool          `yaml:"show-failing-only"`
+	HasFailing      bool          `yaml:"-"`
+	History         struct {
+		Length      int    `yaml:"length"`
+		PersistFile string `yaml:"persist-file"`
+	} `yaml:"history"`
+	FromDocker *dockerDiscoveryConfig `yaml:"from-docker"`
+	// checks of discovered sites loaded from the persist file, used once
+	// their containers show up
+	savedHistory map[string][]monitorCheck
+}
+
+type monitorSite struct {
+	*SiteStatusRequest `yaml:",inline"`
+	Status             *siteStatus         `yaml:"-"`
+	URL                string              `yaml:"-"`
+	ErrorURL           string              `yaml:"error-url"`
+	Title              string              `yaml:"title"`
+	Icon               customIconField     `yaml:"icon"`
+	SameTab            bool                `yaml:"same-tab"`
+	StatusText         string              `yaml:"-"`
+	StatusStyle        string              `yaml:"-"`
+	AltStatusCodes     []int               `yaml:"alt-status-codes"`
+	History            *monitorSiteHistory `yaml:"-"`
+	// set for sites discovered from Docker, whose position in the list
+	// changes as containers come and go
+	discoveryKey string
 }
 
 func (widget *monitorWidget) initialize() error {
 	widget.withTitle("Monitor").withCacheDuration(5 * time.Minute)
 
+	if widget.History.Length == 0 {
+		// enough to calculate the uptime over the last 7 days
+		widget.History.Length = int(min(monitorHistoryMaxLength, 7*24*time.Hour/widget.cacheDuration))
+	} else if widget.History.Length < 2 || widget.History.Length > monitorHistoryMaxLength {
+		return fmt.Errorf("history length must be between 2 and %d", monitorHistoryMaxLength)
+	}
+
+	for i := range widget.Sites {
+		site := &widget.Sites[i]
+
+		if site.SiteStatusRequest == nil {
+			site.SiteStatusRequest = &SiteStatusRequest{}
+		}
+
+		if err := site.SiteStatusRequest.initialize(); err != nil {
+			return fmt.Errorf("site %d: %v", i+1, err)
+		}
+
+		site.History = newMonitorSiteHistory(widget.History.Length)
+	}
+
+	if widget.FromDocker != nil {
+		if err := widget.FromDocker.initialize(); err != nil {
+			return err
+		}
+	}
+
+	if widget.History.PersistFile != "" {
+		if err := widget.loadHistory(); err != nil {
+			slog.Warn("Loading monitor history", "file", widget.History.PersistFile, "error", err)
+		}
+	}
+
+	return nil
+}
+
+// Checks run on schedule so that the history doesn't have gaps while nobody is looking
+func (widget *monitorWidget) updatesOnSchedule() {}
+
+const monitorHistoryMaxLength = 10_000
+
+// Sites are identified by their position and address so that the checks of
+// sites that were moved or removed from the config aren't mixed up
+func (widget *monitorWidget) historyKey(index int) string {
+	if key := widget.Sites[index].discoveryKey; key != "" {
+		return "docker:" + key
+	}
+
+	return strconv.Itoa(index) + ":" + widget.Sites[index].DefaultURL
+}
+
+func (widget *monitorWidget) persistFile() string {
+	return widget.History.PersistFile
+}
+
+func (widget *monitorWidget) loadHistory() error {
+	contents, err := os.ReadFile(widget.History.PersistFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	var saved map[string][]monitorCheck
+	if err := json.Unmarshal(contents, &saved); err != nil {
+		return err
+	}
+
+	for i := range widget.Sites {
+		for _, check := range saved[widget.historyKey(i)] {
+			widget.Sites[i].History.checks.push(check)
+		}
+	}
+
+	if widget.FromDocker != nil {
+		widget.savedHistory = saved
+	}
+
+	return nil
+}
+
+func (widget *monitorWidget) saveHistory() error {
+	saved := make(map[string][]monitorCheck, len(widget.Sites))
+	for i := range widget.Sites {
+		saved[widget.historyKey(i)] = widget.Sites[i].History.values()
+	}
+
+	encoded, err := json.Marshal(saved)
+	if err != nil {
+		return err
+	}
+
+	temp := widget.History.PersistFile + ".tmp"
+	if err := os.WriteFile(temp, encoded, 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(temp, widget.History.PersistFile)
+}
+
+// The discovered sites are placed after the ones from the config and keep
+// their history for as long as their container exists
+func (widget *monitorWidget) discoverSites() error {
+	services, err := widget.FromDocker.discover()
+	if err != nil {
+		return err
+	}
+
+	sites := make([]monitorSite, 0, len(widget.Sites)+len(services))
+	discovered := make(map[string]*monitorSite)
+
+	for i := range widget.Sites {
+		site := &widget.Sites[i]
+		if site.discoveryKey == "" {
+			sites = append(sites, *site)
+		} else {
+			discovered[site.discoveryKey] = site
+		}
+	}
+
+	for i := range services {
+		service := &services[i]
+		key := service.Container + ":" + service.URL
+
+		site := monitorSite{
+			SiteStatusRequest: &SiteStatusRequest{DefaultURL: service.URL, CheckURL: service.CheckURL},
+			Title:             service.Title,
+			SameTab:           service.SameTab != nil && *service.SameTab,
+			discoveryKey:      key,
+		}
+
+		if service.Icon != "" {
+			site.Icon = newCustomIconField(service.Icon)
+		}
+
+		existing, exists := discovered[key]
+		if exists && existing.CheckURL == service.CheckURL {
+			site.SiteStatusRequest = existing.SiteStatusRequest
+			site.Status = existing.Status
+			site.URL = existing.URL
+			site.StatusText = existing.StatusText
+			site.StatusStyle = existing.StatusStyle
+		} else if err := site.SiteStatusRequest.initialize(); err != nil {
+			slog.Warn("Skipping discovered monitor site", "container", service.Container, "error", err)
+			continue
+		}
+
+		if exists {
+			site.History = existing.History
+		} else {
+			site.History = newMonitorSiteHistory(widget.History.Length)
+			for _, check := range widget.savedHistory["docker:"+key] {
+				site.History.checks.push(check)
+			}
+			delete(widget.savedHistory, "docker:"+key)
+		}
+
+		sites = append(sites, site)
+	}
+
+	widget.Sites = sites
 	return nil
 }
 
 func (widget *monitorWidget) update(ctx context.Context) {
+	var discoveryErr error
+	if widget.FromDocker != nil {
+		discoveryErr = widget.discoverSites()
+	}
+
 	requests := make([]*SiteStatusRequest, len(widget.Sites))
 
 	for i := range widget.Sites {
@@ -49,19 +240,24 @@ func (widget *monitorWidget) update(ctx context.Context) {
 	}
 
 	statuses, err := fetchStatusForSites(requests)
+	if err == nil && discoveryErr != nil {
+		err = fmt.Errorf("%w: could not discover containers: %v", errPartialContent, discoveryErr)
+	}
 
 	if !widget.canContinueUpdateAfterHandlingErr(err) {
 		return
 	}
 
 	widget.HasFailing = false
+	now := time.Now()
 
 	for i := range widget.Sites {
 		site := &widget.Sites[i]
 		status := &statuses[i]
 		site.Status = status
 
-		if !slices.Contains(site.AltStatusCodes, status.Code) && (status.Code >= 400 || status.Error != nil) {
+		failing := site.applyStatus(status)
+		if failing {
 			widget.HasFailing = true
 		}
 
@@ -71,11 +267,138 @@ func (widget *monitorWidget) update(ctx context.Context) {
 			site.URL = site.DefaultURL
 		}
 
+		site.History.add(monitorCheck{
+			Time:           now,
+			Up:             !failing,
+			ResponseTimeMs: status.ResponseTime.Milliseconds(),
+		})
+
+		if status.Error != nil {
+			widget.emitAlertEvent(site.Title, failing, status.Error.Error())
+		} else if status.Text != "" {
+			widget.emitAlertEvent(site.Title, failing, fmt.Sprintf("%s check of %s: %s", site.checkType(), site.target(), status.Text))
+		} else {
+			widget.emitAlertEvent(site.Title, failing, fmt.Sprintf("%s responded with status code %d", site.DefaultURL, status.Code))
+		}
+	}
+
+	if widget.History.PersistFile != "" {
+		if err := widget.saveHistory(); err != nil {
+			slog.Warn("Saving monitor history", "file", widget.History.PersistFile, "error", err)
+		}
+	}
+}
+
+// Sets the status text and style of the site and returns whether it's failing
+func (site *monitorSite) applyStatus(status *siteStatus) bool {
+	switch {
+	case status.Error != nil:
 		site.StatusText = statusCodeToText(status.Code, site.AltStatusCodes)
-		site.StatusStyle = statusCodeToStyle(status.Code, site.AltStatusCodes)
+		site.StatusStyle = "error"
+		return !slices.Contains(site.AltStatusCodes, status.Code)
+	case status.Failed:
+		site.StatusText = status.Text
+		site.StatusStyle = "error"
+		return true
+	case status.Text != "":
+		site.StatusText = status.Text
+		site.StatusStyle = "ok"
+		return false
 	}
+
+	site.StatusText = statusCodeToText(status.Code, site.AltStatusCodes)
+	site.StatusStyle = statusCodeToStyle(status.Code, site.AltStatusCodes)
+
+	return !slices.Contains(site.AltStatusCodes, status.Code) && status.Code >= 400
+}
+
+type monitorCheck struct {
+	Time           time.Time `json:"time"`
+	Up             bool      `json:"up"`
+	ResponseTimeMs int64     `json:"response_ms"`
+}
+
+// Results of the checks of a single site, oldest first
+type monitorSiteHistory struct {
+	mu     sync.Mutex
+	checks *ringBuffer[monitorCheck]
+}
+
+func newMonitorSiteHistory(length int) *monitorSiteHistory {
+	return &monitorSiteHistory{checks: newRingBuffer[monitorCheck](length)}
+}
+
+func (h *monitorSiteHistory) add(check monitorCheck) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checks.push(check)
 }
 
+func (h *monitorSiteHistory) values() []monitorCheck {
+	if h == nil {
+		return nil
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.checks.values()
+}
+
+func (h *monitorSiteHistory) uptimeSince(since time.Time) string {
+	var total, up int
+	for _, check := range h.values() {
+		if check.Time.Before(since) {
+			continue
+		}
+
+		total++
+		if check.Up {
+			up++
+		}
+	}
+
+	if total == 0 {
+		return ""
+	}
+
+	if up == total {
+		return "100%"
+	} else if up == 0 {
+		return "0%"
+	}
+
+	// rounded down so that a single failed check never shows up as 100%
+	return strconv.FormatFloat(math.Floor(float64(up)/float64(total)*1000)/10, 'f', 1, 64) + "%"
+}
+
+func (h *monitorSiteHistory) Uptime24h() string {
+	return h.uptimeSince(time.Now().Add(-24 * time.Hour))
+}
+
+func (h *monitorSiteHistory) Uptime7d() string {
+	return h.uptimeSince(time.Now().Add(-7 * 24 * time.Hour))
+}
+
+// The last checks, used for the status bar
+func (h *monitorSiteHistory) RecentChecks() []monitorCheck {
+	checks := h.values()
+	return checks[max(0, len(checks)-monitorRecentChecksCount):]
+}
+
+func (h *monitorSiteHistory) ResponseTimeChartPoints() string {
+	checks := h.RecentChecks()
+	values := make([]float64, 0, len(checks))
+	for i := range checks {
+		if checks[i].Up {
+			values = append(values, float64(checks[i].ResponseTimeMs))
+		}
+	}
+
+	return svgPolylineCoordsFromYValues(100, 20, values)
+}
+
+const monitorRecentChecksCount = 30
+
 func (widget *monitorWidget) Render() template.HTML {
 	if widget.Style == "compact" {
 		return widget.renderTemplate(widget, monitorWidgetCompactTemplate)
@@ -124,6 +447,18 @@ type SiteStatusRequest struct {
 		Username string `yaml:"username"`
 		Password string `yaml:"password"`
 	} `yaml:"basic-auth"`
+
+	Check             string `yaml:"check"`
+	Address           string `yaml:"address"`
+	DNSServer         string `yaml:"dns-server"`
+	DNSName           string `yaml:"dns-name"`
+	DNSRecordType     string `yaml:"dns-record-type"`
+	Expect            string `yaml:"expect"`
+	ExpiryWarningDays int    `yaml:"expiry-warning-days"`
+	Keyword           string `yaml:"keyword"`
+	Regex             string `yaml:"regex"`
+
+	regex *regexp.Regexp
 }
 
 type siteStatus struct {
@@ -131,9 +466,22 @@ type siteStatus struct {
 	TimedOut     bool
 	ResponseTime time.Duration
 	Error        error
+	// Set by the checks which don't have a status code or by a
+	// failed assertion about the response of the HTTP checks
+	Text   string
+	Failed bool
 }
 
 func fetchSiteStatusTask(statusRequest *SiteStatusRequest) (siteStatus, error) {
+	switch statusRequest.Check {
+	case siteCheckTCP:
+		return checkTCPStatus(statusRequest), nil
+	case siteCheckDNS:
+		return checkDNSStatus(statusRequest), nil
+	case siteCheckTLS:
+		return checkTLSStatus(statusRequest), nil
+	}
+
 	var url string
 	if statusRequest.CheckURL != "" {
 		url = statusRequest.CheckURL
@@ -141,8 +489,7 @@ func fetchSiteStatusTask(statusRequest *SiteStatusRequest) (siteStatus, error) {
 		url = statusRequest.DefaultURL
 	}
 
-	timeout := ternary(statusRequest.Timeout > 0, time.Duration(statusRequest.Timeout), 3*time.Second)
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	ctx, cancel := context.WithTimeout(context.Background(), statusRequest.timeout())
 	defer cancel()
 
 	var requestSentAt time.Time
@@ -191,6 +538,10 @@ func fetchSiteStatusTask(statusRequest *SiteStatusRequest) (siteStatus, error) {
 
 	status.Code = response.StatusCode
 
+	if statusRequest.Check == siteCheckKeyword && status.Code < 400 {
+		statusRequest.checkResponseBody(response.Body, &status)
+	}
+
 	return status, nil
 }
 
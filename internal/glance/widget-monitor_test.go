@@ -0,0 +1,161 @@
+package glance
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMonitorSiteHistoryUptime(t *testing.T) {
+	history := newMonitorSiteHistory(5)
+	now := time.Now()
+
+	// the oldest check gets pushed out of the buffer
+	history.add(monitorCheck{Time: now.Add(-48 * time.Hour), Up: true})
+	history.add(monitorCheck{Time: now.Add(-47 * time.Hour), Up: false})
+	history.add(monitorCheck{Time: now.Add(-30 * time.Hour), Up: true})
+	history.add(monitorCheck{Time: now.Add(-2 * time.Hour), Up: true, ResponseTimeMs: 120})
+	history.add(monitorCheck{Time: now.Add(-time.Hour), Up: false})
+	history.add(monitorCheck{Time: now, Up: true, ResponseTimeMs: 80})
+
+	if uptime := history.Uptime24h(); uptime != "66.6%" {
+		t.Errorf("expected 24h uptime of 66.6%%, got %s", uptime)
+	}
+
+	if uptime := history.Uptime7d(); uptime != "60.0%" {
+		t.Errorf("expected 7d uptime of 60.0%%, got %s", uptime)
+	}
+
+	if checks := history.RecentChecks(); len(checks) != 5 || !checks[4].Time.Equal(now) {
+		t.Errorf("expected the 5 most recent checks, oldest first, got %v", checks)
+	}
+
+	if newMonitorSiteHistory(5).Uptime24h() != "" {
+		t.Error("expected no uptime without any checks")
+	}
+}
+
+func TestMonitorHistoryPersistence(t *testing.T) {
+	persistFile := filepath.Join(t.TempDir(), "monitor.json")
+
+	newMonitor := func() *monitorWidget {
+		widget := &monitorWidget{}
+		widget.Sites = make([]monitorSite, 1)
+		widget.Sites[0].SiteStatusRequest = &SiteStatusRequest{DefaultURL: "https://example.com"}
+		widget.History.PersistFile = persistFile
+
+		if err := widget.initialize(); err != nil {
+			t.Fatal(err)
+		}
+
+		return widget
+	}
+
+	widget := newMonitor()
+	if widget.History.Length != 2016 {
+		t.Errorf("expected the default length to cover 7 days of checks, got %d", widget.History.Length)
+	}
+
+	widget.Sites[0].History.add(monitorCheck{Time: time.Now(), Up: true, ResponseTimeMs: 42})
+	if err := widget.saveHistory(); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := newMonitor()
+	if checks := restored.Sites[0].History.values(); len(checks) != 1 || checks[0].ResponseTimeMs != 42 {
+		t.Errorf("expected the saved check to be restored, got %v", checks)
+	}
+}
+
+func TestMonitorCheckTypes(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<h1>Welcome home</h1>"))
+	}))
+	defer server.Close()
+
+	address := strings.TrimPrefix(server.URL, "https://")
+
+	expiredServer := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	expiredServer.TLS = &tls.Config{Certificates: []tls.Certificate{newExpiredCertificate(t)}}
+	expiredServer.StartTLS()
+	defer expiredServer.Close()
+
+	expiredAddress := strings.TrimPrefix(expiredServer.URL, "https://")
+
+	tests := []struct {
+		name    string
+		request SiteStatusRequest
+		failing bool
+	}{
+		{"open port", SiteStatusRequest{Check: "tcp", Address: address}, false},
+		{"closed port", SiteStatusRequest{Check: "tcp", Address: "127.0.0.1:1"}, true},
+		{"valid certificate", SiteStatusRequest{Check: "tls", DefaultURL: server.URL, AllowInsecure: true}, false},
+		{"expiring certificate", SiteStatusRequest{Check: "tls", Address: address, AllowInsecure: true, ExpiryWarningDays: 100_000}, true},
+		{"untrusted certificate", SiteStatusRequest{Check: "tls", Address: address}, true},
+		{"expired certificate", SiteStatusRequest{Check: "tls", Address: expiredAddress}, true},
+		{"keyword found", SiteStatusRequest{Check: "keyword", DefaultURL: server.URL, AllowInsecure: true, Keyword: "Welcome"}, false},
+		{"regex not matched", SiteStatusRequest{Check: "keyword", DefaultURL: server.URL, AllowInsecure: true, Regex: `^\d+$`}, true},
+	}
+
+	for _, test := range tests {
+		if err := test.request.initialize(); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+
+		status, _ := fetchSiteStatusTask(&test.request)
+		site := &monitorSite{SiteStatusRequest: &test.request}
+
+		if failing := site.applyStatus(&status); failing != test.failing {
+			t.Errorf("%s: expected failing to be %v, got status %+v", test.name, test.failing, status)
+		}
+
+		if test.request.Address == expiredAddress && status.Text != "Certyfikat wygasł" {
+			t.Errorf("%s: expected the expiry to be reported, got status %+v", test.name, status)
+		}
+	}
+
+	for _, invalid := range []SiteStatusRequest{
+		{Check: "ping"},
+		{Check: "tcp", Address: "nas.lan"},
+		{Check: "dns"},
+		{Check: "dns", DNSName: "nas.lan", DNSRecordType: "SRV"},
+		{Check: "keyword", DefaultURL: "https://example.com"},
+	} {
+		if err := invalid.initialize(); err == nil {
+			t.Errorf("expected %+v to be rejected", invalid)
+		}
+	}
+}
+
+func newExpiredCertificate(t *testing.T) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-48 * time.Hour),
+		NotAfter:     time.Now().Add(-24 * time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
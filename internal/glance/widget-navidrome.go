@@ -16,6 +16,10 @@ import (
 
 var navidromeWidgetTemplate = mustParseTemplate("navidrome.html", "widget-base.html")
 
+func init() {
+	registerWidget("navidrome", func() widget { return &navidromeWidget{} })
+}
+
 type navidromeWidget struct {
 	widgetBase `yaml:",inline"`
 	URL        string `yaml:"url"`
@@ -8,6 +8,10 @@ import (
 
 var oldCalendarWidgetTemplate = mustParseTemplate("old-calendar.html", "widget-base.html")
 
+func init() {
+	registerWidget("calendar-legacy", func() widget { return &oldCalendarWidget{} })
+}
+
 type oldCalendarWidget struct {
 	widgetBase  `yaml:",inline"`
 	Calendar    *calendar
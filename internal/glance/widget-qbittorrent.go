@@ -18,6 +18,10 @@ import (
 
 var qbittorrentWidgetTemplate = mustParseTemplate("qbittorrent.html", "widget-base.html")
 
+func init() {
+	registerWidget("qbittorrent", func() widget { return &qbittorrentWidget{} })
+}
+
 type qbittorrentWidget struct {
 	widgetBase     `yaml:",inline"`
 	URL            string `yaml:"url"`
@@ -133,7 +137,7 @@ func (widget *qbittorrentWidget) login() error {
 	widget.client = &http.Client{
 		Jar:     jar,
 		Timeout: 10 * time.Second,
-		Transport: &userAgentTransport{
+		Transport: newUpstreamTransport(&userAgentTransport{
 			underlying: &http.Transport{
 				MaxIdleConns:        maxIdleConns,
 				MaxIdleConnsPerHost: maxIdleConnsPerHost,
@@ -141,7 +145,7 @@ func (widget *qbittorrentWidget) login() error {
 				IdleConnTimeout:     idleConnTimeout,
 				DisableKeepAlives:   false,
 			},
-		},
+		}),
 	}
 
 	form := url.Values{}
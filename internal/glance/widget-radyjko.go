@@ -12,6 +12,10 @@ import (
 
 var radyjkoWidgetTemplate = mustParseTemplate("radyjko.html", "widget-base.html")
 
+func init() {
+	registerWidget("radyjko", func() widget { return &radyjkoWidget{} })
+}
+
 type radyjkoWidget struct {
 	widgetBase `yaml:",inline"`
 	Stations   stationList `yaml:"-"`
@@ -18,6 +18,10 @@ var (
 	redditWidgetVerticalCardsTemplate   = mustParseTemplate("reddit-vertical-cards.html", "widget-base.html")
 )
 
+func init() {
+	registerWidget("reddit", func() widget { return &redditWidget{} })
+}
+
 type redditWidget struct {
 	widgetBase          `yaml:",inline"`
 	Posts               forumPostList     `yaml:"-"`
@@ -0,0 +1,129 @@
+package glance
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+// Each widget registers itself from an init function in its own file so that
+// adding a widget doesn't require touching any of the shared code
+type widgetDefinition struct {
+	newWidget func() widget
+	actions   []widgetAction
+}
+
+// A HTTP endpoint of a widget, served at /api/{widget-type}/{widgetID}/{name}
+type widgetAction struct {
+	method string
+	name   string
+	// streaming actions don't hold the page lock for the whole request and
+	// instead get to take it themselves for as long as they read the widget
+	streams bool
+	handle  func(widget, sync.Locker, http.ResponseWriter, *http.Request) bool
+}
+
+var widgetRegistry = map[string]*widgetDefinition{}
+
+func registerWidget(widgetType string, newWidget func() widget, actions ...widgetAction) {
+	if _, exists := widgetRegistry[widgetType]; exists {
+		panic("widget type registered more than once: " + widgetType)
+	}
+
+	widgetRegistry[widgetType] = &widgetDefinition{
+		newWidget: newWidget,
+		actions:   actions,
+	}
+}
+
+// The handler only gets called when the widget with the requested ID is of type T
+func newWidgetAction[T widget](method, name string, handle func(T, http.ResponseWriter, *http.Request)) widgetAction {
+	return widgetAction{
+		method: method,
+		name:   name,
+		handle: func(w widget, _ sync.Locker, rw http.ResponseWriter, r *http.Request) bool {
+			typed, ok := w.(T)
+			if !ok {
+				return false
+			}
+
+			handle(typed, rw, r)
+			return true
+		},
+	}
+}
+
+// Like newWidgetAction but for long-running responses, the handler is passed
+// the lock of the page which it has to hold while accessing the widget
+func newStreamingWidgetAction[T widget](method, name string, handle func(T, sync.Locker, http.ResponseWriter, *http.Request)) widgetAction {
+	return widgetAction{
+		method:  method,
+		name:    name,
+		streams: true,
+		handle: func(w widget, lock sync.Locker, rw http.ResponseWriter, r *http.Request) bool {
+			typed, ok := w.(T)
+			if !ok {
+				return false
+			}
+
+			handle(typed, lock, rw, r)
+			return true
+		},
+	}
+}
+
+func registeredWidgetTypes() []string {
+	types := make([]string, 0, len(widgetRegistry))
+	for widgetType := range widgetRegistry {
+		types = append(types, widgetType)
+	}
+
+	slices.Sort(types)
+	return types
+}
+
+func (a *application) registerWidgetActionRoutes(mux *http.ServeMux) {
+	for _, widgetType := range registeredWidgetTypes() {
+		for _, action := range widgetRegistry[widgetType].actions {
+			pattern := fmt.Sprintf("%s /api/%s/{widgetID}/%s", action.method, widgetType, action.name)
+			mux.HandleFunc(pattern, a.widgetActionHandler(widgetType, action))
+		}
+	}
+}
+
+func (a *application) widgetActionHandler(widgetType string, action widgetAction) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if a.handleUnauthorizedResponse(w, r, showUnauthorizedJSON) {
+			return
+		}
+
+		widgetID, err := strconv.ParseUint(r.PathValue("widgetID"), 10, 64)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Invalid widget ID"))
+			return
+		}
+
+		widget, exists := a.widgetByID[widgetID]
+		if !exists {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("Widget not found"))
+			return
+		}
+
+		// actions read and update the widget's data, which would otherwise race
+		// with the page updating it
+		page := a.pageByWidgetID[widgetID]
+		if !action.streams {
+			page.mu.Lock()
+			defer page.mu.Unlock()
+		}
+
+		if !action.handle(widget, &page.mu, w, r) {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Widget is not a " + widgetType + " widget"))
+		}
+	}
+}
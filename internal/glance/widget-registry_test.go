@@ -0,0 +1,63 @@
+package glance
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWidgetRegistryActionRoutes(t *testing.T) {
+	for _, widgetType := range []string{"markets", "stocks", "vikunja", "group"} {
+		if !slices.Contains(registeredWidgetTypes(), widgetType) {
+			t.Errorf("expected %s to be registered", widgetType)
+		}
+	}
+
+	clock, err := newWidget("clock")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testPage := &page{}
+	app := &application{
+		widgetByID:     map[uint64]widget{clock.GetID(): clock},
+		pageByWidgetID: map[uint64]*page{clock.GetID(): testPage},
+	}
+	mux := http.NewServeMux()
+	app.registerWidgetActionRoutes(mux)
+
+	request := func(path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, httptest.NewRequest("POST", path, strings.NewReader("{}")))
+		return recorder
+	}
+
+	if response := request("/api/cloudflare/999999/update"); response.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a widget that doesn't exist, got %d", response.Code)
+	}
+
+	id := strconv.FormatUint(clock.GetID(), 10)
+	if response := request("/api/cloudflare/" + id + "/update"); response.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a widget of a different type, got %d", response.Code)
+	}
+
+	// actions have to wait for the page to finish updating its widgets
+	testPage.mu.Lock()
+	codes := make(chan int, 1)
+	go func() { codes <- request("/api/cloudflare/" + id + "/update").Code }()
+
+	select {
+	case <-codes:
+		t.Error("expected the action to wait for the page lock")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	testPage.mu.Unlock()
+	if code := <-codes; code != http.StatusBadRequest {
+		t.Errorf("expected 400 once the page was unlocked, got %d", code)
+	}
+}
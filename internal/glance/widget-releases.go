@@ -17,6 +17,10 @@ import (
 
 var releasesWidgetTemplate = mustParseTemplate("releases.html", "widget-base.html")
 
+func init() {
+	registerWidget("releases", func() widget { return &releasesWidget{} })
+}
+
 type releasesWidget struct {
 	widgetBase     `yaml:",inline"`
 	Releases       appReleaseList    `yaml:"-"`
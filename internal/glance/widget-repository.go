@@ -12,6 +12,10 @@ import (
 
 var repositoryWidgetTemplate = mustParseTemplate("repository.html", "widget-base.html")
 
+func init() {
+	registerWidget("repository", func() widget { return &repositoryWidget{} })
+}
+
 type repositoryWidget struct {
 	widgetBase          `yaml:",inline"`
 	RequestedRepository string     `yaml:"repository"`
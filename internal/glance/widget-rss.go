@@ -28,6 +28,10 @@ var (
 
 var feedParser = gofeed.NewParser()
 
+func init() {
+	registerWidget("rss", func() widget { return &rssWidget{} })
+}
+
 type rssWidget struct {
 	widgetBase       `yaml:",inline"`
 	FeedRequests     []rssFeedRequest `yaml:"feeds"`
@@ -16,6 +16,10 @@ type SearchBang struct {
 	Icon     customIconField `yaml:"icon"`
 }
 
+func init() {
+	registerWidget("search", func() widget { return &searchWidget{} })
+}
+
 type searchWidget struct {
 	widgetBase       `yaml:",inline"`
 	cachedHTML       template.HTML `yaml:"-"`
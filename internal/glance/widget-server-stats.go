@@ -2,9 +2,12 @@ package glance
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"html/template"
 	"log/slog"
 	"net/http"
+	"os"
 	"strconv"
 	"strings"
 	"sync"
@@ -15,9 +18,18 @@ import (
 
 var serverStatsWidgetTemplate = mustParseTemplate("server-stats.html", "widget-base.html")
 
+func init() {
+	registerWidget("server-stats", func() widget { return &serverStatsWidget{} })
+}
+
 type serverStatsWidget struct {
 	widgetBase `yaml:",inline"`
 	Servers    []serverStatsRequest `yaml:"servers"`
+	History    *struct {
+		Interval    durationField `yaml:"interval"`
+		Length      int           `yaml:"length"`
+		PersistFile string        `yaml:"persist-file"`
+	} `yaml:"history"`
 }
 
 func (widget *serverStatsWidget) initialize() error {
@@ -31,14 +43,161 @@ func (widget *serverStatsWidget) initialize() error {
 	for i := range widget.Servers {
 		widget.Servers[i].URL = strings.TrimRight(widget.Servers[i].URL, "/")
 
+		if widget.Servers[i].Type == "local" && !widget.Servers[i].hasHostPaths() && isRunningInsideDockerContainer() {
+			slog.Warn("A server-stats widget is displaying the stats of the Glance container rather than the host, mount the host's root directory and set host-root to display them")
+		}
+
 		if widget.Servers[i].Timeout == 0 {
 			widget.Servers[i].Timeout = durationField(3 * time.Second)
 		}
 	}
 
+	if widget.History != nil {
+		if widget.History.Interval == 0 {
+			widget.History.Interval = durationField(time.Minute)
+		} else if widget.History.Interval < durationField(5*time.Second) {
+			return errors.New("history interval must be at least 5s")
+		}
+
+		if widget.History.Length == 0 {
+			widget.History.Length = 60
+		} else if widget.History.Length < 2 || widget.History.Length > 10_000 {
+			return errors.New("history length must be between 2 and 10000")
+		}
+
+		for i := range widget.Servers {
+			widget.Servers[i].History = newServerStatsHistory(widget.History.Length)
+		}
+
+		if widget.History.PersistFile != "" {
+			if err := widget.loadHistory(); err != nil {
+				slog.Warn("Loading server stats history", "file", widget.History.PersistFile, "error", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (widget *serverStatsWidget) startBackgroundTask() func() {
+	if widget.History == nil {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(time.Duration(widget.History.Interval))
+		defer ticker.Stop()
+
+		for {
+			widget.sampleHistory()
+
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
+
+func (widget *serverStatsWidget) sampleHistory() {
+	var wg sync.WaitGroup
+	now := time.Now()
+
+	for i := range widget.Servers {
+		serv := &widget.Servers[i]
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var info *sysinfo.SystemInfo
+			if serv.Type == "local" {
+				info, _ = sysinfo.Collect(serv.SystemInfoRequest)
+			} else {
+				var err error
+				if info, err = fetchRemoteServerInfo(serv); err != nil {
+					return
+				}
+			}
+
+			serv.History.add(now, info)
+		}()
+	}
+
+	wg.Wait()
+
+	if widget.History.PersistFile != "" {
+		if err := widget.saveHistory(); err != nil {
+			slog.Warn("Saving server stats history", "file", widget.History.PersistFile, "error", err)
+		}
+	}
+}
+
+// Servers are identified by their position and address so that samples of
+// servers that were moved or removed from the config aren't mixed up
+func (widget *serverStatsWidget) persistFile() string {
+	if widget.History == nil {
+		return ""
+	}
+
+	return widget.History.PersistFile
+}
+
+func (serv *serverStatsRequest) historyKey(index int) string {
+	return strconv.Itoa(index) + ":" + serv.Type + ":" + serv.URL
+}
+
+func (widget *serverStatsWidget) loadHistory() error {
+	contents, err := os.ReadFile(widget.History.PersistFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	var saved map[string][]serverStatsSample
+	if err := json.Unmarshal(contents, &saved); err != nil {
+		return err
+	}
+
+	// older samples would leave a gap in the charts that can't be told apart from the rest
+	oldestAllowed := time.Now().Add(-time.Duration(widget.History.Interval) * time.Duration(widget.History.Length))
+
+	for i := range widget.Servers {
+		for _, sample := range saved[widget.Servers[i].historyKey(i)] {
+			if sample.Time.After(oldestAllowed) {
+				widget.Servers[i].History.samples.push(sample)
+			}
+		}
+	}
+
 	return nil
 }
 
+func (widget *serverStatsWidget) saveHistory() error {
+	saved := make(map[string][]serverStatsSample, len(widget.Servers))
+	for i := range widget.Servers {
+		saved[widget.Servers[i].historyKey(i)] = widget.Servers[i].History.values()
+	}
+
+	encoded, err := json.Marshal(saved)
+	if err != nil {
+		return err
+	}
+
+	temp := widget.History.PersistFile + ".tmp"
+	if err := os.WriteFile(temp, encoded, 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(temp, widget.History.PersistFile)
+}
+
 func (widget *serverStatsWidget) update(context.Context) {
 	// Refactor later, most of it may change depending on feedback
 	var wg sync.WaitGroup
@@ -87,6 +246,7 @@ func (widget *serverStatsWidget) Render() template.HTML {
 type serverStatsRequest struct {
 	*sysinfo.SystemInfoRequest `yaml:",inline"`
 	Info                       *sysinfo.SystemInfo `yaml:"-"`
+	History                    *serverStatsHistory `yaml:"-"`
 	IsReachable                bool                `yaml:"-"`
 	StatusText                 string              `yaml:"-"`
 	Name                       string              `yaml:"name"`
@@ -99,6 +259,11 @@ type serverStatsRequest struct {
 	// Provider                   string              `yaml:"provider"`
 }
 
+func (serv *serverStatsRequest) hasHostPaths() bool {
+	req := serv.SystemInfoRequest
+	return req != nil && (req.HostRoot != "" || req.HostProc != "" || req.HostSys != "")
+}
+
 func fetchRemoteServerInfo(infoReq *serverStatsRequest) (*sysinfo.SystemInfo, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(infoReq.Timeout))
 	defer cancel()
@@ -115,3 +280,66 @@ func fetchRemoteServerInfo(infoReq *serverStatsRequest) (*sysinfo.SystemInfo, er
 
 	return info, nil
 }
+
+type serverStatsSample struct {
+	Time time.Time `json:"time"`
+	// the 1 minute load average, which is what the widget shows as CPU usage
+	LoadPercent        uint8  `json:"load"`
+	MemoryPercent      uint8  `json:"memory"`
+	NetworkBytesPerSec uint64 `json:"network"`
+}
+
+// Samples of a single server, written by the background task and read when rendering
+type serverStatsHistory struct {
+	mu      sync.Mutex
+	samples *ringBuffer[serverStatsSample]
+}
+
+func newServerStatsHistory(length int) *serverStatsHistory {
+	return &serverStatsHistory{samples: newRingBuffer[serverStatsSample](length)}
+}
+
+func (h *serverStatsHistory) add(now time.Time, info *sysinfo.SystemInfo) {
+	sample := serverStatsSample{
+		Time:               now,
+		LoadPercent:        info.CPU.Load1Percent,
+		MemoryPercent:      info.Memory.UsedPercent,
+		NetworkBytesPerSec: info.Network.TotalRxBytesPerSec() + info.Network.TotalTxBytesPerSec(),
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.samples.push(sample)
+}
+
+func (h *serverStatsHistory) values() []serverStatsSample {
+	if h == nil {
+		return nil
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.samples.values()
+}
+
+func (h *serverStatsHistory) chartPoints(valueOf func(serverStatsSample) float64) string {
+	samples := h.values()
+	values := make([]float64, len(samples))
+	for i := range samples {
+		values[i] = valueOf(samples[i])
+	}
+
+	return svgPolylineCoordsFromYValues(100, 50, values)
+}
+
+func (h *serverStatsHistory) LoadChartPoints() string {
+	return h.chartPoints(func(s serverStatsSample) float64 { return float64(s.LoadPercent) })
+}
+
+func (h *serverStatsHistory) MemoryChartPoints() string {
+	return h.chartPoints(func(s serverStatsSample) float64 { return float64(s.MemoryPercent) })
+}
+
+func (h *serverStatsHistory) NetworkChartPoints() string {
+	return h.chartPoints(func(s serverStatsSample) float64 { return float64(s.NetworkBytesPerSec) })
+}
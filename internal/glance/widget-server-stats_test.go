@@ -0,0 +1,63 @@
+package glance
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/glanceapp/glance/pkg/sysinfo"
+)
+
+func TestRingBufferKeepsNewestItems(t *testing.T) {
+	buffer := newRingBuffer[int](3)
+
+	for i := 1; i <= 5; i++ {
+		buffer.push(i)
+	}
+
+	if values := buffer.values(); !slices.Equal(values, []int{3, 4, 5}) {
+		t.Errorf("expected [3 4 5], got %v", values)
+	}
+}
+
+func TestServerStatsHistoryPersistsAcrossRestarts(t *testing.T) {
+	persistFile := filepath.Join(t.TempDir(), "history.json")
+
+	newWidget := func() *serverStatsWidget {
+		widget := &serverStatsWidget{Servers: []serverStatsRequest{{Type: "local"}}}
+		widget.History = &struct {
+			Interval    durationField `yaml:"interval"`
+			Length      int           `yaml:"length"`
+			PersistFile string        `yaml:"persist-file"`
+		}{Length: 3, PersistFile: persistFile}
+
+		if err := widget.initialize(); err != nil {
+			t.Fatal(err)
+		}
+
+		return widget
+	}
+
+	widget := newWidget()
+	now := time.Now()
+
+	for i := range 4 {
+		info := &sysinfo.SystemInfo{}
+		info.CPU.Load1Percent = uint8(10 * (i + 1))
+		widget.Servers[0].History.add(now.Add(time.Duration(i-4)*time.Second), info)
+	}
+
+	if points := widget.Servers[0].History.LoadChartPoints(); points == "" {
+		t.Error("expected chart points to be generated")
+	}
+
+	if err := widget.saveHistory(); err != nil {
+		t.Fatal(err)
+	}
+
+	samples := newWidget().Servers[0].History.values()
+	if len(samples) != 3 || samples[0].LoadPercent != 20 || samples[2].LoadPercent != 40 {
+		t.Errorf("expected the last 3 samples to be restored, got %+v", samples)
+	}
+}
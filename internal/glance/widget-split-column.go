@@ -8,6 +8,10 @@ import (
 
 var splitColumnWidgetTemplate = mustParseTemplate("split-column.html", "widget-base.html")
 
+func init() {
+	registerWidget("split-column", func() widget { return &splitColumnWidget{} })
+}
+
 type splitColumnWidget struct {
 	widgetBase          `yaml:",inline"`
 	containerWidgetBase `yaml:",inline"`
@@ -37,6 +41,10 @@ func (widget *splitColumnWidget) setProviders(providers *widgetProviders) {
 }
 
 func (widget *splitColumnWidget) requiresUpdate(now *time.Time) bool {
+	if !widget.isVisibleAt(*now) {
+		return false
+	}
+
 	return widget.containerWidgetBase._requiresUpdate(now)
 }
 
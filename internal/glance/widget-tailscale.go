@@ -12,6 +12,10 @@ import (
 
 var tailscaleWidgetTemplate = mustParseTemplate("tailscale.html", "widget-base.html")
 
+func init() {
+	registerWidget("tailscale", func() widget { return &tailscaleWidget{} })
+}
+
 type tailscaleWidget struct {
 	widgetBase           `yaml:",inline"`
 	URL                  string `yaml:"url"`
@@ -136,6 +140,8 @@ func (widget *tailscaleWidget) update(ctx context.Context) {
 	widget.OfflineDevices = make([]tailscaleDevice, 0)
 
 	for _, device := range devices {
+		widget.emitAlertEvent(device.Name, !device.IsOnline, fmt.Sprintf("%s (%s) last seen %s", device.Name, device.PrimaryAddress, device.LastSeenStr))
+
 		if device.IsOnline {
 			widget.OnlineDevices = append(widget.OnlineDevices, device)
 		} else if !widget.HideOffline {
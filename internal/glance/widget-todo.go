@@ -6,6 +6,10 @@ import (
 
 var todoWidgetTemplate = mustParseTemplate("todo.html", "widget-base.html")
 
+func init() {
+	registerWidget("to-do", func() widget { return &todoWidget{} })
+}
+
 type todoWidget struct {
 	widgetBase `yaml:",inline"`
 	cachedHTML template.HTML `yaml:"-"`
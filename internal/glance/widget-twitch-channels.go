@@ -14,6 +14,10 @@ import (
 
 var twitchChannelsWidgetTemplate = mustParseTemplate("twitch-channels.html", "widget-base.html")
 
+func init() {
+	registerWidget("twitch-channels", func() widget { return &twitchChannelsWidget{} })
+}
+
 type twitchChannelsWidget struct {
 	widgetBase      `yaml:",inline"`
 	ChannelsRequest []string        `yaml:"channels"`
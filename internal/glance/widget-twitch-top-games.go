@@ -13,6 +13,10 @@ import (
 
 var twitchGamesWidgetTemplate = mustParseTemplate("twitch-games-list.html", "widget-base.html")
 
+func init() {
+	registerWidget("twitch-top-games", func() widget { return &twitchGamesWidget{} })
+}
+
 type twitchGamesWidget struct {
 	widgetBase    `yaml:",inline"`
 	Categories    []twitchCategory `yaml:"-"`
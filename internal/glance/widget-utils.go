@@ -47,45 +47,45 @@ func (t *debugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 	resp, err := t.underlying.RoundTrip(req)
 	duration := time.Since(start)
 
-	if debugAPITiming && err == nil {
+	if debugAPITiming {
 		seq := atomic.AddInt32(&debugAPICounter, 1)
-		log.Printf("[API #%d] %s %s - %d - %dms", seq, req.Method, req.URL.String(), resp.StatusCode, duration.Milliseconds())
+		if err == nil {
+			log.Printf("[API #%d] %s %s - %d - %dms", seq, req.Method, req.URL.String(), resp.StatusCode, duration.Milliseconds())
+		} else {
+			log.Printf("[API #%d] %s %s - error: %v - %dms", seq, req.Method, req.URL.String(), err, duration.Milliseconds())
+		}
 	}
 
 	return resp, err
 }
 
 var defaultHTTPClient = &http.Client{
-	Transport: &debugTransport{
-		underlying: &userAgentTransport{
-			underlying: &http.Transport{
-				MaxIdleConns:        maxIdleConns,
-				MaxIdleConnsPerHost: maxIdleConnsPerHost,
-				MaxConnsPerHost:     maxOpenConnsPerHost,
-				IdleConnTimeout:     idleConnTimeout,
-				Proxy:               http.ProxyFromEnvironment,
-				DisableKeepAlives:   false,
-			},
+	Transport: newUpstreamTransport(&userAgentTransport{
+		underlying: &http.Transport{
+			MaxIdleConns:        maxIdleConns,
+			MaxIdleConnsPerHost: maxIdleConnsPerHost,
+			MaxConnsPerHost:     maxOpenConnsPerHost,
+			IdleConnTimeout:     idleConnTimeout,
+			Proxy:               http.ProxyFromEnvironment,
+			DisableKeepAlives:   false,
 		},
-	},
+	}),
 	Timeout: defaultClientTimeout,
 }
 
 var defaultInsecureHTTPClient = &http.Client{
 	Timeout: defaultClientTimeout,
-	Transport: &debugTransport{
-		underlying: &userAgentTransport{
-			underlying: &http.Transport{
-				MaxIdleConns:        maxIdleConns,
-				MaxIdleConnsPerHost: maxIdleConnsPerHost,
-				MaxConnsPerHost:     maxOpenConnsPerHost,
-				IdleConnTimeout:     idleConnTimeout,
-				TLSClientConfig:     &tls.Config{InsecureSkipVerify: true},
-				Proxy:               http.ProxyFromEnvironment,
-				DisableKeepAlives:   false,
-			},
+	Transport: newUpstreamTransport(&userAgentTransport{
+		underlying: &http.Transport{
+			MaxIdleConns:        maxIdleConns,
+			MaxIdleConnsPerHost: maxIdleConnsPerHost,
+			MaxConnsPerHost:     maxOpenConnsPerHost,
+			IdleConnTimeout:     idleConnTimeout,
+			TLSClientConfig:     &tls.Config{InsecureSkipVerify: true},
+			Proxy:               http.ProxyFromEnvironment,
+			DisableKeepAlives:   false,
 		},
-	},
+	}),
 }
 
 type requestDoer interface {
@@ -20,6 +20,10 @@ var (
 	videosWidgetVerticalListTemplate = mustParseTemplate("videos-vertical-list.html", "widget-base.html")
 )
 
+func init() {
+	registerWidget("videos", func() widget { return &videosWidget{} })
+}
+
 type videosWidget struct {
 	widgetBase        `yaml:",inline"`
 	Videos            videoList `yaml:"-"`
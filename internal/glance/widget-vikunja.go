@@ -26,6 +26,17 @@ func init() {
 	if err != nil {
 		vikunjaDefaultLocation = time.UTC
 	}
+
+	registerWidget("vikunja", func() widget { return &vikunjaWidget{} },
+		newWidgetAction("POST", "complete-task", (*vikunjaWidget).handleCompleteTaskRequest),
+		newWidgetAction("POST", "update-task", (*vikunjaWidget).handleUpdateTaskRequest),
+		newWidgetAction("POST", "add-label", (*vikunjaWidget).handleAddLabelRequest),
+		newWidgetAction("POST", "remove-label", (*vikunjaWidget).handleRemoveLabelRequest),
+		newWidgetAction("GET", "labels", (*vikunjaWidget).handleLabelsRequest),
+		newWidgetAction("GET", "projects", (*vikunjaWidget).handleProjectsRequest),
+		newWidgetAction("GET", "refresh", (*vikunjaWidget).handleRefreshRequest),
+		newWidgetAction("POST", "create-task", (*vikunjaWidget).handleCreateTaskRequest),
+	)
 }
 
 type vikunjaWidget struct {
@@ -924,3 +935,168 @@ func (widget *vikunjaWidget) fetchAffineNoteTitleWithToken(affineNoteURL string,
 
 	return graphQLResp.Data.Workspace.Doc.Title, nil
 }
+
+func (widget *vikunjaWidget) handleCompleteTaskRequest(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		TaskID int `json:"task_id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid request body"))
+		return
+	}
+
+	if err := widget.completeTask(request.TaskID); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"success": true}`))
+}
+
+func (widget *vikunjaWidget) handleUpdateTaskRequest(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		TaskID          int    `json:"task_id"`
+		Title           string `json:"title"`
+		DueDate         string `json:"due_date"`
+		AffineNoteURL   string `json:"affine_note_url"`
+		CustomLinkURL   string `json:"custom_link_url"`
+		CustomLinkTitle string `json:"custom_link_title"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid request body"))
+		return
+	}
+
+	if err := widget.updateTaskBasic(request.TaskID, request.Title, request.DueDate, request.AffineNoteURL, request.CustomLinkURL, request.CustomLinkTitle); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"success": true}`))
+}
+
+func (widget *vikunjaWidget) handleAddLabelRequest(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		TaskID  int `json:"task_id"`
+		LabelID int `json:"label_id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid request body"))
+		return
+	}
+
+	if err := widget.addLabelToTask(request.TaskID, request.LabelID); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"success": true}`))
+}
+
+func (widget *vikunjaWidget) handleRemoveLabelRequest(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		TaskID  int `json:"task_id"`
+		LabelID int `json:"label_id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid request body"))
+		return
+	}
+
+	if err := widget.removeLabelFromTask(request.TaskID, request.LabelID); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"success": true}`))
+}
+
+func (widget *vikunjaWidget) handleLabelsRequest(w http.ResponseWriter, r *http.Request) {
+	labels, err := widget.fetchAllLabels()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(labels)
+}
+
+func (widget *vikunjaWidget) handleProjectsRequest(w http.ResponseWriter, r *http.Request) {
+	projects, err := widget.fetchProjects()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(projects)
+}
+
+func (widget *vikunjaWidget) handleRefreshRequest(w http.ResponseWriter, r *http.Request) {
+	// Force a refresh of the widget data
+	updateWidget(context.Background(), widget)
+
+	// Render the widget HTML
+	html := widget.Render()
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(html))
+}
+
+func (widget *vikunjaWidget) handleCreateTaskRequest(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Title           string `json:"title"`
+		DueDate         string `json:"due_date"`
+		LabelIDs        []int  `json:"label_ids"`
+		ProjectID       int    `json:"project_id"`
+		AffineNoteURL   string `json:"affine_note_url"`
+		CustomLinkURL   string `json:"custom_link_url"`
+		CustomLinkTitle string `json:"custom_link_title"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid request body"))
+		return
+	}
+
+	task, err := widget.createTask(request.Title, request.DueDate, request.LabelIDs, request.ProjectID, request.AffineNoteURL, request.CustomLinkURL, request.CustomLinkTitle)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(fmt.Sprintf("Failed to create task: %v", err)))
+		return
+	}
+
+	if task == nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Task was created but response was empty"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(task); err != nil {
+		// Log encoding error but response is already sent
+		fmt.Printf("Error encoding task response: %v\n", err)
+	}
+}
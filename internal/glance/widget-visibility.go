@@ -0,0 +1,309 @@
+package glance
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tidwall/gjson"
+	"gopkg.in/yaml.v3"
+)
+
+// Restricts when a widget is displayed and updated, all of the specified rules must match
+type widgetVisibility struct {
+	Days      []string `yaml:"days"`
+	Time      string   `yaml:"time"`
+	Timezone  string   `yaml:"timezone"`
+	Condition string   `yaml:"condition"`
+
+	days      [7]bool
+	hasDays   bool
+	hasTime   bool
+	start     int // minutes since midnight
+	end       int
+	location  *time.Location
+	condition *visibilityCondition
+}
+
+func (v *widgetVisibility) UnmarshalYAML(node *yaml.Node) error {
+	type alias widgetVisibility
+	if err := node.Decode((*alias)(v)); err != nil {
+		return err
+	}
+
+	if err := v.parse(); err != nil {
+		return fmt.Errorf("line %d: show-when: %v", node.Line, err)
+	}
+
+	return nil
+}
+
+var visibilityDays = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+func parseVisibilityDay(name string) (time.Weekday, error) {
+	day, ok := visibilityDays[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return 0, fmt.Errorf("unknown day %q", name)
+	}
+
+	return day, nil
+}
+
+var visibilityTimeRangePattern = regexp.MustCompile(`^(\d{1,2}):(\d{2})\s*-\s*(\d{1,2}):(\d{2})$`)
+
+func (v *widgetVisibility) parse() error {
+	for _, entry := range v.Days {
+		// ranges such as mon-fri wrap around the end of the week
+		from, to, isRange := strings.Cut(entry, "-")
+
+		start, err := parseVisibilityDay(from)
+		if err != nil {
+			return err
+		}
+
+		end := start
+		if isRange {
+			if end, err = parseVisibilityDay(to); err != nil {
+				return err
+			}
+		}
+
+		for day := start; ; day = (day + 1) % 7 {
+			v.days[day] = true
+			if day == end {
+				break
+			}
+		}
+
+		v.hasDays = true
+	}
+
+	if v.Time != "" {
+		matches := visibilityTimeRangePattern.FindStringSubmatch(strings.TrimSpace(v.Time))
+		if matches == nil {
+			return fmt.Errorf("invalid time %q, expected a range such as 08:00-18:00", v.Time)
+		}
+
+		minutesOf := func(hours, minutes string) (int, error) {
+			h, _ := strconv.Atoi(hours)
+			m, _ := strconv.Atoi(minutes)
+			if h > 24 || m > 59 || (h == 24 && m != 0) {
+				return 0, fmt.Errorf("invalid time %q", v.Time)
+			}
+			return h*60 + m, nil
+		}
+
+		var err error
+		if v.start, err = minutesOf(matches[1], matches[2]); err != nil {
+			return err
+		}
+		if v.end, err = minutesOf(matches[3], matches[4]); err != nil {
+			return err
+		}
+
+		v.hasTime = true
+	}
+
+	v.location = time.Local
+	if v.Timezone != "" {
+		location, err := time.LoadLocation(v.Timezone)
+		if err != nil {
+			return fmt.Errorf("invalid timezone %q: %v", v.Timezone, err)
+		}
+		v.location = location
+	}
+
+	if v.Condition != "" {
+		condition, err := parseVisibilityCondition(v.Condition)
+		if err != nil {
+			return fmt.Errorf("invalid condition %q: %v", v.Condition, err)
+		}
+		v.condition = condition
+	}
+
+	return nil
+}
+
+// The title of the widget whose data the condition reads, if any
+func (v *widgetVisibility) referencedWidgetTitle() (string, bool) {
+	if v == nil || v.condition == nil || v.condition.source != "widget" || v.condition.path == "" {
+		return "", false
+	}
+
+	return v.condition.argument, true
+}
+
+func (v *widgetVisibility) matches(now time.Time, providers *widgetProviders) bool {
+	now = now.In(v.location)
+
+	if v.hasDays && !v.days[now.Weekday()] {
+		return false
+	}
+
+	if v.hasTime {
+		minutes := now.Hour()*60 + now.Minute()
+
+		if v.start <= v.end {
+			if minutes < v.start || minutes >= v.end {
+				return false
+			}
+		} else if minutes < v.start && minutes >= v.end {
+			// the range spans midnight, such as 22:00-06:00
+			return false
+		}
+	}
+
+	if v.condition != nil && !v.condition.evaluate(providers) {
+		return false
+	}
+
+	return true
+}
+
+// A comparison such as env("MODE") == "work" or widget("Weather").Weather.Code >= 61,
+// when the operator is omitted the value only has to be truthy
+type visibilityCondition struct {
+	source   string // env or widget
+	argument string
+	path     string
+	operator string
+	value    string
+}
+
+var visibilityConditionPattern = regexp.MustCompile(
+	`^(env|widget)\("((?:[^"\\]|\\.)*)"\)((?:\.[^\s=!<>]+)?)\s*(?:(==|!=|>=|<=|>|<|contains)\s*(.+))?$`,
+)
+
+func parseVisibilityCondition(expression string) (*visibilityCondition, error) {
+	matches := visibilityConditionPattern.FindStringSubmatch(strings.TrimSpace(expression))
+	if matches == nil {
+		return nil, errors.New(`expected env("NAME") or widget("Title").path, optionally followed by an operator and a value`)
+	}
+
+	condition := &visibilityCondition{
+		source:   matches[1],
+		argument: matches[2],
+		path:     strings.TrimPrefix(matches[3], "."),
+		operator: matches[4],
+	}
+
+	if condition.source == "env" && condition.path != "" {
+		return nil, errors.New("env values don't have properties")
+	}
+
+	if condition.operator != "" {
+		value := strings.TrimSpace(matches[5])
+		if strings.HasPrefix(value, `"`) {
+			unquoted, err := strconv.Unquote(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid string %s", value)
+			}
+			value = unquoted
+		} else if _, err := strconv.ParseFloat(value, 64); err != nil && value != "true" && value != "false" {
+			return nil, fmt.Errorf("value %s must be a quoted string, a number or a boolean", value)
+		}
+		condition.value = value
+	}
+
+	return condition, nil
+}
+
+func (c *visibilityCondition) resolve(providers *widgetProviders) (string, bool) {
+	if c.source == "env" {
+		return os.LookupEnv(c.argument)
+	}
+
+	if providers == nil || providers.widgetByTitle == nil {
+		return "", false
+	}
+
+	w := providers.widgetByTitle(c.argument)
+	if w == nil {
+		return "", false
+	}
+
+	if c.path == "" {
+		return "true", true
+	}
+
+	// the widget itself may be in the middle of an update, so the data is
+	// read from what it looked like after the previous one
+	snapshot := w.getSnapshot()
+	if snapshot == nil {
+		return "", false
+	}
+
+	result := gjson.GetBytes(snapshot, c.path)
+	if !result.Exists() || result.Type == gjson.Null {
+		return "", false
+	}
+
+	return result.String(), true
+}
+
+func (c *visibilityCondition) evaluate(providers *widgetProviders) bool {
+	value, exists := c.resolve(providers)
+
+	switch c.operator {
+	case "":
+		return exists && value != "" && value != "false" && value != "0"
+	case "==":
+		return exists && compareVisibilityValues(value, c.value) == 0
+	case "!=":
+		return !exists || compareVisibilityValues(value, c.value) != 0
+	case "contains":
+		return exists && strings.Contains(value, c.value)
+	}
+
+	if !exists {
+		return false
+	}
+
+	a, errA := strconv.ParseFloat(value, 64)
+	b, errB := strconv.ParseFloat(c.value, 64)
+	if errA != nil || errB != nil {
+		return false
+	}
+
+	switch c.operator {
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	}
+
+	return false
+}
+
+// Numbers are compared by value so that 1 and 1.0 are equal
+func compareVisibilityValues(a, b string) int {
+	if x, err := strconv.ParseFloat(a, 64); err == nil {
+		if y, err := strconv.ParseFloat(b, 64); err == nil {
+			switch {
+			case x < y:
+				return -1
+			case x > y:
+				return 1
+			}
+			return 0
+		}
+	}
+
+	return strings.Compare(a, b)
+}
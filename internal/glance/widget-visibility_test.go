@@ -0,0 +1,147 @@
+package glance
+
+import (
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func parseTestVisibility(t *testing.T, config string) *widgetVisibility {
+	t.Helper()
+
+	var visibility widgetVisibility
+	if err := yaml.Unmarshal([]byte(config), &visibility); err != nil {
+		t.Fatal(err)
+	}
+
+	return &visibility
+}
+
+func TestWidgetVisibilitySchedule(t *testing.T) {
+	visibility := parseTestVisibility(t, `
+days: [mon-fri]
+time: 22:00-06:00
+timezone: UTC
+`)
+
+	tests := []struct {
+		time    string
+		visible bool
+	}{
+		{"2026-10-19T23:30:00Z", true},  // monday night
+		{"2026-10-20T05:59:00Z", true},  // tuesday morning
+		{"2026-10-20T06:00:00Z", false}, // end is exclusive
+		{"2026-10-20T12:00:00Z", false},
+		{"2026-10-18T23:30:00Z", false}, // sunday
+	}
+
+	for _, test := range tests {
+		now, _ := time.Parse(time.RFC3339, test.time)
+		if visible := visibility.matches(now, nil); visible != test.visible {
+			t.Errorf("at %s expected visible to be %v", test.time, test.visible)
+		}
+	}
+
+	weekend := parseTestVisibility(t, `days: [sat-sun]`)
+	saturday, _ := time.Parse(time.RFC3339, "2026-10-17T12:00:00Z")
+	if !weekend.matches(saturday, nil) {
+		t.Error("expected sat-sun to include saturday")
+	}
+}
+
+func TestWidgetVisibilityConditions(t *testing.T) {
+	t.Setenv("GLANCE_TEST_MODE", "work")
+	t.Setenv("GLANCE_TEST_COUNT", "3")
+
+	weather := &weatherWidget{Weather: &weather{Temperature: 2}}
+	weather.keepSnapshots(weather)
+	// conditions read the data from after the last update, not a widget that's mid-update
+	weather.Weather.Temperature = 10
+	providers := &widgetProviders{
+		widgetByTitle: func(title string) widget {
+			if title == "Weather" {
+				return weather
+			}
+			return nil
+		},
+	}
+
+	tests := []struct {
+		condition string
+		visible   bool
+	}{
+		{`env("GLANCE_TEST_MODE") == "work"`, true},
+		{`env("GLANCE_TEST_MODE") != "work"`, false},
+		{`env("GLANCE_TEST_MODE") contains "or"`, true},
+		{`env("GLANCE_TEST_COUNT") >= 3`, true},
+		{`env("GLANCE_TEST_COUNT") == 3.0`, true},
+		{`env("GLANCE_TEST_MISSING")`, false},
+		{`widget("Weather").Weather.Temperature < 5`, true},
+		{`widget("Weather").Weather.Temperature > 5`, false},
+		{`widget("Missing").Weather.Temperature < 5`, false},
+	}
+
+	for _, test := range tests {
+		visibility := &widgetVisibility{Condition: test.condition}
+		if err := visibility.parse(); err != nil {
+			t.Fatalf("parsing %s: %v", test.condition, err)
+		}
+
+		if visible := visibility.matches(time.Now(), providers); visible != test.visible {
+			t.Errorf("expected %s to be %v", test.condition, test.visible)
+		}
+	}
+
+	for _, invalid := range []string{`days: [someday]`, `time: 8am-6pm`, `condition: env(MODE)`, `condition: env("MODE") == work`} {
+		var visibility widgetVisibility
+		if err := yaml.Unmarshal([]byte(invalid), &visibility); err == nil {
+			t.Errorf("expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestOnlyWidgetsReferencedByConditionsKeepSnapshots(t *testing.T) {
+	config, err := newConfigFromYAML([]byte(`
+pages:
+  - name: Home
+    columns:
+      - size: full
+        widgets:
+          - type: html
+            title: Status
+            source: <p>ok</p>
+            show-when:
+              condition: widget("Tracked").Title == "Tracked"
+          - type: group
+            widgets:
+              - type: html
+                title: Tracked
+                source: <p>tracked</p>
+              - type: html
+                title: Other
+                source: <p>other</p>
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app, err := newApplication(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	byTitle := make(map[string]widget)
+	for _, w := range app.widgetByID {
+		title := w.(interface{ getTitle() string }).getTitle()
+		byTitle[title] = w
+
+		if hasSnapshot := w.getSnapshot() != nil; hasSnapshot != (title == "Tracked") {
+			t.Errorf("expected %s to have a snapshot: %v", title, title == "Tracked")
+		}
+	}
+
+	if !byTitle["Status"].IsVisible() {
+		t.Error("expected the condition to read the snapshot of the referenced widget")
+	}
+}
@@ -18,6 +18,10 @@ import (
 
 var weatherWidgetTemplate = mustParseTemplate("weather.html", "widget-base.html")
 
+func init() {
+	registerWidget("weather", func() widget { return &weatherWidget{} })
+}
+
 type weatherWidget struct {
 	widgetBase   `yaml:",inline"`
 	Location     string                      `yaml:"location"`
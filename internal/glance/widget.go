@@ -3,12 +3,14 @@ package glance
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"html/template"
 	"log/slog"
 	"math"
 	"net/http"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -22,87 +24,12 @@ func newWidget(widgetType string) (widget, error) {
 		return nil, errors.New("widget 'type' property is empty or not specified")
 	}
 
-	var w widget
-
-	switch widgetType {
-	case "calendar":
-		w = &calendarWidget{}
-	case "calendar-legacy":
-		w = &oldCalendarWidget{}
-	case "clock":
-		w = &clockWidget{}
-	case "weather":
-		w = &weatherWidget{}
-	case "bookmarks":
-		w = &bookmarksWidget{}
-	case "iframe":
-		w = &iframeWidget{}
-	case "html":
-		w = &htmlWidget{}
-	case "hacker-news":
-		w = &hackerNewsWidget{}
-	case "releases":
-		w = &releasesWidget{}
-	case "videos":
-		w = &videosWidget{}
-	case "markets", "stocks":
-		w = &marketsWidget{}
-	case "reddit":
-		w = &redditWidget{}
-	case "rss":
-		w = &rssWidget{}
-	case "monitor":
-		w = &monitorWidget{}
-	case "twitch-top-games":
-		w = &twitchGamesWidget{}
-	case "twitch-channels":
-		w = &twitchChannelsWidget{}
-	case "lobsters":
-		w = &lobstersWidget{}
-	case "change-detection":
-		w = &changeDetectionWidget{}
-	case "repository":
-		w = &repositoryWidget{}
-	case "github":
-		w = &githubWidget{}
-	case "search":
-		w = &searchWidget{}
-	case "extension":
-		w = &extensionWidget{}
-	case "group":
-		w = &groupWidget{}
-	case "dns-stats":
-		w = &dnsStatsWidget{}
-	case "split-column":
-		w = &splitColumnWidget{}
-	case "custom-api":
-		w = &customAPIWidget{}
-	case "docker-containers":
-		w = &dockerContainersWidget{}
-	case "server-stats":
-		w = &serverStatsWidget{}
-	case "to-do":
-		w = &todoWidget{}
-	case "radyjko":
-		w = &radyjkoWidget{}
-	case "vikunja":
-		w = &vikunjaWidget{}
-	case "tailscale":
-		w = &tailscaleWidget{}
-	case "beszel":
-		w = &beszelWidget{}
-	case "cloudflare":
-		w = &cloudflareWidget{}
-	case "google-compute":
-		w = &googleComputeWidget{}
-	case "navidrome":
-		w = &navidromeWidget{}
-	case "qbittorrent":
-		w = &qbittorrentWidget{}
-	default:
+	definition, exists := widgetRegistry[widgetType]
+	if !exists {
 		return nil, fmt.Errorf("unknown widget type: %s", widgetType)
 	}
 
+	w := definition.newWidget()
 	w.setID(widgetIDCounter.Add(1))
 
 	return w, nil
@@ -117,47 +44,92 @@ func (w *widgets) UnmarshalYAML(node *yaml.Node) error {
 		return err
 	}
 
+	// errors are collected as a TypeError so that the decoder reports
+	// the problems with every widget rather than stopping at the first one
+	typeErr := &yaml.TypeError{}
+
 	for _, node := range nodes {
 		meta := struct {
 			Type string `yaml:"type"`
 		}{}
 
 		if err := node.Decode(&meta); err != nil {
-			return err
+			appendWidgetDecodeError(typeErr, node.Line, err)
+			continue
 		}
 
 		widget, err := newWidget(meta.Type)
 		if err != nil {
-			return fmt.Errorf("line %d: %w", node.Line, err)
+			typeErr.Errors = append(typeErr.Errors, fmt.Sprintf("line %d: %v", node.Line, err))
+			continue
 		}
 
+		widget.setSourceLine(node.Line)
+
 		if err = node.Decode(widget); err != nil {
-			return err
+			appendWidgetDecodeError(typeErr, node.Line, err)
+			continue
 		}
 
 		*w = append(*w, widget)
 	}
 
+	if len(typeErr.Errors) > 0 {
+		return typeErr
+	}
+
 	return nil
 }
 
+func appendWidgetDecodeError(typeErr *yaml.TypeError, line int, err error) {
+	var nested *yaml.TypeError
+	if errors.As(err, &nested) {
+		typeErr.Errors = append(typeErr.Errors, nested.Errors...)
+		return
+	}
+
+	typeErr.Errors = append(typeErr.Errors, fmt.Sprintf("line %d: %v", line, err))
+}
+
 type widget interface {
 	// These need to be exported because they get called in templates
 	Render() template.HTML
 	GetType() string
 	GetID() uint64
+	IsVisible() bool
 
 	initialize() error
 	requiresUpdate(*time.Time) bool
 	setProviders(*widgetProviders)
 	update(context.Context)
 	setID(uint64)
+	setSourceLine(int)
+	getSourceLine() int
 	handleRequest(w http.ResponseWriter, r *http.Request)
+	keepSnapshots(widget)
+	storeSnapshot(widget)
+	getSnapshot() []byte
+	getShowWhen() *widgetVisibility
+	beginAlertEvents()
+	finishAlertEvents()
 	setHideHeader(bool)
 	setUpdating(bool)
+	startUpdating() bool
 	CheckIsUpdating() bool
 }
 
+// Implemented by widgets which need to do work regardless of whether anyone is viewing
+// the page, the returned function gets called when the config is reloaded
+type widgetWithBackgroundTask interface {
+	startBackgroundTask() (stop func())
+}
+
+// Implemented by widgets which get updated according to their cache duration
+// even when nobody is viewing their page
+type widgetWithScheduledUpdates interface {
+	updatesOnSchedule()
+}
+
 type cacheType int
 
 const (
@@ -167,32 +139,47 @@ const (
 )
 
 type widgetBase struct {
-	ID                  uint64           `yaml:"-"`
-	Providers           *widgetProviders `yaml:"-"`
-	Type                string           `yaml:"type"`
-	Title               string           `yaml:"title"`
-	TitleURL            string           `yaml:"title-url"`
-	HideHeader          bool             `yaml:"hide-header"`
-	CSSClass            string           `yaml:"css-class"`
-	CustomCacheDuration durationField    `yaml:"cache"`
-	ContentAvailable    bool             `yaml:"-"`
-	WIP                 bool             `yaml:"-"`
-	Error               error            `yaml:"-"`
-	Notice              error            `yaml:"-"`
-	templateBuffer      bytes.Buffer     `yaml:"-"`
-	cacheDuration       time.Duration    `yaml:"-"`
-	cacheType           cacheType        `yaml:"-"`
-	nextUpdate          time.Time        `yaml:"-"`
-	updateRetriedTimes  int              `yaml:"-"`
-	IsUpdating          bool             `yaml:"-"`
+	ID                  uint64            `yaml:"-"`
+	Providers           *widgetProviders  `yaml:"-"`
+	Type                string            `yaml:"type"`
+	Title               string            `yaml:"title"`
+	TitleURL            string            `yaml:"title-url"`
+	HideHeader          bool              `yaml:"hide-header"`
+	CSSClass            string            `yaml:"css-class"`
+	CustomCacheDuration durationField     `yaml:"cache"`
+	ShowWhen            *widgetVisibility `yaml:"show-when"`
+	ContentAvailable    bool              `yaml:"-"`
+	WIP                 bool              `yaml:"-"`
+	Error               error             `yaml:"-"`
+	Notice              error             `yaml:"-"`
+	templateBuffer      bytes.Buffer      `yaml:"-"`
+	cacheDuration       time.Duration     `yaml:"-"`
+	cacheType           cacheType         `yaml:"-"`
+	nextUpdate          time.Time         `yaml:"-"`
+	updateRetriedTimes  int               `yaml:"-"`
+	updating            atomic.Bool       `yaml:"-"`
+	sourceLine          int               `yaml:"-"`
+	// the widget encoded as JSON after its last update, only kept for widgets
+	// referenced by the show-when conditions of other widgets
+	snapshot      []byte       `yaml:"-"`
+	snapshotMu    sync.RWMutex `yaml:"-"`
+	keepsSnapshot bool         `yaml:"-"`
+	// subjects reported to alerts during the current update
+	alertSubjects map[string]bool `yaml:"-"`
 }
 
 type widgetProviders struct {
 	assetResolver     func(string) string
 	userAssetResolver func(string) string
+	widgetByTitle     func(string) widget
+	alerts            *alertDispatcher
 }
 
 func (w *widgetBase) requiresUpdate(now *time.Time) bool {
+	if !w.isVisibleAt(*now) {
+		return false
+	}
+
 	if w.cacheType == cacheTypeInfinite {
 		return false
 	}
@@ -204,22 +191,96 @@ func (w *widgetBase) requiresUpdate(now *time.Time) bool {
 	return now.After(w.nextUpdate)
 }
 
+func (w *widgetBase) IsVisible() bool {
+	return w.isVisibleAt(time.Now())
+}
+
+func (w *widgetBase) isVisibleAt(now time.Time) bool {
+	if w.ShowWhen == nil {
+		return true
+	}
+
+	return w.ShowWhen.matches(now, w.Providers)
+}
+
+// Widgets that never expire have nothing to refresh
+func (w *widgetBase) CanRefresh() bool {
+	return w.cacheType != cacheTypeInfinite
+}
+
+func (w *widgetBase) expireCache() {
+	w.nextUpdate = time.Time{}
+}
+
 func (w *widgetBase) IsWIP() bool {
 	return w.WIP
 }
 
 func (w *widgetBase) setUpdating(updating bool) {
-	w.IsUpdating = updating
+	w.updating.Store(updating)
+}
+
+// Reports false if the widget is already being updated, so that a manual
+// refresh doesn't run at the same time as another one
+func (w *widgetBase) startUpdating() bool {
+	return w.updating.CompareAndSwap(false, true)
 }
 
 func (w *widgetBase) CheckIsUpdating() bool {
-	return w.IsUpdating
+	return w.updating.Load()
 }
 
 func (w *widgetBase) update(ctx context.Context) {
 
 }
 
+// Updates should go through here rather than calling update directly so that
+// the snapshot used by show-when conditions stays current
+func updateWidget(ctx context.Context, w widget) {
+	w.beginAlertEvents()
+	w.update(ctx)
+	w.finishAlertEvents()
+	w.storeSnapshot(w)
+}
+
+// Encoding every widget after each update would be wasteful when only a few of
+// them are ever read by conditions, so this is called for those when the config loads
+func (w *widgetBase) keepSnapshots(self widget) {
+	if w.keepsSnapshot {
+		return
+	}
+
+	w.keepsSnapshot = true
+	w.storeSnapshot(self)
+}
+
+// Takes the outer widget since the base can't encode the fields of the widget embedding it
+func (w *widgetBase) storeSnapshot(self widget) {
+	if !w.keepsSnapshot {
+		return
+	}
+
+	encoded, err := json.Marshal(self)
+	if err != nil {
+		slog.Warn("Encoding widget snapshot", "type", w.Type, "error", err)
+		return
+	}
+
+	w.snapshotMu.Lock()
+	defer w.snapshotMu.Unlock()
+	w.snapshot = encoded
+}
+
+func (w *widgetBase) getShowWhen() *widgetVisibility {
+	return w.ShowWhen
+}
+
+func (w *widgetBase) getSnapshot() []byte {
+	w.snapshotMu.RLock()
+	defer w.snapshotMu.RUnlock()
+	return w.snapshot
+}
+
 func (w *widgetBase) GetID() uint64 {
 	return w.ID
 }
@@ -228,6 +289,14 @@ func (w *widgetBase) setID(id uint64) {
 	w.ID = id
 }
 
+func (w *widgetBase) setSourceLine(line int) {
+	w.sourceLine = line
+}
+
+func (w *widgetBase) getSourceLine() int {
+	return w.sourceLine
+}
+
 func (w *widgetBase) setHideHeader(value bool) {
 	w.HideHeader = value
 }
@@ -274,6 +343,14 @@ func (w *widgetBase) renderTemplate(data any, t *template.Template) template.HTM
 	return template.HTML(w.templateBuffer.String())
 }
 
+func (w *widgetBase) getTitle() string {
+	return w.Title
+}
+
+func (w *widgetBase) getErrors() (error, error) {
+	return w.Error, w.Notice
+}
+
 func (w *widgetBase) withTitle(title string) *widgetBase {
 	if w.Title == "" {
 		w.Title = title
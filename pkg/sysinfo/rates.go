@@ -0,0 +1,232 @@
+package sysinfo
+
+import (
+	"context"
+	"math"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/cpu"
+	"github.com/shirou/gopsutil/v4/disk"
+	"github.com/shirou/gopsutil/v4/net"
+)
+
+// Calls to Collect made sooner than this after the previous sample reuse the
+// previously computed rates since the deltas would be too small to be meaningful
+const minSampleInterval = time.Second
+
+// Keeps the previous reading of monotonically increasing counters so that the rate
+// of change can be computed between calls to Collect. Shared between all requests
+// since the counters are system wide and filtering is applied after the fact.
+type counterSampler[S any, R any] struct {
+	mu        sync.Mutex
+	sampledAt time.Time
+	sample    S
+	rates     R
+	hasRates  bool
+}
+
+func (s *counterSampler[S, R]) update(now time.Time, sample S, computeRates func(previous, current S, elapsed float64) R) (R, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.sampledAt.IsZero() {
+		elapsed := now.Sub(s.sampledAt)
+		if elapsed < minSampleInterval {
+			return s.rates, s.hasRates
+		}
+
+		s.rates = computeRates(s.sample, sample, elapsed.Seconds())
+		s.hasRates = true
+	}
+
+	s.sample = sample
+	s.sampledAt = now
+
+	return s.rates, s.hasRates
+}
+
+// Counters can reset, for example when an interface gets recreated, in which case there's no valid delta
+func counterRate(previous, current uint64, elapsed float64) uint64 {
+	if current < previous {
+		return 0
+	}
+
+	return uint64(float64(current-previous) / elapsed)
+}
+
+// The samplers of each host, keyed the same way as the cached host info
+var hostSamplers = struct {
+	sync.Mutex
+	byHost map[string]*samplers
+}{byHost: map[string]*samplers{}}
+
+type samplers struct {
+	network counterSampler[map[string]net.IOCountersStat, map[string]NetworkInterfaceInfo]
+	disk    counterSampler[map[string]disk.IOCountersStat, map[string]DiskIOInfo]
+	cpu     counterSampler[[]cpu.TimesStat, []uint8]
+}
+
+func samplersOfHost(hostKey string) *samplers {
+	hostSamplers.Lock()
+	defer hostSamplers.Unlock()
+
+	s, ok := hostSamplers.byHost[hostKey]
+	if !ok {
+		s = &samplers{}
+		hostSamplers.byHost[hostKey] = s
+	}
+
+	return s
+}
+
+func collectNetworkRates(ctx context.Context, hostKey string, filter DeviceFilter) ([]NetworkInterfaceInfo, bool, error) {
+	counters, err := net.IOCountersWithContext(ctx, true)
+	if err != nil {
+		return nil, false, err
+	}
+
+	sample := make(map[string]net.IOCountersStat, len(counters))
+	for i := range counters {
+		sample[counters[i].Name] = counters[i]
+	}
+
+	rates, ok := samplersOfHost(hostKey).network.update(time.Now(), sample, func(previous, current map[string]net.IOCountersStat, elapsed float64) map[string]NetworkInterfaceInfo {
+		rates := make(map[string]NetworkInterfaceInfo, len(current))
+		for name, c := range current {
+			p, exists := previous[name]
+			if !exists {
+				continue
+			}
+
+			rates[name] = NetworkInterfaceInfo{
+				Name:          name,
+				RxBytesPerSec: counterRate(p.BytesRecv, c.BytesRecv, elapsed),
+				TxBytesPerSec: counterRate(p.BytesSent, c.BytesSent, elapsed),
+			}
+		}
+		return rates
+	})
+
+	if !ok {
+		return nil, false, nil
+	}
+
+	interfaces := make([]NetworkInterfaceInfo, 0, len(rates))
+	for name, rate := range rates {
+		if filter.matches(name, defaultExcludedNetworkInterfaces) {
+			interfaces = append(interfaces, rate)
+		}
+	}
+
+	sort.Slice(interfaces, func(a, b int) bool {
+		return interfaces[a].Name < interfaces[b].Name
+	})
+
+	return interfaces, true, nil
+}
+
+func collectDiskIORates(ctx context.Context, hostKey string, filter DeviceFilter) ([]DiskIOInfo, bool, error) {
+	counters, err := disk.IOCountersWithContext(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	rates, ok := samplersOfHost(hostKey).disk.update(time.Now(), counters, func(previous, current map[string]disk.IOCountersStat, elapsed float64) map[string]DiskIOInfo {
+		rates := make(map[string]DiskIOInfo, len(current))
+		for name, c := range current {
+			p, exists := previous[name]
+			if !exists {
+				continue
+			}
+
+			rates[name] = DiskIOInfo{
+				Device:             name,
+				ReadBytesPerSec:    counterRate(p.ReadBytes, c.ReadBytes, elapsed),
+				WrittenBytesPerSec: counterRate(p.WriteBytes, c.WriteBytes, elapsed),
+			}
+		}
+		return rates
+	})
+
+	if !ok {
+		return nil, false, nil
+	}
+
+	devices := make([]DiskIOInfo, 0, len(rates))
+	for name, rate := range rates {
+		if filter.matches(name, defaultExcludedDiskDevices) {
+			devices = append(devices, rate)
+		}
+	}
+
+	sort.Slice(devices, func(a, b int) bool {
+		return devices[a].Device < devices[b].Device
+	})
+
+	return devices, true, nil
+}
+
+func collectCoresUsage(ctx context.Context, hostKey string) ([]uint8, bool, error) {
+	times, err := cpu.TimesWithContext(ctx, true)
+	if err != nil {
+		return nil, false, err
+	}
+
+	usage, ok := samplersOfHost(hostKey).cpu.update(time.Now(), times, func(previous, current []cpu.TimesStat, _ float64) []uint8 {
+		if len(previous) != len(current) {
+			return nil
+		}
+
+		usage := make([]uint8, len(current))
+		for i := range current {
+			total := current[i].Total() - previous[i].Total()
+			idle := (current[i].Idle + current[i].Iowait) - (previous[i].Idle + previous[i].Iowait)
+
+			if total > 0 {
+				usage[i] = uint8(math.Max(0, math.Min((total-idle)/total*100, 100)))
+			}
+		}
+		return usage
+	})
+
+	return usage, ok, nil
+}
+
+// Loopback and virtual interfaces created by container runtimes and hypervisors
+var defaultExcludedNetworkInterfaces = []string{"lo", "lo0", "veth*", "docker*", "br-*", "virbr*", "cni*", "flannel*"}
+
+// Virtual block devices which would otherwise clutter the list
+var defaultExcludedDiskDevices = []string{"loop*", "ram*", "zram*"}
+
+type DeviceFilter struct {
+	// Glob patterns, when set only matching devices are included
+	Include []string `yaml:"include"`
+	// Glob patterns of devices to leave out, replaces the defaults
+	Exclude []string `yaml:"exclude"`
+}
+
+func (f DeviceFilter) matches(name string, defaultExclude []string) bool {
+	if len(f.Include) > 0 && !matchesAnyPattern(name, f.Include) {
+		return false
+	}
+
+	exclude := f.Exclude
+	if exclude == nil && len(f.Include) == 0 {
+		exclude = defaultExclude
+	}
+
+	return !matchesAnyPattern(name, exclude)
+}
+
+func matchesAnyPattern(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,56 @@
+package sysinfo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCounterSamplerComputesRatesBetweenCalls(t *testing.T) {
+	var sampler counterSampler[uint64, uint64]
+	perSecond := func(previous, current uint64, elapsed float64) uint64 {
+		return counterRate(previous, current, elapsed)
+	}
+
+	start := time.Now()
+
+	if _, ok := sampler.update(start, 1000, perSecond); ok {
+		t.Fatal("expected no rate to be available after the first sample")
+	}
+
+	if rate, ok := sampler.update(start.Add(2*time.Second), 5000, perSecond); !ok || rate != 2000 {
+		t.Fatalf("expected a rate of 2000, got %d (available: %t)", rate, ok)
+	}
+
+	// too soon after the previous sample, the previous rate gets reused
+	if rate, _ := sampler.update(start.Add(2100*time.Millisecond), 9000, perSecond); rate != 2000 {
+		t.Errorf("expected the previous rate to be reused, got %d", rate)
+	}
+
+	// counter reset
+	if rate, _ := sampler.update(start.Add(4*time.Second), 100, perSecond); rate != 0 {
+		t.Errorf("expected a rate of 0 after the counter was reset, got %d", rate)
+	}
+}
+
+func TestDeviceFilter(t *testing.T) {
+	tests := []struct {
+		filter   DeviceFilter
+		name     string
+		expected bool
+	}{
+		{DeviceFilter{}, "eth0", true},
+		{DeviceFilter{}, "lo", false},
+		{DeviceFilter{}, "veth12ab", false},
+		{DeviceFilter{Include: []string{"lo"}}, "lo", true},
+		{DeviceFilter{Include: []string{"eth*"}}, "wlan0", false},
+		{DeviceFilter{Exclude: []string{"eth1"}}, "lo", true},
+		{DeviceFilter{Exclude: []string{"eth1"}}, "eth1", false},
+		{DeviceFilter{Include: []string{"eth*"}, Exclude: []string{"eth1"}}, "eth1", false},
+	}
+
+	for _, test := range tests {
+		if actual := test.filter.matches(test.name, defaultExcludedNetworkInterfaces); actual != test.expected {
+			t.Errorf("%+v matching %s: expected %t, got %t", test.filter, test.name, test.expected, actual)
+		}
+	}
+}
@@ -1,19 +1,25 @@
 package sysinfo
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"os"
+	"path/filepath"
 	"runtime"
 	"sort"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/shirou/gopsutil/v4/common"
 	"github.com/shirou/gopsutil/v4/cpu"
 	"github.com/shirou/gopsutil/v4/disk"
 	"github.com/shirou/gopsutil/v4/host"
 	"github.com/shirou/gopsutil/v4/load"
 	"github.com/shirou/gopsutil/v4/mem"
+	"github.com/shirou/gopsutil/v4/process"
 	"github.com/shirou/gopsutil/v4/sensors"
 )
 
@@ -48,8 +54,16 @@ type SystemInfo struct {
 
 		TemperatureIsAvailable bool  `json:"temperature_is_available"`
 		TemperatureC           uint8 `json:"temperature_c"`
+
+		CoresUsageIsAvailable bool    `json:"cores_usage_is_available"`
+		CoresUsedPercent      []uint8 `json:"cores_used_percent,omitempty"`
 	} `json:"cpu"`
 
+	Processes struct {
+		IsAvailable bool   `json:"processes_is_available"`
+		Count       uint64 `json:"count"`
+	} `json:"processes"`
+
 	Memory struct {
 		IsAvailable bool   `json:"memory_is_available"`
 		TotalMB     uint64 `json:"total_mb"`
@@ -63,6 +77,48 @@ type SystemInfo struct {
 	} `json:"memory"`
 
 	Mountpoints []MountpointInfo `json:"mountpoints"`
+
+	// Rates are computed from the difference between two calls to Collect
+	// and are not available on the first call
+	Network NetworkInfo `json:"network"`
+
+	DiskIO struct {
+		IsAvailable bool         `json:"disk_io_is_available"`
+		Devices     []DiskIOInfo `json:"devices"`
+	} `json:"disk_io"`
+}
+
+type NetworkInfo struct {
+	IsAvailable bool                   `json:"network_is_available"`
+	Interfaces  []NetworkInterfaceInfo `json:"interfaces"`
+}
+
+func (n *NetworkInfo) TotalRxBytesPerSec() uint64 {
+	var total uint64
+	for i := range n.Interfaces {
+		total += n.Interfaces[i].RxBytesPerSec
+	}
+	return total
+}
+
+func (n *NetworkInfo) TotalTxBytesPerSec() uint64 {
+	var total uint64
+	for i := range n.Interfaces {
+		total += n.Interfaces[i].TxBytesPerSec
+	}
+	return total
+}
+
+type NetworkInterfaceInfo struct {
+	Name          string `json:"name"`
+	RxBytesPerSec uint64 `json:"rx_bytes_per_sec"`
+	TxBytesPerSec uint64 `json:"tx_bytes_per_sec"`
+}
+
+type DiskIOInfo struct {
+	Device             string `json:"device"`
+	ReadBytesPerSec    uint64 `json:"read_bytes_per_sec"`
+	WrittenBytesPerSec uint64 `json:"written_bytes_per_sec"`
 }
 
 type MountpointInfo struct {
@@ -77,6 +133,59 @@ type SystemInfoRequest struct {
 	CPUTempSensor            string                       `yaml:"cpu-temp-sensor"`
 	HideMountpointsByDefault bool                         `yaml:"hide-mountpoints-by-default"`
 	Mountpoints              map[string]MointpointRequest `yaml:"mountpoints"`
+	NetworkInterfaces        DeviceFilter                 `yaml:"network-interfaces"`
+	DiskDevices              DeviceFilter                 `yaml:"disk-devices"`
+
+	// Paths where the host's filesystems are mounted when running inside of a container
+	HostProc string `yaml:"host-proc"`
+	HostSys  string `yaml:"host-sys"`
+	HostRoot string `yaml:"host-root"`
+}
+
+// Returns a context which points gopsutil to the host's filesystems along with a key
+// identifying them, which is used to keep the cached values of different hosts apart
+func (req *SystemInfoRequest) hostContext() (context.Context, string) {
+	env := common.EnvMap{}
+
+	hostProc, hostSys := req.HostProc, req.HostSys
+	if req.HostRoot != "" {
+		if hostProc == "" {
+			hostProc = filepath.Join(req.HostRoot, "proc")
+		}
+		if hostSys == "" {
+			hostSys = filepath.Join(req.HostRoot, "sys")
+		}
+
+		env[common.HostRootEnvKey] = req.HostRoot
+		env[common.HostEtcEnvKey] = filepath.Join(req.HostRoot, "etc")
+		env[common.HostVarEnvKey] = filepath.Join(req.HostRoot, "var")
+		env[common.HostRunEnvKey] = filepath.Join(req.HostRoot, "run")
+		env[common.HostDevEnvKey] = filepath.Join(req.HostRoot, "dev")
+	}
+
+	if hostProc != "" {
+		env[common.HostProcEnvKey] = hostProc
+	}
+
+	if hostSys != "" {
+		env[common.HostSysEnvKey] = hostSys
+	}
+
+	if len(env) == 0 {
+		return context.Background(), ""
+	}
+
+	return context.WithValue(context.Background(), common.EnvKey, env), hostProc + "\x00" + hostSys + "\x00" + req.HostRoot
+}
+
+// Mountpoints are reported using their path on the host, this returns the path
+// through which they're accessible from where Glance is running
+func (req *SystemInfoRequest) accessiblePath(hostPath string) string {
+	if req.HostRoot == "" {
+		return hostPath
+	}
+
+	return filepath.Join(req.HostRoot, hostPath)
 }
 
 type MointpointRequest struct {
@@ -94,23 +203,25 @@ type cacheableHostInfo struct {
 	bootTime  timestampJSON
 }
 
-var cachedHostInfo cacheableHostInfo
+// Keyed by the host paths of the request
+var cachedHostInfo = map[string]cacheableHostInfo{}
+var cachedHostInfoMu sync.Mutex
 
-func getHostInfo() (cacheableHostInfo, error) {
+func getHostInfo(ctx context.Context, hostRoot string) (cacheableHostInfo, error) {
 	var err error
 	info := cacheableHostInfo{}
 
-	info.hostname, err = os.Hostname()
+	info.hostname, err = getHostname(hostRoot)
 	if err != nil {
 		return info, err
 	}
 
-	info.platform, _, _, err = host.PlatformInformation()
+	info.platform, _, _, err = host.PlatformInformationWithContext(ctx)
 	if err != nil {
 		return info, err
 	}
 
-	bootTime, err := host.BootTime()
+	bootTime, err := host.BootTimeWithContext(ctx)
 	if err != nil {
 		return info, err
 	}
@@ -121,6 +232,20 @@ func getHostInfo() (cacheableHostInfo, error) {
 	return info, nil
 }
 
+// The hostname reported to processes in a container is the container's, unless
+// it runs with the host's UTS namespace, so we read the one the host has configured
+func getHostname(hostRoot string) (string, error) {
+	if hostRoot != "" {
+		if contents, err := os.ReadFile(filepath.Join(hostRoot, "etc", "hostname")); err == nil {
+			if hostname := strings.TrimSpace(string(contents)); hostname != "" {
+				return hostname, nil
+			}
+		}
+	}
+
+	return os.Hostname()
+}
+
 func Collect(req *SystemInfoRequest) (*SystemInfo, []error) {
 	if req == nil {
 		req = &SystemInfoRequest{}
@@ -136,28 +261,32 @@ func Collect(req *SystemInfoRequest) (*SystemInfo, []error) {
 		Mountpoints: []MountpointInfo{},
 	}
 
-	applyCachedHostInfo := func() {
+	applyCachedHostInfo := func(hostInfo cacheableHostInfo) {
 		info.HostInfoIsAvailable = true
-		info.BootTime = cachedHostInfo.bootTime
-		info.Hostname = cachedHostInfo.hostname
-		info.Platform = cachedHostInfo.platform
+		info.BootTime = hostInfo.bootTime
+		info.Hostname = hostInfo.hostname
+		info.Platform = hostInfo.platform
 	}
 
-	if cachedHostInfo.available {
-		applyCachedHostInfo()
+	ctx, hostKey := req.hostContext()
+
+	cachedHostInfoMu.Lock()
+	if hostInfo, ok := cachedHostInfo[hostKey]; ok {
+		applyCachedHostInfo(hostInfo)
 	} else {
-		hostInfo, err := getHostInfo()
+		hostInfo, err := getHostInfo(ctx, req.HostRoot)
 		if err == nil {
-			cachedHostInfo = hostInfo
-			applyCachedHostInfo()
+			cachedHostInfo[hostKey] = hostInfo
+			applyCachedHostInfo(hostInfo)
 		} else {
 			addErr(fmt.Errorf("getting host info: %v", err))
 		}
 	}
+	cachedHostInfoMu.Unlock()
 
-	coreCount, err := cpu.Counts(true)
+	coreCount, err := cpu.CountsWithContext(ctx, true)
 	if err == nil {
-		loadAvg, err := load.Avg()
+		loadAvg, err := load.AvgWithContext(ctx)
 		if err == nil {
 			info.CPU.LoadIsAvailable = true
 			if runtime.GOOS == "windows" {
@@ -178,7 +307,21 @@ func Collect(req *SystemInfoRequest) (*SystemInfo, []error) {
 		addErr(fmt.Errorf("getting core count: %v", err))
 	}
 
-	memory, err := mem.VirtualMemory()
+	if cores, ok, err := collectCoresUsage(ctx, hostKey); err == nil {
+		info.CPU.CoresUsageIsAvailable = ok && len(cores) > 0
+		info.CPU.CoresUsedPercent = cores
+	} else {
+		addErr(fmt.Errorf("getting per core CPU usage: %v", err))
+	}
+
+	if pids, err := process.PidsWithContext(ctx); err == nil {
+		info.Processes.IsAvailable = true
+		info.Processes.Count = uint64(len(pids))
+	} else {
+		addErr(fmt.Errorf("getting process count: %v", err))
+	}
+
+	memory, err := mem.VirtualMemoryWithContext(ctx)
 	if err == nil {
 		info.Memory.IsAvailable = true
 		info.Memory.TotalMB = memory.Total / 1024 / 1024
@@ -188,7 +331,7 @@ func Collect(req *SystemInfoRequest) (*SystemInfo, []error) {
 		addErr(fmt.Errorf("getting memory info: %v", err))
 	}
 
-	swapMemory, err := mem.SwapMemory()
+	swapMemory, err := mem.SwapMemoryWithContext(ctx)
 	if err == nil {
 		info.Memory.SwapIsAvailable = true
 		info.Memory.SwapTotalMB = swapMemory.Total / 1024 / 1024
@@ -204,7 +347,7 @@ func Collect(req *SystemInfoRequest) (*SystemInfo, []error) {
 	// compared against the temperatures Libre Hardware Monitor reports.
 	// Also disabled on the bsd's because it's not implemented by go-psutil for them
 	if runtime.GOOS != "windows" && runtime.GOOS != "openbsd" && runtime.GOOS != "netbsd" && runtime.GOOS != "freebsd" {
-		sensorReadings, err := sensors.SensorsTemperatures()
+		sensorReadings, err := sensors.TemperaturesWithContext(ctx)
 		_, errIsWarning := err.(*sensors.Warnings)
 		if err == nil || errIsWarning {
 			if req.CPUTempSensor != "" {
@@ -242,7 +385,7 @@ func Collect(req *SystemInfoRequest) (*SystemInfo, []error) {
 			return
 		}
 
-		usage, err := disk.Usage(requestedPath)
+		usage, err := disk.UsageWithContext(ctx, req.accessiblePath(requestedPath))
 		if err == nil {
 			mpInfo := MountpointInfo{
 				Path:        requestedPath,
@@ -260,7 +403,7 @@ func Collect(req *SystemInfoRequest) (*SystemInfo, []error) {
 	}
 
 	if !req.HideMountpointsByDefault {
-		filesystems, err := disk.Partitions(false)
+		filesystems, err := disk.PartitionsWithContext(ctx, false)
 		if err == nil {
 			for _, fs := range filesystems {
 				addMountpointInfo(fs.Mountpoint, req.Mountpoints[fs.Mountpoint])
@@ -278,6 +421,20 @@ func Collect(req *SystemInfoRequest) (*SystemInfo, []error) {
 		return info.Mountpoints[a].UsedPercent > info.Mountpoints[b].UsedPercent
 	})
 
+	if interfaces, ok, err := collectNetworkRates(ctx, hostKey, req.NetworkInterfaces); err == nil {
+		info.Network.IsAvailable = ok
+		info.Network.Interfaces = interfaces
+	} else {
+		addErr(fmt.Errorf("getting network counters: %v", err))
+	}
+
+	if devices, ok, err := collectDiskIORates(ctx, hostKey, req.DiskDevices); err == nil {
+		info.DiskIO.IsAvailable = ok
+		info.DiskIO.Devices = devices
+	} else {
+		addErr(fmt.Errorf("getting disk IO counters: %v", err))
+	}
+
 	return info, errs
 }
 
@@ -0,0 +1,44 @@
+package sysinfo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shirou/gopsutil/v4/common"
+)
+
+func TestHostRootMapsPathsToTheHost(t *testing.T) {
+	hostRoot := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(hostRoot, "etc"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(hostRoot, "etc", "hostname"), []byte("nas\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	req := &SystemInfoRequest{HostRoot: hostRoot, HostSys: "/custom/sys"}
+
+	ctx, key := req.hostContext()
+	env, _ := ctx.Value(common.EnvKey).(common.EnvMap)
+
+	if env[common.HostProcEnvKey] != filepath.Join(hostRoot, "proc") {
+		t.Errorf("expected host-proc to default to a path under host-root, got %q", env[common.HostProcEnvKey])
+	}
+
+	if env[common.HostSysEnvKey] != "/custom/sys" {
+		t.Errorf("expected host-sys to take precedence over host-root, got %q", env[common.HostSysEnvKey])
+	}
+
+	if _, defaultKey := (&SystemInfoRequest{}).hostContext(); key == defaultKey {
+		t.Error("expected hosts with different paths to have different keys")
+	}
+
+	if path := req.accessiblePath("/mnt/data"); path != filepath.Join(hostRoot, "mnt", "data") {
+		t.Errorf("unexpected accessible path %q", path)
+	}
+
+	if hostname, _ := getHostname(hostRoot); hostname != "nas" {
+		t.Errorf("expected hostname to be read from host-root, got %q", hostname)
+	}
+}